@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/Sugar-pack/orders-manager/internal/importer"
+	"github.com/Sugar-pack/orders-manager/pkg/pb"
+)
+
+// maxBatchGetOrders is grpcapi.MaxBatchGetOrders, get splits ids into batches of it.
+const maxBatchGetOrders = 100
+
+var errNotFound = errors.New("order not found")
+
+// insert inserts orders and prints their prepared transactions, they are kept until confirmed.
+func (c *cli) insert(ctx context.Context, args []string) error {
+	flags := c.newFlagSet("insert")
+	userID := flags.String("user", "", "user id of the order")
+	label := flags.String("label", "", "label of the order")
+	createdAt := flags.String("created-at", "", "creation time of the order, RFC3339 (default now)")
+	file := flags.String("file", "", "NDJSON or CSV file of orders with user_id, label and created_at, - is stdin")
+	format := flags.String("format", "", "format of -file, csv or ndjson (default by file extension, ndjson otherwise)")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() > 0 || (*file == "") == (*userID == "") {
+		return usageError(flags)
+	}
+
+	if *file == "" {
+		order := &pb.Order{UserId: *userID, Label: *label, CreatedAt: timestamppb.Now()}
+		if *createdAt != "" {
+			parsed, err := time.Parse(time.RFC3339Nano, *createdAt)
+			if err != nil {
+				return fmt.Errorf("invalid -created-at: %w", err)
+			}
+			order.CreatedAt = timestamppb.New(parsed)
+		}
+		inserted, err := c.orders.InsertOrder(ctx, order)
+		if err != nil {
+			return errors.New(describe(err))
+		}
+		result := newTable("id", "tnx")
+		result.add(inserted.GetId(), inserted.GetTnx())
+
+		return c.finish(result, 1)
+	}
+
+	if *format == "" {
+		*format = importer.FormatNDJSON
+		if strings.EqualFold(filepath.Ext(*file), ".csv") {
+			*format = importer.FormatCSV
+		}
+	}
+	input, err := c.open(*file)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	reader, err := importer.NewReader(*format, input)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	// ids of rows are ignored, the service assigns ids of inserted orders
+	result := newTable("line", "id", "tnx")
+	total := 0
+	for {
+		row, readErr := reader.Read()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return readErr //nolint:wrapcheck
+		}
+		total++
+		line := "line " + strconv.Itoa(row.Line)
+		if row.Err != nil {
+			c.fail(line, row.Err)
+
+			continue
+		}
+		inserted, insertErr := c.orders.InsertOrder(ctx, &pb.Order{
+			UserId:    row.Order.UserID.String(),
+			Label:     row.Order.Label,
+			CreatedAt: timestamppb.New(row.Order.CreatedAt),
+		})
+		if insertErr != nil {
+			c.fail(line, insertErr)
+
+			continue
+		}
+		result.add(strconv.Itoa(row.Line), inserted.GetId(), inserted.GetTnx())
+	}
+
+	return c.finish(result, total)
+}
+
+// get prints orders of ids, fetching them in batches.
+func (c *cli) get(ctx context.Context, args []string) error {
+	flags := c.newFlagSet("get")
+	file := flags.String("file", "", "file of order ids, an id per line, - is stdin")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	ids, err := c.items(flags.Args(), *file)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return usageError(flags)
+	}
+
+	result := newTable("id", "user_id", "label", "created_at")
+	valid := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, parseErr := uuid.Parse(id); parseErr != nil {
+			c.fail(id, parseErr)
+
+			continue
+		}
+		valid = append(valid, id)
+	}
+	for batch := range slices.Chunk(valid, maxBatchGetOrders) {
+		response, batchErr := c.orders.BatchGetOrders(ctx, &pb.BatchGetOrdersRequest{Ids: batch})
+		if batchErr != nil {
+			for _, id := range batch {
+				c.fail(id, batchErr)
+			}
+
+			continue
+		}
+		for _, order := range response.GetOrders() {
+			result.add(order.GetId(), order.GetUserId(), order.GetLabel(), formatTime(order.GetCreatedAt()))
+		}
+		for _, id := range response.GetMissingIds() {
+			c.fail(id, errNotFound)
+		}
+	}
+
+	return c.finish(result, len(ids))
+}
+
+// list prints orders of a user page by page.
+func (c *cli) list(ctx context.Context, args []string) error {
+	flags := c.newFlagSet("list")
+	userID := flags.String("user", "", "user id of the orders")
+	pageSize := flags.Int("page-size", 0, "orders fetched with a call (default of the server)")
+	limit := flags.Int("limit", 0, "print at most limit orders, 0 prints all")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() > 0 || *userID == "" || *pageSize < 0 || *limit < 0 {
+		return usageError(flags)
+	}
+
+	result := newTable("id", "user_id", "label", "created_at")
+	request := &pb.ListOrdersRequest{UserId: *userID, PageSize: int32(*pageSize)} //nolint:gosec // checked by the server
+	for {
+		page, err := c.orders.ListOrders(ctx, request)
+		if err != nil {
+			return errors.New(describe(err))
+		}
+		for _, order := range page.GetOrders() {
+			result.add(order.GetId(), order.GetUserId(), order.GetLabel(), formatTime(order.GetCreatedAt()))
+		}
+		if *limit > 0 && len(result.rows) >= *limit {
+			result.rows = result.rows[:*limit]
+
+			break
+		}
+		if page.GetNextPageToken() == "" {
+			break
+		}
+		request.PageToken = page.GetNextPageToken()
+	}
+
+	return c.finish(result, len(result.rows))
+}
+
+// confirm commits or rolls back prepared transactions.
+func (c *cli) confirm(ctx context.Context, command string, args []string, commit bool) error {
+	flags := c.newFlagSet(command)
+	file := flags.String("file", "", "file of transaction ids, an id per line, - is stdin")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	tnxs, err := c.items(flags.Args(), *file)
+	if err != nil {
+		return err
+	}
+	if len(tnxs) == 0 {
+		return usageError(flags)
+	}
+
+	outcome := "rolled back"
+	if commit {
+		outcome = "committed"
+	}
+	result := newTable("tnx", "result")
+	for _, tnx := range tnxs {
+		if _, confirmErr := c.transactions.SendConfirmation(ctx, &pb.Confirmation{Tnx: tnx, Commit: commit}); confirmErr != nil {
+			c.fail(tnx, confirmErr)
+
+			continue
+		}
+		result.add(tnx, outcome)
+	}
+
+	return c.finish(result, len(tnxs))
+}
+
+// prepared prints transactions prepared at least min-age ago.
+func (c *cli) prepared(ctx context.Context, args []string) error {
+	flags := c.newFlagSet("prepared")
+	minAge := flags.Duration("min-age", 0, "list transactions prepared at least that long ago")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() > 0 || *minAge < 0 {
+		return usageError(flags)
+	}
+
+	response, err := c.transactions.ListPreparedTransactions(ctx, &pb.ListPreparedTransactionsRequest{
+		MinAge: durationpb.New(*minAge),
+	})
+	if err != nil {
+		return errors.New(describe(err))
+	}
+	result := newTable("tnx", "prepared_at", "age")
+	for _, transaction := range response.GetTransactions() {
+		age := time.Since(transaction.GetPreparedAt().AsTime()).Round(time.Second)
+		result.add(transaction.GetTnx(), formatTime(transaction.GetPreparedAt()), age.String())
+	}
+
+	return c.finish(result, len(response.GetTransactions()))
+}
+
+// items returns ids of args followed by ids of file.
+func (c *cli) items(args []string, file string) ([]string, error) {
+	items := slices.Clone(args)
+	if file != "" {
+		input, err := c.open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer input.Close()
+		scanner := bufio.NewScanner(input)
+		for scanner.Scan() {
+			// blank lines and comments are skipped
+			if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+				items = append(items, line)
+			}
+		}
+		if err = scanner.Err(); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+	}
+
+	return items, nil
+}
+
+// open opens file, - is stdin.
+func (c *cli) open(file string) (io.ReadCloser, error) {
+	if file == "-" {
+		return io.NopCloser(c.stdin), nil
+	}
+
+	return os.Open(file) //nolint:wrapcheck
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// tenantHeader is grpcapi.TenantHeader, the client does not depend on server packages.
+const tenantHeader = "x-tenant-id"
+
+// connOptions are the global flags.
+type connOptions struct {
+	addr               string
+	tls                bool
+	caFile             string
+	certFile           string
+	keyFile            string
+	serverName         string
+	insecureSkipVerify bool
+	token              string
+	tokenFile          string
+	tenant             string
+	timeout            time.Duration
+}
+
+// dial creates connection of opts, it connects lazily on the first call.
+func dial(opts *connOptions) (*grpc.ClientConn, error) {
+	transport, err := transportCredentials(opts)
+	if err != nil {
+		return nil, err
+	}
+	token := opts.token
+	if opts.tokenFile != "" {
+		content, readErr := os.ReadFile(opts.tokenFile)
+		if readErr != nil {
+			return nil, readErr //nolint:wrapcheck
+		}
+		token = strings.TrimSpace(string(content))
+	}
+
+	return grpc.NewClient(opts.addr, //nolint:wrapcheck
+		grpc.WithTransportCredentials(transport),
+		grpc.WithUnaryInterceptor(withCallOptions(token, opts.tenant, opts.timeout)),
+	)
+}
+
+// transportCredentials returns TLS credentials when flags ask for TLS, plaintext otherwise.
+func transportCredentials(opts *connOptions) (credentials.TransportCredentials, error) {
+	if !opts.tls && opts.caFile == "" && opts.certFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         opts.serverName,
+		InsecureSkipVerify: opts.insecureSkipVerify, //nolint:gosec // explicitly asked for by the flag
+	}
+	if opts.caFile != "" {
+		pem, err := os.ReadFile(opts.caFile)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.caFile)
+		}
+	}
+	if opts.certFile != "" || opts.keyFile != "" {
+		if opts.certFile == "" || opts.keyFile == "" {
+			return nil, errors.New("-cert-file and -key-file go together")
+		}
+		certificate, err := tls.LoadX509KeyPair(opts.certFile, opts.keyFile)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// withCallOptions sends bearer token and tenant with every call and limits its duration.
+func withCallOptions(token, tenant string, timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		}
+		if tenant != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, tenantHeader, tenant)
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// describe returns message and code of api errors, err as is otherwise.
+func describe(err error) string {
+	if grpcStatus, ok := status.FromError(err); ok {
+		return fmt.Sprintf("%s (%s)", grpcStatus.Message(), grpcStatus.Code())
+	}
+
+	return err.Error()
+}
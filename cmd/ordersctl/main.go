@@ -0,0 +1,156 @@
+// Command ordersctl calls orders-manager api from the command line.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/Sugar-pack/orders-manager/pkg/pb"
+)
+
+const usage = `Usage: %s [flags] <command> [arguments]
+
+Commands:
+  insert [flags]                    insert an order or orders of a file, see insert -h
+  get <id>... | -file <file>        get orders by id
+  list -user <id> [flags]           list orders of a user oldest first, see list -h
+  confirm <tnx>... | -file <file>   commit prepared transactions
+  rollback <tnx>... | -file <file>  roll back prepared transactions
+  prepared [-min-age duration]      list prepared transactions waiting for confirmation
+
+Files of get, confirm and rollback list an id per line, - is stdin. Bulk commands
+go on past failed items, report them to stderr and exit with status 1.
+
+Flags:
+`
+
+// errUsage is returned for malformed command line, usage is printed instead of the error.
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	err := run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ordersctl:", err)
+		os.Exit(1)
+	}
+}
+
+// run executes the command of args, usage is printed to stderr for errUsage.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("ordersctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, usage, flags.Name())
+		flags.PrintDefaults()
+	}
+	var conn connOptions
+	flags.StringVar(&conn.addr, "addr", "localhost:8080", "host:port of grpc api")
+	flags.BoolVar(&conn.tls, "tls", false, "connect with TLS, implied by -ca-file and -cert-file")
+	flags.StringVar(&conn.caFile, "ca-file", "", "CA certificates verifying the server (default system pool)")
+	flags.StringVar(&conn.certFile, "cert-file", "", "client certificate authenticating the caller")
+	flags.StringVar(&conn.keyFile, "key-file", "", "private key of -cert-file")
+	flags.StringVar(&conn.serverName, "server-name", "", "expected server name (default host of -addr)")
+	flags.BoolVar(&conn.insecureSkipVerify, "insecure-skip-verify", false, "do not verify server certificate, for testing only")
+	flags.StringVar(&conn.token, "token", "", "bearer token authenticating the caller")
+	flags.StringVar(&conn.tokenFile, "token-file", "", "file holding bearer token, preferred over -token")
+	flags.StringVar(&conn.tenant, "tenant", "", "tenant of the calls, required when tenancy is enabled")
+	flags.DurationVar(&conn.timeout, "timeout", 30*time.Second, "timeout of every call, 0 disables it")
+	format := flags.String("o", formatTable, fmt.Sprintf("output format, one of %v", formats))
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 || !slices.Contains(formats, *format) {
+		if !errors.Is(err, flag.ErrHelp) {
+			flags.Usage()
+		}
+
+		return errUsage
+	}
+
+	command, commandArgs := flags.Arg(0), flags.Args()[1:]
+	commands := map[string]func(*cli, context.Context, []string) error{
+		"insert":   (*cli).insert,
+		"get":      (*cli).get,
+		"list":     (*cli).list,
+		"confirm":  func(c *cli, ctx context.Context, args []string) error { return c.confirm(ctx, "confirm", args, true) },
+		"rollback": func(c *cli, ctx context.Context, args []string) error { return c.confirm(ctx, "rollback", args, false) },
+		"prepared": (*cli).prepared,
+	}
+	execute, ok := commands[command]
+	if !ok {
+		flags.Usage()
+
+		return errUsage
+	}
+
+	clientConn, err := dial(&conn)
+	if err != nil {
+		return err
+	}
+	defer clientConn.Close()
+
+	return execute(newCLI(clientConn, *format, stdin, stdout, stderr), ctx, commandArgs)
+}
+
+// cli runs commands against the api and collects failures of bulk commands.
+type cli struct {
+	orders       pb.OrdersManagerServiceClient
+	transactions pb.TnxConfirmingServiceClient
+	format       string
+	stdin        io.Reader
+	stdout       io.Writer
+	stderr       io.Writer
+	failures     int
+}
+
+func newCLI(conn grpc.ClientConnInterface, format string, stdin io.Reader, stdout, stderr io.Writer) *cli {
+	return &cli{
+		orders:       pb.NewOrdersManagerServiceClient(conn),
+		transactions: pb.NewTnxConfirmingServiceClient(conn),
+		format:       format,
+		stdin:        stdin,
+		stdout:       stdout,
+		stderr:       stderr,
+	}
+}
+
+// newFlagSet creates flags of command printing usage to stderr.
+func (c *cli) newFlagSet(command string) *flag.FlagSet {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+
+	return flags
+}
+
+// usageError prints usage of command flags and returns errUsage.
+func usageError(flags *flag.FlagSet) error {
+	flags.Usage()
+
+	return errUsage
+}
+
+// fail reports a failed item of a bulk command, the command goes on with the next one.
+func (c *cli) fail(item string, err error) {
+	c.failures++
+	fmt.Fprintf(c.stderr, "%s: %s\n", item, describe(err))
+}
+
+// finish prints result and fails when some of total items have failed.
+func (c *cli) finish(result *table, total int) error {
+	if err := result.write(c.stdout, c.format); err != nil {
+		return err
+	}
+	if c.failures > 0 {
+		return fmt.Errorf("%d of %d failed", c.failures, total)
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/Sugar-pack/orders-manager/internal/grpcapi"
+	"github.com/Sugar-pack/orders-manager/internal/mock"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+	"github.com/Sugar-pack/orders-manager/pkg/pb"
+)
+
+// serve starts api of repo and returns its address and metadata of the last call.
+func serve(t *testing.T, repo repository.OrderRepoWith2PC) (string, *metadata.MD) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	var received metadata.MD
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		received, _ = metadata.FromIncomingContext(ctx)
+
+		return handler(ctx, req)
+	}))
+	pb.RegisterOrdersManagerServiceServer(server, &grpcapi.OrderService{Repo: repo})
+	pb.RegisterTnxConfirmingServiceServer(server, &grpcapi.TnxConfirmingService{Repo: repo})
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String(), &received
+}
+
+// execute runs ordersctl with args and returns its stdout and stderr.
+func execute(t *testing.T, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+
+	return stdout.String(), stderr.String(), err
+}
+
+func TestInsert(t *testing.T) {
+	repo := mock.NewOrderRepoWith2PC(t)
+	addr, received := serve(t, repo)
+	userID := uuid.New()
+	repo.On("PrepareInsertOrder", testify.Anything, testify.MatchedBy(func(order *repository.Order) bool {
+		return order.UserID == userID && order.Label == "books"
+	}), testify.Anything).Return(nil).Once()
+
+	stdout, _, err := execute(t, "", "-addr", addr, "-token", "t0ken", "-tenant", "acme", "-o", "json",
+		"insert", "-user", userID.String(), "-label", "books", "-created-at", "2026-10-19T12:00:00Z")
+	assert.NoError(t, err)
+	var inserted []map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &inserted))
+	if assert.Len(t, inserted, 1) {
+		assert.NotEmpty(t, inserted[0]["id"])
+		assert.NotEmpty(t, inserted[0]["tnx"])
+	}
+	assert.Equal(t, []string{"Bearer t0ken"}, received.Get("authorization"))
+	assert.Equal(t, []string{"acme"}, received.Get(tenantHeader))
+}
+
+func TestInsert_File(t *testing.T) {
+	repo := mock.NewOrderRepoWith2PC(t)
+	addr, _ := serve(t, repo)
+	userID := uuid.New()
+	repo.On("PrepareInsertOrder", testify.Anything, testify.Anything, testify.Anything).Return(nil).Twice()
+	input := "user_id,label,created_at\n" +
+		userID.String() + ",books,2026-10-19T12:00:00Z\n" +
+		"user,pens,2026-10-19T12:00:00Z\n" +
+		userID.String() + ",pens,2026-10-19T12:00:01Z\n"
+	path := filepath.Join(t.TempDir(), "orders.csv")
+	assert.NoError(t, os.WriteFile(path, []byte(input), 0o600))
+
+	stdout, stderr, err := execute(t, "", "-addr", addr, "insert", "-file", path)
+	assert.EqualError(t, err, "1 of 3 failed")
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.Equal(t, []string{"LINE", "ID", "TNX"}, strings.Fields(lines[0]))
+		assert.Equal(t, "2", strings.Fields(lines[1])[0])
+		assert.Equal(t, "4", strings.Fields(lines[2])[0])
+	}
+	assert.Contains(t, stderr, "line 3: invalid user_id")
+}
+
+func TestGet(t *testing.T) {
+	repo := mock.NewOrderRepoWith2PC(t)
+	addr, _ := serve(t, repo)
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "books", CreatedAt: time.Now()}
+	missing := uuid.New()
+	repo.On("GetOrders", testify.Anything, []uuid.UUID{order.ID, missing}).Return([]*repository.Order{order}, nil).Once()
+
+	stdout, stderr, err := execute(t, "# orders\n"+missing.String()+"\n\n",
+		"-addr", addr, "get", "-file", "-", order.ID.String(), "order")
+	assert.Error(t, err)
+	assert.Contains(t, stdout, order.ID.String())
+	assert.Contains(t, stdout, "books")
+	assert.Contains(t, stderr, "order: ")
+	assert.Contains(t, stderr, missing.String()+": order not found")
+}
+
+func TestList(t *testing.T) {
+	repo := mock.NewOrderRepoWith2PC(t)
+	addr, _ := serve(t, repo)
+	userID := uuid.New()
+	now := time.Now().UTC()
+	orders := make([]*repository.Order, 5)
+	for i := range orders {
+		orders[i] = &repository.Order{ID: uuid.New(), UserID: userID, Label: "order", CreatedAt: now.Add(time.Duration(i) * time.Second)}
+	}
+	repo.On("ListOrders", testify.Anything, userID, repository.OrderCursor{}, 3).Return(orders[:3], nil).Once()
+	repo.On("ListOrders", testify.Anything, userID, repository.CursorOf(orders[1]), 3).Return(orders[2:5], nil).Once()
+
+	stdout, _, err := execute(t, "", "-addr", addr, "-o", "json", "list", "-user", userID.String(), "-page-size", "2", "-limit", "3")
+	assert.NoError(t, err)
+	var listed []map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &listed))
+	if assert.Len(t, listed, 3) {
+		for i, order := range listed {
+			assert.Equal(t, orders[i].ID.String(), order["id"])
+		}
+	}
+}
+
+func TestConfirmAndRollback(t *testing.T) {
+	repo := mock.NewOrderRepoWith2PC(t)
+	addr, _ := serve(t, repo)
+	committed, failed := uuid.New(), uuid.New()
+	repo.On("CommitInsertTransaction", testify.Anything, committed).Return(nil).Once()
+	repo.On("CommitInsertTransaction", testify.Anything, failed).Return(errors.New("no such transaction")).Once()
+	repo.On("RollbackInsertTransaction", testify.Anything, committed).Return(nil).Once()
+
+	stdout, stderr, err := execute(t, "", "-addr", addr, "confirm", committed.String(), failed.String())
+	assert.EqualError(t, err, "1 of 2 failed")
+	assert.Contains(t, stdout, committed.String()+"  committed")
+	assert.Contains(t, stderr, failed.String()+": commit tx failed (Internal)")
+
+	stdout, _, err = execute(t, "", "-addr", addr, "rollback", committed.String())
+	assert.NoError(t, err)
+	assert.Contains(t, stdout, "rolled back")
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"unknown"},
+		{"-o", "yaml", "get", uuid.NewString()},
+		{"get"},
+		{"list"},
+		{"insert", "-user", uuid.NewString(), "-file", "orders.csv"},
+	} {
+		_, stderr, err := execute(t, "", args...)
+		assert.ErrorIs(t, err, errUsage, "%v", args)
+		assert.Contains(t, stderr, "Usage", "%v", args)
+	}
+}
+
+func TestTable_Write(t *testing.T) {
+	result := newTable("tnx", "result")
+	result.add("tx-1", "committed")
+	result.add("tx-22", `rolled "back"`)
+
+	var out bytes.Buffer
+	assert.NoError(t, result.write(&out, formatTable))
+	assert.Equal(t, "TNX    RESULT\ntx-1   committed\ntx-22  rolled \"back\"\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, result.write(&out, formatJSON))
+	assert.JSONEq(t, `[{"tnx":"tx-1","result":"committed"},{"tnx":"tx-22","result":"rolled \"back\""}]`, out.String())
+
+	out.Reset()
+	assert.NoError(t, newTable("tnx").write(&out, formatJSON))
+	assert.Equal(t, "[]\n", out.String())
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+var formats = []string{formatTable, formatJSON}
+
+// table is a command result, printed as aligned columns or as JSON objects keyed by column.
+type table struct {
+	columns []string
+	rows    [][]string
+}
+
+func newTable(columns ...string) *table {
+	return &table{columns: columns}
+}
+
+func (t *table) add(values ...string) {
+	t.rows = append(t.rows, values)
+}
+
+func (t *table) write(w io.Writer, format string) error {
+	if format == formatJSON {
+		return t.writeJSON(w)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:mnd // padding of columns
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(t.columns, "\t")))
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush() //nolint:wrapcheck
+}
+
+// writeJSON writes an array of rows, keys keep the order of columns.
+func (t *table) writeJSON(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, row := range t.rows {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('{')
+		for j, column := range t.columns {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(column) // strings are always encoded
+			value, _ := json.Marshal(row[j])
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(']')
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return err //nolint:wrapcheck
+	}
+	out.WriteByte('\n')
+	_, err := out.WriteTo(w)
+
+	return err //nolint:wrapcheck
+}
+
+func formatTime(timestamp *timestamppb.Timestamp) string {
+	if timestamp == nil {
+		return ""
+	}
+
+	return timestamp.AsTime().UTC().Format(time.RFC3339Nano)
+}
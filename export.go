@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/google/uuid"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	"github.com/Sugar-pack/orders-manager/internal/db"
+	"github.com/Sugar-pack/orders-manager/internal/export"
+	applog "github.com/Sugar-pack/orders-manager/internal/logger"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+)
+
+// runExport streams orders matching flags of the "export" command to stdout or a file.
+func runExport(configPath string, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	userID := flags.String("user", "", "export orders of the user only")
+	from := flags.String("from", "", "export orders created at or after, RFC3339 time or YYYY-MM-DD")
+	to := flags.String("to", "", "export orders created before, RFC3339 time or YYYY-MM-DD")
+	format := flags.String("format", export.FormatCSV, fmt.Sprintf("output format, one of %v", export.Formats))
+	output := flags.String("output", "-", "output file, - is stdout")
+	tenant := flags.String("tenant", "", "export orders of the tenant, required when tenancy is enabled")
+	batchSize := flags.Int("batch-size", 1000, "orders fetched from DB at a time")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 || *batchSize <= 0 {
+		return errUsage
+	}
+	if !slices.Contains(export.Formats, *format) {
+		return fmt.Errorf("unknown export format %q, supported are %v", *format, export.Formats)
+	}
+	filter, err := exportFilter(*userID, *from, *to)
+	if err != nil {
+		return err
+	}
+	if *tenant != "" {
+		if _, err = repository.ParseTenant(*tenant); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	appConfig, err := config.GetAppConfig(configPath)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	logger, logHandle, err := applog.New(appConfig.Log)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	defer logHandle.Close()
+	if logHandle.ToStdout() {
+		// stdout may carry the export
+		logHandle.SetOutput(os.Stderr)
+	}
+	ctx := repository.WithTenant(logging.WithContext(context.Background(), logger), *tenant)
+
+	count := 0
+	err = writeOutput(*output, func(out io.Writer) error {
+		writer, writerErr := export.NewWriter(*format, out)
+		if writerErr != nil {
+			return writerErr //nolint:wrapcheck
+		}
+		shardErr := eachShard(appConfig.Db, func(conf *config.DB, _ string) error {
+			dbConn, connectErr := db.Connect(ctx, conf)
+			if connectErr != nil {
+				return connectErr //nolint:wrapcheck
+			}
+			defer func() {
+				if disconnectErr := db.Disconnect(ctx, dbConn); disconnectErr != nil {
+					logger.WithError(disconnectErr).Error("disconnect failed")
+				}
+			}()
+
+			return repository.StreamOrders(ctx, dbConn, filter, *batchSize, func(order *repository.Order) error {
+				count++
+
+				return writer.Write(order)
+			})
+		})
+		if closeErr := writer.Close(); shardErr == nil {
+			shardErr = closeErr
+		}
+
+		return shardErr
+	})
+	if err != nil {
+		return fmt.Errorf("export failed after %d orders: %w", count, err)
+	}
+	logger.WithField("orders", count).Info("export finished")
+
+	return nil
+}
+
+// exportFilter parses filter flags of the "export" command.
+func exportFilter(userID, from, to string) (repository.OrderFilter, error) {
+	var filter repository.OrderFilter
+	var err error
+	if userID != "" {
+		if filter.UserID, err = uuid.Parse(userID); err != nil {
+			return filter, fmt.Errorf("invalid user: %w", err)
+		}
+	}
+	if filter.From, err = parseTimeFlag(from); err != nil {
+		return filter, fmt.Errorf("invalid from: %w", err)
+	}
+	if filter.To, err = parseTimeFlag(to); err != nil {
+		return filter, fmt.Errorf("invalid to: %w", err)
+	}
+
+	return filter, nil
+}
+
+// parseTimeFlag parses RFC3339 time or date in UTC, empty value is zero time.
+func parseTimeFlag(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		return date, nil
+	}
+
+	return time.Parse(time.RFC3339, value) //nolint:wrapcheck
+}
+
+// writeOutput runs write on stdout for "-" or on file path. The file is written aside and
+// renamed into place when write succeeds, so a failed export leaves no partial file behind.
+func writeOutput(path string, write func(out io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+
+	if err = write(file); err != nil {
+		_ = file.Close()
+
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	return os.Rename(file.Name(), path) //nolint:wrapcheck
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	"github.com/Sugar-pack/orders-manager/internal/db"
+	"github.com/Sugar-pack/orders-manager/internal/importer"
+	applog "github.com/Sugar-pack/orders-manager/internal/logger"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+)
+
+// runImport loads orders of the input file of the "import" command with COPY.
+func runImport(configPath string, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", "", "input format, csv or ndjson (default by file extension, ndjson otherwise)")
+	tenant := flags.String("tenant", "", "import orders of the tenant, required when tenancy is enabled")
+	chunkSize := flags.Int("chunk-size", 10000, "rows loaded with a single COPY")
+	checkpoint := flags.String("checkpoint", "", "file recording progress (default <input>.checkpoint)")
+	rejectsPath := flags.String("rejects", "", "file receiving rejected rows as NDJSON (default <input>.rejects)")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 || *chunkSize <= 0 {
+		return errUsage
+	}
+	inputPath := flags.Arg(0)
+	if *format == "" {
+		*format = importer.FormatNDJSON
+		if strings.EqualFold(filepath.Ext(inputPath), ".csv") {
+			*format = importer.FormatCSV
+		}
+	}
+	if *checkpoint == "" {
+		*checkpoint = inputPath + ".checkpoint"
+	}
+	if *rejectsPath == "" {
+		*rejectsPath = inputPath + ".rejects"
+	}
+	if *tenant != "" {
+		if _, err := repository.ParseTenant(*tenant); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	appConfig, err := config.GetAppConfig(configPath)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	logger, logHandle, err := applog.New(appConfig.Log)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	defer logHandle.Close()
+	ctx := repository.WithTenant(logging.WithContext(context.Background(), logger), *tenant)
+
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	defer input.Close()
+	reader, err := importer.NewReader(*format, input)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	rejects, err := os.OpenFile(*rejectsPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	defer rejects.Close()
+
+	// COPY needs native pgx connections whatever db.backend is
+	shards := appConfig.Db.Shards()
+	repos := make([]*repository.PgxRepository, 0, len(shards))
+	for _, shardConf := range shards {
+		pool, openErr := db.OpenPool(ctx, shardConf)
+		if openErr != nil {
+			return openErr //nolint:wrapcheck
+		}
+		defer pool.Close()
+		if openErr = db.PingPool(ctx, pool, shardConf); openErr != nil {
+			return openErr //nolint:wrapcheck
+		}
+		repos = append(repos, repository.NewPgxRepository(pool))
+	}
+	load := func(ctx context.Context, shard int, orders []*repository.Order) (int64, error) {
+		return repos[shard].ImportOrders(ctx, orders)
+	}
+
+	report, err := importer.Import(ctx, reader, load, rejects, importer.Options{
+		Source:     filepath.Base(inputPath),
+		ChunkSize:  *chunkSize,
+		Shards:     len(shards),
+		Checkpoint: *checkpoint,
+	})
+	fmt.Printf("rows: %d, imported: %d, already stored: %d, rejected: %d\n",
+		report.Rows, report.Imported, report.Existing, report.Rejected)
+	if report.Rejected > 0 {
+		fmt.Printf("rejected rows are listed in %s\n", *rejectsPath)
+	}
+	if err != nil {
+		return fmt.Errorf("import interrupted, run it again to resume from %s: %w", *checkpoint, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+// Package auth authenticates callers by bearer JWTs and authorizes access to orders and transactions.
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Subject string
+	Roles   []string
+	Tenant  string // tenant the principal may act for, empty when it is bound to none
+}
+
+// HasRole reports whether principal has role.
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+type principalCtx struct{}
+
+// WithPrincipal puts principal to the context.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalCtx{}, principal)
+}
+
+// PrincipalFromContext extracts principal from the context.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalCtx{}).(*Principal)
+
+	return principal, ok
+}
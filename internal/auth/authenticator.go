@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+const (
+	DefaultRolesClaim      = "roles"
+	DefaultTenantClaim     = "tenant"
+	DefaultCoordinatorRole = "coordinator"
+	DefaultAdminRole       = "admin"
+)
+
+var ErrNoSubject = errors.New("token has no subject")
+
+var validMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+	"HS256", "HS384", "HS512",
+}
+
+// Authenticator turns bearer tokens and verified client certificates into principals.
+type Authenticator struct {
+	keys            keySet
+	parser          *jwt.Parser
+	rolesClaim      string
+	tenantClaim     string
+	coordinatorRole string
+	coordinatorCNs  []string
+	clientTenants   map[string]string
+}
+
+// NewAuthenticator loads verifying keys described by conf.
+func NewAuthenticator(conf *config.Auth) (*Authenticator, error) {
+	keys, err := loadKeys(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(validMethods),
+		jwt.WithExpirationRequired(),
+	}
+	if conf.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(conf.Issuer))
+	}
+	if conf.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(conf.Audience))
+	}
+
+	rolesClaim := conf.RolesClaim
+	if rolesClaim == "" {
+		rolesClaim = DefaultRolesClaim
+	}
+	tenantClaim := conf.TenantClaim
+	if tenantClaim == "" {
+		tenantClaim = DefaultTenantClaim
+	}
+	clientTenants := make(map[string]string, len(conf.ClientTenants))
+	for _, client := range conf.ClientTenants {
+		clientTenants[client.CommonName] = client.Tenant
+	}
+
+	return &Authenticator{
+		keys:            keys,
+		parser:          jwt.NewParser(parserOpts...),
+		rolesClaim:      rolesClaim,
+		tenantClaim:     tenantClaim,
+		coordinatorRole: coordinatorRole(conf),
+		coordinatorCNs:  conf.CoordinatorClientCNs,
+		clientTenants:   clientTenants,
+	}, nil
+}
+
+func coordinatorRole(conf *config.Auth) string {
+	if conf.CoordinatorRole == "" {
+		return DefaultCoordinatorRole
+	}
+
+	return conf.CoordinatorRole
+}
+
+func adminRole(conf *config.Auth) string {
+	if conf.AdminRole == "" {
+		return DefaultAdminRole
+	}
+
+	return conf.AdminRole
+}
+
+// Authenticate validates token and returns principal it was issued to.
+func (a *Authenticator) Authenticate(token string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(token, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		return a.keys.lookup(kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	if subject == "" {
+		return nil, ErrNoSubject
+	}
+
+	tenant, _ := claims[a.tenantClaim].(string)
+
+	return &Principal{
+		Subject: subject,
+		Roles:   rolesFromClaim(claims[a.rolesClaim]),
+		Tenant:  tenant,
+	}, nil
+}
+
+// PrincipalFromClientCN returns coordinator principal for clients authenticated by TLS certificate,
+// its tenant is taken from config.Auth.ClientTenants.
+func (a *Authenticator) PrincipalFromClientCN(commonName string) (*Principal, bool) {
+	if commonName == "" || !slices.Contains(a.coordinatorCNs, commonName) {
+		return nil, false
+	}
+
+	return &Principal{
+		Subject: commonName,
+		Roles:   []string{a.coordinatorRole},
+		Tenant:  a.clientTenants[commonName],
+	}, true
+}
+
+// rolesFromClaim accepts both list of roles and space separated string, as in "scope" claim.
+func rolesFromClaim(claim any) []string {
+	switch value := claim.(type) {
+	case string:
+		return strings.Fields(value)
+	case []any:
+		roles := make([]string, 0, len(value))
+		for _, role := range value {
+			if s, ok := role.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+
+		return roles
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+const testSecret = "test-secret"
+
+func signHS256(t *testing.T, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   "user-1",
+		"iss":   "issuer",
+		"aud":   "orders",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"coordinator"},
+	}
+}
+
+func staticConf() *config.Auth {
+	return &config.Auth{
+		StaticKeys: []config.StaticKey{{KeyID: "hs", Secret: testSecret}},
+		Issuer:     "issuer",
+		Audience:   "orders",
+	}
+}
+
+func TestNewAuthenticator_NoKeys(t *testing.T) {
+	_, err := NewAuthenticator(&config.Auth{})
+	assert.ErrorIs(t, err, ErrNoVerifyingKeys)
+}
+
+func TestAuthenticate_StaticSecret(t *testing.T) {
+	authenticator, err := NewAuthenticator(staticConf())
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+
+	principal, err := authenticator.Authenticate(signHS256(t, "hs", validClaims()))
+	assert.NoError(t, err)
+	if assert.NotNil(t, principal) {
+		assert.Equal(t, "user-1", principal.Subject)
+		assert.True(t, principal.HasRole("coordinator"))
+		assert.Empty(t, principal.Tenant, "token without tenant claim is bound to no tenant")
+	}
+
+	claims := validClaims()
+	claims[DefaultTenantClaim] = "acme"
+	principal, err = authenticator.Authenticate(signHS256(t, "hs", claims))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "acme", principal.Tenant)
+	}
+
+	// single configured key is used for tokens without kid
+	_, err = authenticator.Authenticate(signHS256(t, "", validClaims()))
+	assert.NoError(t, err)
+}
+
+func TestAuthenticate_Invalid(t *testing.T) {
+	authenticator, err := NewAuthenticator(staticConf())
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	noExp := validClaims()
+	delete(noExp, "exp")
+	wrongIssuer := validClaims()
+	wrongIssuer["iss"] = "someone"
+	wrongAudience := validClaims()
+	wrongAudience["aud"] = "payments"
+	noSubject := validClaims()
+	delete(noSubject, "sub")
+
+	tests := map[string]string{
+		"expired":        signHS256(t, "hs", expired),
+		"no exp":         signHS256(t, "hs", noExp),
+		"wrong issuer":   signHS256(t, "hs", wrongIssuer),
+		"wrong audience": signHS256(t, "hs", wrongAudience),
+		"no subject":     signHS256(t, "hs", noSubject),
+		"unknown kid":    signHS256(t, "other", validClaims()),
+		"garbage":        "not a token",
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := authenticator.Authenticate(token)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAuthenticate_JWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	jwks, err := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "rsa-1",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	if err != nil {
+		t.Fatalf("marshal jwks: %v", err)
+	}
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err = os.WriteFile(jwksFile, jwks, 0o600); err != nil {
+		t.Fatalf("write jwks: %v", err)
+	}
+
+	authenticator, err := NewAuthenticator(&config.Auth{JWKSFile: jwksFile, RolesClaim: "scope"})
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+
+	claims := jwt.MapClaims{"sub": "svc", "exp": time.Now().Add(time.Hour).Unix(), "scope": "coordinator audit"}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "rsa-1"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	principal, err := authenticator.Authenticate(signed)
+	assert.NoError(t, err)
+	if assert.NotNil(t, principal) {
+		assert.Equal(t, []string{"coordinator", "audit"}, principal.Roles)
+	}
+
+	// HMAC token must not be verified with RSA public key
+	_, err = authenticator.Authenticate(signHS256(t, "rsa-1", claims))
+	assert.Error(t, err)
+}
+
+func TestAuthenticate_StaticPublicKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	authenticator, err := NewAuthenticator(&config.Auth{
+		StaticKeys: []config.StaticKey{{KeyID: "pem", PublicKeyFile: keyFile}},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "u", "exp": time.Now().Add(time.Hour).Unix()})
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	principal, err := authenticator.Authenticate(signed)
+	assert.NoError(t, err)
+	if assert.NotNil(t, principal) {
+		assert.Empty(t, principal.Roles)
+	}
+}
+
+func TestPrincipalFromClientCN(t *testing.T) {
+	conf := staticConf()
+	conf.CoordinatorClientCNs = []string{"coordinator-1", "coordinator-2"}
+	conf.ClientTenants = []config.ClientTenant{{CommonName: "coordinator-1", Tenant: "acme"}}
+	authenticator, err := NewAuthenticator(conf)
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+
+	principal, ok := authenticator.PrincipalFromClientCN("coordinator-1")
+	assert.True(t, ok)
+	if assert.NotNil(t, principal) {
+		assert.True(t, principal.HasRole(DefaultCoordinatorRole))
+		assert.Equal(t, "acme", principal.Tenant)
+	}
+	principal, ok = authenticator.PrincipalFromClientCN("coordinator-2")
+	assert.True(t, ok)
+	if assert.NotNil(t, principal) {
+		assert.Empty(t, principal.Tenant)
+	}
+
+	_, ok = authenticator.PrincipalFromClientCN("stranger")
+	assert.False(t, ok)
+}
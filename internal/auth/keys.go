@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+var (
+	ErrUnknownKey      = errors.New("unknown signing key")
+	ErrUnsupportedKey  = errors.New("unsupported key type")
+	ErrNoVerifyingKeys = errors.New("neither jwks_file nor static_keys are configured")
+)
+
+// keySet maps key id to a key verifying token signatures.
+type keySet map[string]any
+
+func (k keySet) lookup(kid string) (any, error) {
+	if key, ok := k[kid]; ok {
+		return key, nil
+	}
+	// tokens without kid are accepted only when there is no choice
+	if kid == "" && len(k) == 1 {
+		for _, key := range k {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: kid %q", ErrUnknownKey, kid)
+}
+
+func loadKeys(conf *config.Auth) (keySet, error) {
+	keys := make(keySet)
+	if conf.JWKSFile != "" {
+		data, err := os.ReadFile(conf.JWKSFile)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // path is already in the error
+		}
+		if err = keys.addJWKS(data); err != nil {
+			return nil, fmt.Errorf("parse jwks %s: %w", conf.JWKSFile, err)
+		}
+	}
+	for _, static := range conf.StaticKeys {
+		key, err := loadStaticKey(static)
+		if err != nil {
+			return nil, fmt.Errorf("load static key %q: %w", static.KeyID, err)
+		}
+		keys[static.KeyID] = key
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoVerifyingKeys
+	}
+
+	return keys, nil
+}
+
+func loadStaticKey(static config.StaticKey) (any, error) {
+	if static.Secret != "" {
+		return []byte(static.Secret), nil
+	}
+	data, err := os.ReadFile(static.PublicKeyFile)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // path is already in the error
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // too simple to wrap
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k keySet) addJWKS(data []byte) error {
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return err //nolint:wrapcheck // wrapped by caller
+	}
+	for _, key := range jwks.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.publicKey()
+		if err != nil {
+			return fmt.Errorf("key %q: %w", key.Kid, err)
+		}
+		k[key.Kid] = publicKey
+	}
+
+	return nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("%w: curve %q", ErrUnsupportedKey, k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("%w: curve %q", ErrUnsupportedKey, k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // too simple to wrap
+		}
+
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("%w: kty %q", ErrUnsupportedKey, k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // too simple to wrap
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
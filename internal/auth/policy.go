@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+var (
+	ErrUnauthenticated = errors.New("caller is not authenticated")
+	ErrForbidden       = errors.New("access denied")
+)
+
+// Policy decides which principals may access orders and transactions.
+// Nil Policy allows everything, it is used when authentication is disabled.
+type Policy struct {
+	coordinatorRole string
+	adminRole       string
+}
+
+// NewPolicy creates Policy.
+func NewPolicy(conf *config.Auth) *Policy {
+	return &Policy{coordinatorRole: coordinatorRole(conf), adminRole: adminRole(conf)}
+}
+
+// AuthorizeConfirmation allows only coordinators to commit or rollback prepared transactions.
+func (p *Policy) AuthorizeConfirmation(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if !principal.HasRole(p.coordinatorRole) {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// AuthorizeAdmin allows only administrators to manage webhooks.
+func (p *Policy) AuthorizeAdmin(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if !principal.HasRole(p.adminRole) {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// AuthorizeOrderRead allows coordinators to read any order and end users to read only their own orders.
+func (p *Policy) AuthorizeOrderRead(ctx context.Context, userID uuid.UUID) error {
+	if p == nil {
+		return nil
+	}
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if principal.HasRole(p.coordinatorRole) || principal.Subject == userID.String() {
+		return nil
+	}
+
+	return ErrForbidden
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+func TestPolicy_Nil(t *testing.T) {
+	var policy *Policy
+	ctx := context.Background()
+	assert.NoError(t, policy.AuthorizeConfirmation(ctx))
+	assert.NoError(t, policy.AuthorizeOrderRead(ctx, uuid.New()))
+	assert.NoError(t, policy.AuthorizeAdmin(ctx))
+}
+
+func TestPolicy_AuthorizeConfirmation(t *testing.T) {
+	policy := NewPolicy(&config.Auth{})
+
+	assert.ErrorIs(t, policy.AuthorizeConfirmation(context.Background()), ErrUnauthenticated)
+
+	user := WithPrincipal(context.Background(), &Principal{Subject: uuid.NewString()})
+	assert.ErrorIs(t, policy.AuthorizeConfirmation(user), ErrForbidden)
+
+	coordinator := WithPrincipal(context.Background(), &Principal{Subject: "tx-coordinator", Roles: []string{"coordinator"}})
+	assert.NoError(t, policy.AuthorizeConfirmation(coordinator))
+}
+
+func TestPolicy_AuthorizeAdmin(t *testing.T) {
+	policy := NewPolicy(&config.Auth{})
+
+	assert.ErrorIs(t, policy.AuthorizeAdmin(context.Background()), ErrUnauthenticated)
+
+	coordinator := WithPrincipal(context.Background(), &Principal{Subject: "tx-coordinator", Roles: []string{"coordinator"}})
+	assert.ErrorIs(t, policy.AuthorizeAdmin(coordinator), ErrForbidden)
+
+	admin := WithPrincipal(context.Background(), &Principal{Subject: "operator", Roles: []string{"admin"}})
+	assert.NoError(t, policy.AuthorizeAdmin(admin))
+}
+
+func TestPolicy_AuthorizeOrderRead(t *testing.T) {
+	policy := NewPolicy(&config.Auth{CoordinatorRole: "tx"})
+	owner := uuid.New()
+
+	assert.ErrorIs(t, policy.AuthorizeOrderRead(context.Background(), owner), ErrUnauthenticated)
+
+	ownerCtx := WithPrincipal(context.Background(), &Principal{Subject: owner.String()})
+	assert.NoError(t, policy.AuthorizeOrderRead(ownerCtx, owner))
+
+	strangerCtx := WithPrincipal(context.Background(), &Principal{Subject: uuid.NewString()})
+	assert.ErrorIs(t, policy.AuthorizeOrderRead(strangerCtx, owner), ErrForbidden)
+
+	coordinatorCtx := WithPrincipal(context.Background(), &Principal{Subject: "svc", Roles: []string{"tx"}})
+	assert.NoError(t, policy.AuthorizeOrderRead(coordinatorCtx, owner))
+}
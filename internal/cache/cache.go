@@ -0,0 +1,235 @@
+// Package cache keeps committed orders read by GetOrder in front of a repository.
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+	"github.com/Sugar-pack/orders-manager/internal/tracing"
+)
+
+// Backend stores orders by key. Implementations must be safe for concurrent use.
+type Backend interface {
+	Get(ctx context.Context, key string) (*repository.Order, bool)
+	Set(ctx context.Context, key string, order *repository.Order)
+	Delete(ctx context.Context, key string)
+}
+
+// New creates Backend configured by conf.
+func New(conf *config.Cache) (Backend, error) {
+	switch conf.Backend {
+	case config.CacheLRU:
+		return NewLRU[string, *repository.Order](conf.MaxEntries, conf.TTL), nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", conf.Backend)
+	}
+}
+
+type bypassKey struct{}
+
+// WithBypass makes GetOrder of the call read the repository and refresh the cached order.
+func WithBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// Bypassed reports whether ctx is marked by WithBypass.
+func Bypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassKey{}).(bool)
+
+	return bypass
+}
+
+// pendingKey identifies a prepared transaction, transactions of tenants are confirmed separately.
+type pendingKey struct {
+	tenant string
+	txID   uuid.UUID
+}
+
+// Repository caches orders returned by GetOrder of the wrapped repository.
+// Only committed orders are cached: GetOrder does not see prepared transactions, nothing is cached on prepare
+// and missing orders are not cached, so an order shows up as soon as its transaction is committed.
+// Commit of a transaction drops the cached order it wrote. Orders changed through another replica
+// stay stale until their TTL runs out.
+type Repository struct {
+	repo    repository.Shard
+	backend Backend
+	// pending maps prepared transactions to keys of orders they write
+	pending *LRU[pendingKey, string]
+	// generation grows on every invalidation, a read started before one does not fill the cache
+	generation atomic.Uint64
+	hits       metric.Int64Counter
+	misses     metric.Int64Counter
+}
+
+// NewRepository wraps repo with backend. maxPending bounds prepared transactions tracked for invalidation.
+// Hits and misses are counted by instruments of provider, it must be the provider metrics are exported from.
+func NewRepository(repo repository.Shard, backend Backend, maxPending int, provider metric.MeterProvider) (*Repository, error) {
+	meter := provider.Meter(tracing.TracerName)
+	hits, err := meter.Int64Counter("orders.cache.hits",
+		metric.WithDescription("Number of GetOrder calls served from the cache"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create cache hits counter failed: %w", err)
+	}
+	misses, err := meter.Int64Counter("orders.cache.misses",
+		metric.WithDescription("Number of GetOrder calls served from the repository"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create cache misses counter failed: %w", err)
+	}
+
+	return &Repository{
+		repo:    repo,
+		backend: backend,
+		pending: NewLRU[pendingKey, string](maxPending, 0),
+		hits:    hits,
+		misses:  misses,
+	}, nil
+}
+
+// orderKey is a key of order id of the tenant of ctx, tenants do not see orders of each other.
+func orderKey(ctx context.Context, id uuid.UUID) string {
+	return repository.TenantFromContext(ctx) + "/" + id.String()
+}
+
+// NewIDs keeps IDs assigned by the wrapped repository.
+func (r *Repository) NewIDs(userID uuid.UUID) (orderID, txID uuid.UUID) {
+	if assigner, ok := r.repo.(repository.IDAssigner); ok {
+		return assigner.NewIDs(userID)
+	}
+
+	return uuid.New(), uuid.New()
+}
+
+func (r *Repository) PrepareInsertOrder(ctx context.Context, order *repository.Order, txID uuid.UUID) error {
+	err := r.repo.PrepareInsertOrder(ctx, order, txID)
+	if err == nil {
+		r.pending.Set(ctx, pendingKey{tenant: repository.TenantFromContext(ctx), txID: txID}, orderKey(ctx, order.ID))
+	}
+
+	return err //nolint:wrapcheck
+}
+
+func (r *Repository) CommitInsertTransaction(ctx context.Context, txID uuid.UUID) error {
+	err := r.repo.CommitInsertTransaction(ctx, txID)
+	if err == nil {
+		r.finish(ctx, txID, true)
+	}
+
+	return err //nolint:wrapcheck
+}
+
+func (r *Repository) RollbackInsertTransaction(ctx context.Context, txID uuid.UUID) error {
+	err := r.repo.RollbackInsertTransaction(ctx, txID)
+	if err == nil {
+		r.finish(ctx, txID, false)
+	}
+
+	return err //nolint:wrapcheck
+}
+
+// finish forgets prepared transaction and drops the order it wrote when it is committed.
+func (r *Repository) finish(ctx context.Context, txID uuid.UUID, committed bool) {
+	key := pendingKey{tenant: repository.TenantFromContext(ctx), txID: txID}
+	written, ok := r.pending.Get(ctx, key)
+	if !ok {
+		return
+	}
+	r.pending.Delete(ctx, key)
+	if committed {
+		r.generation.Add(1)
+		r.backend.Delete(ctx, written)
+	}
+}
+
+// GetOrder returns cached order unless the call bypasses the cache or requires read-your-writes,
+// then it reads the repository and refreshes the cached order.
+func (r *Repository) GetOrder(ctx context.Context, id uuid.UUID) (*repository.Order, error) {
+	key := orderKey(ctx, id)
+	bypass := Bypassed(ctx) || repository.ReadYourWritesRequired(ctx)
+	if !bypass {
+		if order, ok := r.backend.Get(ctx, key); ok {
+			r.hits.Add(ctx, 1)
+			orderCopy := *order
+
+			return &orderCopy, nil
+		}
+	}
+	r.misses.Add(ctx, 1, metric.WithAttributes(attribute.Bool("cache.bypass", bypass)))
+
+	generation := r.generation.Load()
+	order, err := r.repo.GetOrder(ctx, id)
+	if err != nil {
+		return order, err //nolint:wrapcheck
+	}
+	if r.generation.Load() == generation {
+		orderCopy := *order
+		r.backend.Set(ctx, key, &orderCopy)
+	}
+
+	return order, nil
+}
+
+// GetOrders returns cached orders and reads the rest from the repository at once, see GetOrder.
+func (r *Repository) GetOrders(ctx context.Context, ids []uuid.UUID) ([]*repository.Order, error) {
+	bypass := Bypassed(ctx) || repository.ReadYourWritesRequired(ctx)
+	orders := make([]*repository.Order, 0, len(ids))
+	lookup := ids
+	if !bypass {
+		lookup = nil
+		for _, id := range ids {
+			if order, ok := r.backend.Get(ctx, orderKey(ctx, id)); ok {
+				orderCopy := *order
+				orders = append(orders, &orderCopy)
+			} else {
+				lookup = append(lookup, id)
+			}
+		}
+		if hits := len(ids) - len(lookup); hits > 0 {
+			r.hits.Add(ctx, int64(hits))
+		}
+		if len(lookup) == 0 {
+			return orders, nil
+		}
+	}
+	r.misses.Add(ctx, int64(len(lookup)), metric.WithAttributes(attribute.Bool("cache.bypass", bypass)))
+
+	generation := r.generation.Load()
+	found, err := r.repo.GetOrders(ctx, lookup)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	if r.generation.Load() == generation {
+		for _, order := range found {
+			orderCopy := *order
+			r.backend.Set(ctx, orderKey(ctx, order.ID), &orderCopy)
+		}
+	}
+	orders = append(orders, found...)
+	repository.SortByIDs(orders, ids)
+
+	return orders, nil
+}
+
+// ListOrders reads the repository, lists are not cached.
+func (r *Repository) ListOrders(ctx context.Context, userID uuid.UUID, after repository.OrderCursor, limit int,
+) ([]*repository.Order, error) {
+	return r.repo.ListOrders(ctx, userID, after, limit) //nolint:wrapcheck
+}
+
+func (r *Repository) CommittedOrder(ctx context.Context, txID uuid.UUID) (uuid.UUID, error) {
+	return r.repo.CommittedOrder(ctx, txID) //nolint:wrapcheck
+}
+
+func (r *Repository) ListPreparedTransactions(ctx context.Context, preparedBefore time.Time,
+) ([]repository.PreparedTransaction, error) {
+	return r.repo.ListPreparedTransactions(ctx, preparedBefore) //nolint:wrapcheck
+}
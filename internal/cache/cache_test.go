@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	"github.com/Sugar-pack/orders-manager/internal/mock"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+)
+
+// shard adds listing of prepared transactions to the mock repository.
+type shard struct {
+	*mock.OrderRepoWith2PC
+}
+
+func (shard) ListPreparedTransactions(context.Context, time.Time) ([]repository.PreparedTransaction, error) {
+	return nil, nil
+}
+
+func (shard) CommittedOrder(context.Context, uuid.UUID) (uuid.UUID, error) {
+	return uuid.Nil, sql.ErrNoRows
+}
+
+func newCached(t *testing.T, provider metric.MeterProvider) (*Repository, *mock.OrderRepoWith2PC) {
+	t.Helper()
+	repo := &mock.OrderRepoWith2PC{}
+	t.Cleanup(func() { repo.AssertExpectations(t) })
+	backend, err := New(&config.Cache{Backend: config.CacheLRU, MaxEntries: 10, TTL: time.Minute})
+	if err != nil {
+		t.Fatalf("new backend: %v", err)
+	}
+
+	cached, err := NewRepository(shard{repo}, backend, 10, provider)
+	if err != nil {
+		t.Fatalf("new repository: %v", err)
+	}
+
+	return cached, repo
+}
+
+func recorded(t *testing.T, reader *sdkmetric.ManualReader, name string) int64 {
+	t.Helper()
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	var total int64
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == name {
+				for _, point := range sum.DataPoints {
+					total += point.Value
+				}
+			}
+		}
+	}
+
+	return total
+}
+
+func TestRepository_GetOrder(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	cached, repo := newCached(t, sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	ctx := context.Background()
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+	repo.On("GetOrder", testify.Anything, order.ID).Return(order, nil).Once()
+
+	for range 2 {
+		got, err := cached.GetOrder(ctx, order.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, order, got)
+	}
+	got, _ := cached.GetOrder(ctx, order.ID)
+	got.Label = "changed by caller"
+	got, _ = cached.GetOrder(ctx, order.ID)
+	assert.Equal(t, "label", got.Label, "callers get copies")
+	assert.Equal(t, int64(3), recorded(t, reader, "orders.cache.hits"))
+	assert.Equal(t, int64(1), recorded(t, reader, "orders.cache.misses"))
+
+	// bypass and read-your-writes read the repository
+	repo.On("GetOrder", testify.Anything, order.ID).Return(order, nil).Twice()
+	_, err := cached.GetOrder(WithBypass(ctx), order.ID)
+	assert.NoError(t, err)
+	_, err = cached.GetOrder(repository.WithReadYourWrites(ctx), order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), recorded(t, reader, "orders.cache.misses"))
+
+	// orders of other tenants are cached apart
+	repo.On("GetOrder", testify.Anything, order.ID).Return(&repository.Order{}, sql.ErrNoRows).Twice()
+	for range 2 {
+		_, err = cached.GetOrder(repository.WithTenant(ctx, "acme"), order.ID)
+		assert.ErrorIs(t, err, sql.ErrNoRows, "missing orders are not cached")
+	}
+}
+
+func TestRepository_Transactions(t *testing.T) {
+	cached, repo := newCached(t, noop.NewMeterProvider())
+	ctx := context.Background()
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+	committedTx, rolledBackTx := uuid.New(), uuid.New()
+	repo.On("GetOrder", testify.Anything, order.ID).Return(order, nil).Once()
+	_, err := cached.GetOrder(ctx, order.ID)
+	assert.NoError(t, err)
+
+	// prepared changes are not cached, rollback keeps the cached order
+	repo.On("PrepareInsertOrder", ctx, order, rolledBackTx).Return(nil).Once()
+	repo.On("RollbackInsertTransaction", ctx, rolledBackTx).Return(nil).Once()
+	assert.NoError(t, cached.PrepareInsertOrder(ctx, order, rolledBackTx))
+	assert.NoError(t, cached.RollbackInsertTransaction(ctx, rolledBackTx))
+	_, err = cached.GetOrder(ctx, order.ID)
+	assert.NoError(t, err)
+
+	// commit drops the cached order
+	repo.On("PrepareInsertOrder", ctx, order, committedTx).Return(nil).Once()
+	repo.On("CommitInsertTransaction", ctx, committedTx).Return(nil).Once()
+	assert.NoError(t, cached.PrepareInsertOrder(ctx, order, committedTx))
+	assert.NoError(t, cached.CommitInsertTransaction(ctx, committedTx))
+	repo.On("GetOrder", testify.Anything, order.ID).Return(order, nil).Once()
+	_, err = cached.GetOrder(ctx, order.ID)
+	assert.NoError(t, err)
+}
+
+func TestRepository_GetOrderRacingCommit(t *testing.T) {
+	cached, repo := newCached(t, noop.NewMeterProvider())
+	ctx := context.Background()
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+	txID := uuid.New()
+	repo.On("PrepareInsertOrder", ctx, order, txID).Return(nil).Once()
+	repo.On("CommitInsertTransaction", ctx, txID).Return(nil).Once()
+	assert.NoError(t, cached.PrepareInsertOrder(ctx, order, txID))
+
+	// read started before the commit does not fill the cache with what it saw
+	repo.On("GetOrder", testify.Anything, order.ID).Run(func(testify.Arguments) {
+		assert.NoError(t, cached.CommitInsertTransaction(ctx, txID))
+	}).Return(order, nil).Once()
+	_, err := cached.GetOrder(ctx, order.ID)
+	assert.NoError(t, err)
+
+	repo.On("GetOrder", testify.Anything, order.ID).Return(order, nil).Once()
+	_, err = cached.GetOrder(ctx, order.ID)
+	assert.NoError(t, err)
+}
+
+func TestRepository_NewIDs(t *testing.T) {
+	sharded := repository.NewShardedRepository(shard{&mock.OrderRepoWith2PC{}}, shard{&mock.OrderRepoWith2PC{}})
+	backend := NewLRU[string, *repository.Order](10, time.Minute)
+	cached, err := NewRepository(sharded, backend, 10, noop.NewMeterProvider())
+	assert.NoError(t, err)
+
+	orderID, txID := cached.NewIDs(uuid.New())
+	_, ok := repository.ShardOf(orderID)
+	assert.True(t, ok, "IDs of sharded repository carry the shard")
+	_, ok = repository.ShardOf(txID)
+	assert.True(t, ok)
+}
+
+func TestRepository_GetOrders(t *testing.T) {
+	cached, repo := newCached(t, noop.NewMeterProvider())
+	ctx := context.Background()
+	first := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "first", CreatedAt: time.Now().UTC()}
+	second := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "second", CreatedAt: time.Now().UTC()}
+	missing := uuid.New()
+	repo.On("GetOrder", testify.Anything, first.ID).Return(first, nil).Once()
+	_, err := cached.GetOrder(ctx, first.ID)
+	assert.NoError(t, err)
+
+	// cached orders are not read again
+	repo.On("GetOrders", testify.Anything, []uuid.UUID{second.ID, missing}).
+		Return([]*repository.Order{second}, nil).Once()
+	orders, err := cached.GetOrders(ctx, []uuid.UUID{second.ID, missing, first.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, []*repository.Order{second, first}, orders)
+
+	orders, err = cached.GetOrders(ctx, []uuid.UUID{first.ID, second.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, []*repository.Order{first, second}, orders)
+
+	repo.On("GetOrders", testify.Anything, []uuid.UUID{first.ID}).Return(nil, sql.ErrConnDone).Once()
+	_, err = cached.GetOrders(WithBypass(ctx), []uuid.UUID{first.ID})
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+}
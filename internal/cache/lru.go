@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU keeps at most maxEntries values, evicting the least recently used one, and forgets values after ttl.
+// It is safe for concurrent use.
+type LRU[K comparable, V any] struct {
+	maxEntries int
+	ttl        time.Duration // zero keeps values until evicted
+	now        func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // front is the most recently used
+	entries map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// NewLRU creates LRU. maxEntries must be positive.
+func NewLRU[K comparable, V any](maxEntries int, ttl time.Duration) *LRU[K, V] {
+	return &LRU[K, V]{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		now:        time.Now,
+		order:      list.New(),
+		entries:    make(map[K]*list.Element, maxEntries),
+	}
+}
+
+// Get returns value of key unless it is missing or expired.
+func (c *LRU[K, V]) Get(_ context.Context, key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	element, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	entry := element.Value.(*lruEntry[K, V]) //nolint:forcetypeassert // only entries are stored
+	if c.ttl > 0 && !c.now().Before(entry.expires) {
+		c.remove(element)
+
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+
+	return entry.value, true
+}
+
+// Set stores value of key, evicting the least recently used value when LRU is full.
+func (c *LRU[K, V]) Set(_ context.Context, key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*lruEntry[K, V]) //nolint:forcetypeassert // only entries are stored
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(element)
+
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, expires: expires})
+	for c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+// Delete forgets value of key.
+func (c *LRU[K, V]) Delete(_ context.Context, key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+}
+
+// Len returns number of stored values, expired ones included until they are evicted.
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *LRU[K, V]) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*lruEntry[K, V]).key) //nolint:forcetypeassert // only entries are stored
+}
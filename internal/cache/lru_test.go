@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	lru := NewLRU[string, int](2, 0)
+	lru.Set(ctx, "a", 1)
+	lru.Set(ctx, "b", 2)
+	_, _ = lru.Get(ctx, "a")
+	lru.Set(ctx, "c", 3)
+
+	_, ok := lru.Get(ctx, "b")
+	assert.False(t, ok, "b is the least recently used")
+	value, ok := lru.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, 2, lru.Len())
+
+	lru.Set(ctx, "a", 10)
+	value, _ = lru.Get(ctx, "a")
+	assert.Equal(t, 10, value)
+	assert.Equal(t, 2, lru.Len())
+
+	lru.Delete(ctx, "a")
+	_, ok = lru.Get(ctx, "a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, lru.Len())
+}
+
+func TestLRU_TTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	lru := NewLRU[string, int](10, time.Minute)
+	lru.now = func() time.Time { return now }
+	lru.Set(ctx, "a", 1)
+
+	now = now.Add(time.Minute - time.Second)
+	_, ok := lru.Get(ctx, "a")
+	assert.True(t, ok)
+
+	now = now.Add(time.Second)
+	_, ok = lru.Get(ctx, "a")
+	assert.False(t, ok, "value expires after ttl")
+	assert.Equal(t, 0, lru.Len(), "expired value is evicted")
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -9,37 +12,358 @@ import (
 
 // DB contains database and migration settings.
 type DB struct {
-	ConnString       string        `mapstructure:"conn_string"`
-	MaxOpenCons      int           `mapstructure:"max_open_cons"`
-	ConnMaxLifetime  time.Duration `mapstructure:"conn_max_lifetime"`
-	MigrationDirPath string        `mapstructure:"migration_dir_path"`
-	MigrationTable   string        `mapstructure:"migration_table"`
+	ConnString string `mapstructure:"conn_string"`
+	// Backend selects repository implementation, BackendSQL when empty.
+	Backend string `mapstructure:"backend"`
+	// ShardConnStrings are databases of shards 1..N, shard 0 is at ConnString. Orders are spread
+	// across shards by hash of user_id when set.
+	ShardConnStrings []string `mapstructure:"shard_conn_strings"`
+	// ReplicaConnStrings are read replicas of the primary at ConnString, reads go to them when set.
+	ReplicaConnStrings []string      `mapstructure:"replica_conn_strings"`
+	MaxOpenConns       int           `mapstructure:"max_open_conns"`
+	ConnMaxLifetime    time.Duration `mapstructure:"conn_max_lifetime"`
+	MigrationDirPath   string        `mapstructure:"migration_dir_path"` // empty for migrations embedded in the binary
+	MigrationTable     string        `mapstructure:"migration_table"`
+	// MigrationLockTimeout limits the wait for migrations run by another replica.
+	MigrationLockTimeout time.Duration `mapstructure:"migration_lock_timeout"`
+	// ConnectRetry is used while DB is not reachable at startup, nil for defaults.
+	ConnectRetry *Retry `mapstructure:"connect_retry"`
+}
+
+// MaxShards limits number of shards, shard number is encoded in two bytes of order and transaction IDs.
+const MaxShards = 1 << 16
+
+// Shards returns settings of every shard, they differ from d only in ConnString.
+// Without ShardConnStrings it returns d alone.
+func (d *DB) Shards() []*DB {
+	shards := []*DB{d}
+	for _, connString := range d.ShardConnStrings {
+		shard := *d
+		shard.ConnString = connString
+		shards = append(shards, &shard)
+	}
+
+	return shards
+}
+
+// Repository backends.
+const (
+	// BackendSQL goes through database/sql and sqlx, it supports read replicas.
+	BackendSQL = "sql"
+	// BackendPgx uses native pgx pool and prepares orders in a single round trip.
+	BackendPgx = "pgx"
+)
+
+// Retry configures exponential backoff between attempts.
+type Retry struct {
+	InitialInterval time.Duration `mapstructure:"initial_interval"`
+	MaxInterval     time.Duration `mapstructure:"max_interval"`
+	Multiplier      float64       `mapstructure:"multiplier"`
+	MaxElapsedTime  time.Duration `mapstructure:"max_elapsed_time"`
+}
+
+// TLS contains transport security settings of the api listeners.
+type TLS struct {
+	CertFile          string        `mapstructure:"cert_file"`
+	KeyFile           string        `mapstructure:"key_file"`
+	ClientCAFile      string        `mapstructure:"client_ca_file"`      // client certificates are verified if given
+	RequireClientCert bool          `mapstructure:"require_client_cert"` // mutual TLS, requires client_ca_file
+	ReloadInterval    time.Duration `mapstructure:"reload_interval"`     // how often files are checked for rotation
 }
 
 // API contains api settings.
 type API struct {
-	Bind string `mapstructure:"bind"`
+	Bind     string `mapstructure:"bind"`
+	HTTPBind string `mapstructure:"http_bind"` // REST/JSON gateway is disabled when empty
+	TLS      *TLS   `mapstructure:"tls"`       // plaintext when empty
+}
+
+// StaticKey is a key verifying JWT signatures, either PEM public key or HMAC secret.
+type StaticKey struct {
+	KeyID         string `mapstructure:"kid"`
+	PublicKeyFile string `mapstructure:"public_key_file"`
+	Secret        string `mapstructure:"secret"`
+}
+
+// Auth contains authentication and authorization settings.
+type Auth struct {
+	Enabled              bool        `mapstructure:"enabled"`
+	JWKSFile             string      `mapstructure:"jwks_file"`
+	StaticKeys           []StaticKey `mapstructure:"static_keys"`
+	Issuer               string      `mapstructure:"issuer"`   // not checked when empty
+	Audience             string      `mapstructure:"audience"` // not checked when empty
+	RolesClaim           string      `mapstructure:"roles_claim"`
+	CoordinatorRole      string      `mapstructure:"coordinator_role"`
+	AdminRole            string      `mapstructure:"admin_role"`             // may manage webhooks
+	CoordinatorClientCNs []string    `mapstructure:"coordinator_client_cns"` // mTLS clients trusted as coordinators
+	// TenantClaim names the claim holding tenant of the token principal, it is checked with tenancy.
+	TenantClaim   string         `mapstructure:"tenant_claim"`
+	ClientTenants []ClientTenant `mapstructure:"client_tenants"` // tenants of coordinator_client_cns
+}
+
+// ClientTenant binds mTLS client with CommonName to the tenant it may act for.
+type ClientTenant struct {
+	CommonName string `mapstructure:"common_name"`
+	Tenant     string `mapstructure:"tenant"`
+}
+
+// RateLimitRule is a token bucket: rps tokens are added per second, up to burst tokens are kept.
+type RateLimitRule struct {
+	Method string  `mapstructure:"method"` // full grpc method name, used only in method rules
+	RPS    float64 `mapstructure:"rps"`
+	Burst  int     `mapstructure:"burst"`
+}
+
+// RateLimit contains rate limiting settings. Dimensions are independent, a call has to fit all of them.
+type RateLimit struct {
+	Enabled   bool            `mapstructure:"enabled"`
+	Methods   []RateLimitRule `mapstructure:"methods"`    // limit of a method shared by all callers
+	PerClient *RateLimitRule  `mapstructure:"per_client"` // limit of every authenticated client or peer
+	PerUser   *RateLimitRule  `mapstructure:"per_user"`   // limit of every user_id from requests
+	IdleTTL   time.Duration   `mapstructure:"idle_ttl"`   // buckets of inactive clients and users are dropped
+}
+
+// Log formats.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// Log outputs, any other value is a path of file logs are appended to.
+const (
+	LogOutputStdout = "stdout"
+	LogOutputStderr = "stderr"
+)
+
+// Log contains logging settings.
+type Log struct {
+	Level    string       `mapstructure:"level"`  // trace, debug, info, warn or error
+	Format   string       `mapstructure:"format"` // LogFormatText when empty
+	Output   string       `mapstructure:"output"` // LogOutputStdout when empty
+	Payloads *LogPayloads `mapstructure:"payloads"`
+}
+
+// LogPayloads selects calls whose requests and responses are logged at debug level.
+type LogPayloads struct {
+	Methods      []string `mapstructure:"methods"`       // full grpc method names, e.g. /pb.OrdersManagerService/GetOrder
+	MaskedFields []string `mapstructure:"masked_fields"` // proto names of fields whose values are not logged
+}
+
+// Tenancy contains multi-tenancy settings.
+type Tenancy struct {
+	Enabled bool `mapstructure:"enabled"` // calls must name their tenant, orders of other tenants are invisible
+}
+
+// Retention policies of old order partitions.
+const (
+	// RetentionDetach detaches old partitions, they stay as standalone tables next to orders.
+	RetentionDetach = "detach"
+	// RetentionArchive detaches old partitions and moves them to Partitions.ArchiveSchema.
+	RetentionArchive = "archive"
+)
+
+// Partitions contains settings of monthly orders partitions maintenance.
+type Partitions struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	Premake         int           `mapstructure:"premake"`          // months created ahead of the current one
+	Retention       int           `mapstructure:"retention"`        // months kept before the current one, 0 keeps all
+	RetentionPolicy string        `mapstructure:"retention_policy"` // RetentionDetach or RetentionArchive
+	ArchiveSchema   string        `mapstructure:"archive_schema"`   // schema of archived partitions
+	CheckInterval   time.Duration `mapstructure:"check_interval"`
+	LockTimeout     time.Duration `mapstructure:"lock_timeout"` // DDL gives up waiting for locks held by prepared transactions
+}
+
+// Cache backends.
+const (
+	// CacheLRU keeps orders in memory of the process, evicting the least recently used ones.
+	CacheLRU = "lru"
+)
+
+// Cache contains settings of the GetOrder cache.
+type Cache struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Backend    string        `mapstructure:"backend"`     // CacheLRU
+	MaxEntries int           `mapstructure:"max_entries"` // orders kept, the least recently used ones are evicted
+	TTL        time.Duration `mapstructure:"ttl"`         // bounds staleness of orders changed through another replica
+}
+
+// Exporters of spans and metrics.
+const (
+	ExporterOTLPGRPC = "otlp-grpc"
+	ExporterOTLPHTTP = "otlp-http"
+	ExporterStdout   = "stdout"
+	ExporterFile     = "file" // spans or metrics are appended to the file of the section as JSON
+	ExporterNone     = "none" // spans are sampled for propagation but not exported, metrics are not collected
+)
+
+// Tracing samplers.
+const (
+	SamplerAlways = "always"
+	SamplerNever  = "never"
+	SamplerRatio  = "ratio" // samples Tracing.Ratio of traces by trace ID
+)
+
+// Tracing contains settings of span export and sampling.
+type Tracing struct {
+	Exporter string `mapstructure:"exporter"` // ExporterOTLPGRPC when empty
+	// Endpoint is host:port of OTLP collector, OTEL_EXPORTER_OTLP_* environment variables apply when empty.
+	Endpoint    string  `mapstructure:"endpoint"`
+	Insecure    bool    `mapstructure:"insecure"` // OTLP without TLS
+	File        string  `mapstructure:"file"`
+	Sampler     string  `mapstructure:"sampler"` // SamplerAlways when empty
+	Ratio       float64 `mapstructure:"ratio"`
+	ParentBased bool    `mapstructure:"parent_based"` // sampled traces of callers are sampled whatever the sampler says
+}
+
+// Metrics contains settings of metric export.
+type Metrics struct {
+	Exporter string `mapstructure:"exporter"` // ExporterOTLPGRPC when empty
+	// Endpoint is host:port of OTLP collector, OTEL_EXPORTER_OTLP_* environment variables apply when empty.
+	Endpoint string        `mapstructure:"endpoint"`
+	Insecure bool          `mapstructure:"insecure"` // OTLP without TLS
+	File     string        `mapstructure:"file"`
+	Interval time.Duration `mapstructure:"interval"` // between exports, 1m when zero
+}
+
+// Webhooks contains settings of HTTP callbacks about committed and aborted transactions.
+type Webhooks struct {
+	Enabled     bool `mapstructure:"enabled"`
+	MaxAttempts int  `mapstructure:"max_attempts"` // failed deliveries are moved to dead letters after it
+	// Backoff spreads attempts of a failed delivery, max_elapsed_time is not used.
+	Backoff      *Retry        `mapstructure:"backoff"`
+	Timeout      time.Duration `mapstructure:"timeout"` // of a single delivery attempt
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	BatchSize    int           `mapstructure:"batch_size"` // deliveries attempted at once
 }
 
 // AppConfig is a container for application config.
 type AppConfig struct {
-	API *API `mapstructure:"api"`
-	Db  *DB  `mapstructure:"db"`
+	API        *API        `mapstructure:"api"`
+	Db         *DB         `mapstructure:"db"`
+	Auth       *Auth       `mapstructure:"auth"`
+	RateLimit  *RateLimit  `mapstructure:"rate_limit"`
+	Log        *Log        `mapstructure:"log"`
+	Tenancy    *Tenancy    `mapstructure:"tenancy"`
+	Partitions *Partitions `mapstructure:"partitions"`
+	Cache      *Cache      `mapstructure:"cache"`
+	Tracing    *Tracing    `mapstructure:"tracing"`
+	Metrics    *Metrics    `mapstructure:"metrics"`
+	Webhooks   *Webhooks   `mapstructure:"webhooks"`
+}
+
+const (
+	// DefaultPath is used when config path is given neither by flag nor by PathEnv.
+	DefaultPath = "config.yml"
+	// PathEnv is an environment variable with config path.
+	PathEnv = "ORDERS_MANAGER_CONFIG"
+	// EnvPrefix is a prefix of environment variables overriding config keys,
+	// nested keys are joined by underscore: db.conn_string is ORDERS_MANAGER_DB_CONN_STRING.
+	EnvPrefix = "ORDERS_MANAGER"
+)
+
+// ResolvePath returns config path from flag value, PathEnv or DefaultPath, in that order.
+func ResolvePath(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if envValue := os.Getenv(PathEnv); envValue != "" {
+		return envValue
+	}
+
+	return DefaultPath
+}
+
+// GetAppConfig reads config from path, applies environment overrides and validates the result.
+// Unknown keys are rejected.
+func GetAppConfig(path string) (*AppConfig, error) {
+	config, _, err := load(path)
+
+	return config, err
 }
 
-// GetAppConfig returns *Config.
-func GetAppConfig() (*AppConfig, error) {
-	viper.SetConfigName("config") // hardcoded config name
-	viper.AddConfigPath(".")      // hardcoded configfile path
-	if err := viper.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("unable to read config from file: %w", err)
+// load returns config and flattened settings it was decoded from.
+func load(path string) (*AppConfig, map[string]any, error) {
+	v := newViper(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, nil, fmt.Errorf("unable to read config from file: %w", err)
 	}
-	viper.AutomaticEnv()
 
+	config, err := decode(v)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	settings := make(map[string]any)
+	for _, key := range v.AllKeys() {
+		settings[key] = v.Get(key)
+	}
+
+	return config, settings, nil
+}
+
+// decode decodes and validates settings of v.
+func decode(v *viper.Viper) (*AppConfig, error) {
 	config := new(AppConfig)
-	if err := viper.Unmarshal(config); err != nil {
+	if err := v.UnmarshalExact(config); err != nil {
 		return nil, fmt.Errorf("unable to decode into struct, %w", err)
 	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
 
 	return config, nil
 }
+
+// fromSettings decodes settings collected by load.
+func fromSettings(settings map[string]any) (*AppConfig, error) {
+	v := viper.New()
+	for key, value := range settings {
+		// keys bound to unset environment variables are nil, viper skips them as well
+		if value != nil {
+			v.Set(key, value)
+		}
+	}
+
+	return decode(v)
+}
+
+func newViper(path string) *viper.Viper {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	// AutomaticEnv works only for keys viper already knows, so keys missing in the file are bound explicitly
+	for _, key := range envKeys(reflect.TypeOf(AppConfig{}), "") {
+		_ = v.BindEnv(key)
+	}
+
+	return v
+}
+
+// envKeys lists keys of scalar fields of struct t, lists of structs can not be set from environment.
+func envKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := prefix + tag
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		switch {
+		case fieldType.Kind() == reflect.Struct && fieldType != reflect.TypeOf(time.Time{}):
+			keys = append(keys, envKeys(fieldType, key+".")...)
+		case fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.Struct:
+			continue
+		default:
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
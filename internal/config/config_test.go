@@ -4,81 +4,248 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
+const validConfig = `api:
+  bind: ":8080"
+db:
+  conn_string: "default"
+  max_open_conns: 10
+  conn_max_lifetime: 5s
+  migration_dir_path: "./migrations"
+  migration_table: "migrations"
+`
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write config failed: %v", err)
+	}
+
+	return path
+}
+
 func TestGetAppConfig_FileNotFound(t *testing.T) {
-	dir := t.TempDir()
-	prev, err := os.Getwd()
+	cfg, err := GetAppConfig(filepath.Join(t.TempDir(), "config.yml"))
+	assert.Error(t, err)
+	assert.Nil(t, cfg)
+}
+
+func TestGetAppConfig_Success(t *testing.T) {
+	cfg, err := GetAppConfig(writeConfig(t, validConfig))
 	if err != nil {
-		t.Fatalf("getwd failed: %v", err)
+		t.Fatalf("GetAppConfig returned error: %v", err)
 	}
-	t.Cleanup(func() {
-		if err := os.Chdir(prev); err != nil {
-			t.Fatalf("restore wd: %v", err)
-		}
-	})
-	if err := os.Chdir(dir); err != nil {
-		t.Fatalf("chdir failed: %v", err)
+	assert.Equal(t, ":8080", cfg.API.Bind)
+	assert.Equal(t, "default", cfg.Db.ConnString)
+	assert.Equal(t, 10, cfg.Db.MaxOpenConns)
+	assert.Equal(t, 5*time.Second, cfg.Db.ConnMaxLifetime)
+	assert.Equal(t, "migrations", cfg.Db.MigrationTable)
+	assert.Nil(t, cfg.Auth)
+}
+
+func TestGetAppConfig_EnvOverride(t *testing.T) {
+	t.Setenv("ORDERS_MANAGER_DB_CONN_STRING", "from-env")
+	t.Setenv("ORDERS_MANAGER_DB_MAX_OPEN_CONNS", "42")
+	// keys missing in the file can be set from environment as well
+	t.Setenv("ORDERS_MANAGER_API_HTTP_BIND", ":9090")
+	t.Setenv("ORDERS_MANAGER_RATE_LIMIT_PER_USER_RPS", "2.5")
+
+	cfg, err := GetAppConfig(writeConfig(t, validConfig))
+	if err != nil {
+		t.Fatalf("GetAppConfig returned error: %v", err)
 	}
+	assert.Equal(t, "from-env", cfg.Db.ConnString)
+	assert.Equal(t, 42, cfg.Db.MaxOpenConns)
+	assert.Equal(t, ":9090", cfg.API.HTTPBind)
+	if assert.NotNil(t, cfg.RateLimit) && assert.NotNil(t, cfg.RateLimit.PerUser) {
+		assert.InDelta(t, 2.5, cfg.RateLimit.PerUser.RPS, 0.001)
+	}
+}
 
-	cfg, err := GetAppConfig()
+func TestGetAppConfig_UnmarshalError(t *testing.T) {
+	data := "api:\n  bind: \":8080\"\ndb:\n  max_open_conns: \"notint\"\n"
+
+	cfg, err := GetAppConfig(writeConfig(t, data))
 	assert.Error(t, err)
 	assert.Nil(t, cfg)
 }
 
-func TestGetAppConfig_SuccessWithEnvOverride(t *testing.T) {
-	dir := t.TempDir()
-	prev, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("getwd failed: %v", err)
+func TestGetAppConfig_UnknownKey(t *testing.T) {
+	data := validConfig + "  max_open_cons: 10\n"
+
+	cfg, err := GetAppConfig(writeConfig(t, data))
+	assert.ErrorContains(t, err, "max_open_cons")
+	assert.Nil(t, cfg)
+}
+
+func TestGetAppConfig_MissingRequired(t *testing.T) {
+	data := "api:\n  bind: \":8080\"\ndb:\n  migration_table: \"migrations\"\n"
+
+	cfg, err := GetAppConfig(writeConfig(t, data))
+	assert.ErrorContains(t, err, "db.conn_string is required")
+	assert.Nil(t, cfg)
+}
+
+func TestValidate_Sections(t *testing.T) {
+	cfg := &AppConfig{
+		API: &API{Bind: ":8080", TLS: &TLS{RequireClientCert: true}},
+		Db:  &DB{ConnString: "c", MigrationDirPath: "d", MigrationTable: "t"},
+		Auth: &Auth{
+			Enabled:       true,
+			StaticKeys:    []StaticKey{{KeyID: "k", Secret: "s", PublicKeyFile: "f"}},
+			ClientTenants: []ClientTenant{{CommonName: "coordinator"}},
+		},
+		RateLimit: &RateLimit{Enabled: true, PerUser: &RateLimitRule{RPS: 1}},
+	}
+
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "api.tls.cert_file is required")
+	assert.ErrorContains(t, err, "api.tls.client_ca_file is required")
+	assert.ErrorContains(t, err, "auth.static_keys[0]")
+	assert.ErrorContains(t, err, "auth.client_tenants[0].tenant is required")
+	assert.ErrorContains(t, err, "rate_limit.per_user")
+
+	assert.Error(t, (&AppConfig{}).Validate())
+}
+
+func TestValidate_TenancyRequiresAuth(t *testing.T) {
+	cfg := &AppConfig{
+		API:     &API{Bind: ":8080"},
+		Db:      &DB{ConnString: "c", MigrationTable: "t"},
+		Tenancy: &Tenancy{Enabled: true},
 	}
-	t.Cleanup(func() {
-		if err := os.Chdir(prev); err != nil {
-			t.Fatalf("restore wd: %v", err)
+	assert.ErrorContains(t, cfg.Validate(), "tenancy.enabled requires auth.enabled")
+
+	cfg.Auth = &Auth{Enabled: true, StaticKeys: []StaticKey{{KeyID: "k", Secret: "s"}}}
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_Backend(t *testing.T) {
+	valid := func(backend string, replicas ...string) error {
+		cfg := &AppConfig{
+			API: &API{Bind: ":8080"},
+			Db:  &DB{ConnString: "c", MigrationTable: "t", Backend: backend, ReplicaConnStrings: replicas},
 		}
-	})
-	if err := os.Chdir(dir); err != nil {
-		t.Fatalf("chdir failed: %v", err)
+
+		return cfg.Validate()
 	}
 
-	data := []byte("api:\n  bind: \":8080\"\ndb:\n  conn_string: \"default\"\n  max_open_cons: 10\n  conn_max_lifetime: 5s\n  migration_dir_path: \"./migrations\"\n  migration_table: \"migrations\"\n")
-	if err := os.WriteFile(filepath.Join(dir, "config.yml"), data, 0o644); err != nil {
-		t.Fatalf("write config failed: %v", err)
+	assert.NoError(t, valid(""))
+	assert.NoError(t, valid(BackendSQL, "r"))
+	assert.NoError(t, valid(BackendPgx))
+	assert.ErrorContains(t, valid(BackendPgx, "r"), "not supported by pgx backend")
+	assert.ErrorContains(t, valid("mysql"), "db.backend must be")
+}
+
+func TestDB_Shards(t *testing.T) {
+	conf := &DB{ConnString: "primary", MigrationTable: "t"}
+	assert.Equal(t, []*DB{conf}, conf.Shards())
+
+	conf.ShardConnStrings = []string{"second", "third"}
+	shards := conf.Shards()
+	assert.Len(t, shards, 3)
+	assert.Equal(t, "primary", shards[0].ConnString)
+	assert.Equal(t, "third", shards[2].ConnString)
+	assert.Equal(t, "t", shards[2].MigrationTable)
+
+	conf.ReplicaConnStrings = []string{"replica"}
+	cfg := &AppConfig{API: &API{Bind: ":8080"}, Db: conf}
+	assert.ErrorContains(t, cfg.Validate(), "not supported with db.shard_conn_strings")
+}
+
+func TestPartitions_Validate(t *testing.T) {
+	valid := func(p *Partitions) error {
+		return (&AppConfig{API: &API{Bind: ":8080"}, Db: &DB{ConnString: "c", MigrationTable: "t"}, Partitions: p}).Validate()
 	}
 
-	cfg, err := GetAppConfig()
-	if err != nil {
-		t.Fatalf("GetAppConfig returned error: %v", err)
+	assert.NoError(t, valid(&Partitions{Enabled: true, Premake: 3}))
+	assert.NoError(t, valid(&Partitions{Enabled: true, Retention: 12, RetentionPolicy: RetentionDetach}))
+	assert.NoError(t, valid(&Partitions{Retention: -1}), "disabled section is not checked")
+	assert.ErrorContains(t, valid(&Partitions{Enabled: true, Premake: -1}), "must not be negative")
+	assert.ErrorContains(t, valid(&Partitions{Enabled: true, Retention: 12}), "retention_policy is required")
+	assert.ErrorContains(t, valid(&Partitions{Enabled: true, RetentionPolicy: RetentionArchive}), "archive_schema is required")
+	assert.ErrorContains(t, valid(&Partitions{Enabled: true, RetentionPolicy: "drop"}), "retention_policy must be")
+}
+
+func TestLog_Validate(t *testing.T) {
+	valid := func(c *Log) error {
+		return (&AppConfig{API: &API{Bind: ":8080"}, Db: &DB{ConnString: "c", MigrationTable: "t"}, Log: c}).Validate()
 	}
-	assert.Equal(t, ":8080", cfg.API.Bind)
-	assert.Equal(t, "default", cfg.Db.ConnString)
-	assert.Equal(t, 10, cfg.Db.MaxOpenCons)
-	assert.Equal(t, "migrations", cfg.Db.MigrationTable)
+
+	assert.NoError(t, valid(&Log{}))
+	assert.NoError(t, valid(&Log{Level: "info", Format: LogFormatJSON, Output: "/var/log/orders-manager.log",
+		Payloads: &LogPayloads{Methods: []string{"/pb.OrdersManagerService/GetOrder"}, MaskedFields: []string{"user_id"}}}))
+	assert.ErrorContains(t, valid(&Log{Level: "verbose"}), "log.level must be")
+	assert.ErrorContains(t, valid(&Log{Format: "logfmt"}), "log.format must be")
+	assert.ErrorContains(t, valid(&Log{Payloads: &LogPayloads{Methods: []string{"GetOrder"}}}), "log.payloads.methods[0]")
 }
 
-func TestGetAppConfig_UnmarshalError(t *testing.T) {
-	dir := t.TempDir()
-	prev, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("getwd failed: %v", err)
+func TestCache_Validate(t *testing.T) {
+	valid := func(c *Cache) error {
+		return (&AppConfig{API: &API{Bind: ":8080"}, Db: &DB{ConnString: "c", MigrationTable: "t"}, Cache: c}).Validate()
 	}
-	t.Cleanup(func() {
-		if err := os.Chdir(prev); err != nil {
-			t.Fatalf("restore wd: %v", err)
-		}
-	})
-	if err := os.Chdir(dir); err != nil {
-		t.Fatalf("chdir failed: %v", err)
+
+	assert.NoError(t, valid(&Cache{Enabled: true, Backend: CacheLRU, MaxEntries: 100, TTL: time.Minute}))
+	assert.NoError(t, valid(&Cache{Backend: "redis"}), "disabled section is not checked")
+	assert.ErrorContains(t, valid(&Cache{Enabled: true, Backend: "redis", MaxEntries: 100, TTL: time.Minute}), "cache.backend must be")
+	assert.ErrorContains(t, valid(&Cache{Enabled: true, Backend: CacheLRU, TTL: time.Minute}), "max_entries must be positive")
+	assert.ErrorContains(t, valid(&Cache{Enabled: true, Backend: CacheLRU, MaxEntries: 100}), "ttl must be positive")
+}
+
+func TestTracing_Validate(t *testing.T) {
+	valid := func(c *Tracing) error {
+		return (&AppConfig{API: &API{Bind: ":8080"}, Db: &DB{ConnString: "c", MigrationTable: "t"}, Tracing: c}).Validate()
 	}
 
-	data := []byte("api:\n  bind: \":8080\"\ndb:\n  max_open_cons: \"notint\"\n")
-	if err := os.WriteFile(filepath.Join(dir, "config.yml"), data, 0o644); err != nil {
-		t.Fatalf("write config failed: %v", err)
+	assert.NoError(t, valid(&Tracing{}), "defaults are valid")
+	assert.NoError(t, valid(&Tracing{Exporter: ExporterFile, File: "spans.json", Sampler: SamplerRatio, Ratio: 0.1}))
+	assert.ErrorContains(t, valid(&Tracing{Exporter: "zipkin"}), "tracing.exporter must be")
+	assert.ErrorContains(t, valid(&Tracing{Exporter: ExporterFile}), "tracing.file is required")
+	assert.ErrorContains(t, valid(&Tracing{Sampler: "sometimes"}), "tracing.sampler must be")
+	assert.ErrorContains(t, valid(&Tracing{Sampler: SamplerRatio, Ratio: 1.5}), "tracing.ratio must be")
+}
+
+func TestMetrics_Validate(t *testing.T) {
+	valid := func(m *Metrics) error {
+		return (&AppConfig{API: &API{Bind: ":8080"}, Db: &DB{ConnString: "c", MigrationTable: "t"}, Metrics: m}).Validate()
 	}
 
-	cfg, err := GetAppConfig()
-	assert.Error(t, err)
-	assert.Nil(t, cfg)
+	assert.NoError(t, valid(&Metrics{}), "defaults are valid")
+	assert.NoError(t, valid(&Metrics{Exporter: ExporterFile, File: "metrics.json", Interval: time.Minute}))
+	assert.ErrorContains(t, valid(&Metrics{Exporter: "prometheus"}), "metrics.exporter must be")
+	assert.ErrorContains(t, valid(&Metrics{Exporter: ExporterFile}), "metrics.file is required")
+	assert.ErrorContains(t, valid(&Metrics{Interval: -time.Second}), "metrics.interval must not be negative")
+}
+
+func TestWebhooks_Validate(t *testing.T) {
+	valid := func(w *Webhooks) error {
+		return (&AppConfig{API: &API{Bind: ":8080"}, Db: &DB{ConnString: "c", MigrationTable: "t"}, Webhooks: w}).Validate()
+	}
+
+	assert.NoError(t, valid(&Webhooks{Enabled: true}), "defaults are valid")
+	assert.NoError(t, valid(&Webhooks{MaxAttempts: -1}), "disabled section is not checked")
+	assert.ErrorContains(t, valid(&Webhooks{Enabled: true, MaxAttempts: -1}), "webhooks.max_attempts")
+	assert.ErrorContains(t, valid(&Webhooks{Enabled: true, Timeout: -time.Second}), "webhooks intervals")
+}
+
+func TestResolvePath(t *testing.T) {
+	t.Setenv(PathEnv, "")
+	assert.Equal(t, DefaultPath, ResolvePath(""))
+
+	t.Setenv(PathEnv, "/etc/orders/config.yml")
+	assert.Equal(t, "/etc/orders/config.yml", ResolvePath(""))
+	assert.Equal(t, "flag.yml", ResolvePath("flag.yml"))
+}
+
+func TestGetAppConfig_RepoConfig(t *testing.T) {
+	cfg, err := GetAppConfig("../../config.yml")
+	if err != nil {
+		t.Fatalf("config.yml shipped with the service is invalid: %v", err)
+	}
+	assert.Positive(t, cfg.Db.MaxOpenConns)
 }
@@ -0,0 +1,273 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Validate checks that required settings are present and consistent.
+func (c *AppConfig) Validate() error {
+	var errs []error
+	if c.API == nil {
+		errs = append(errs, errors.New("api section is required"))
+	} else {
+		errs = append(errs, c.API.validate()...)
+	}
+	if c.Db == nil {
+		errs = append(errs, errors.New("db section is required"))
+	} else {
+		errs = append(errs, c.Db.validate()...)
+	}
+	if c.Auth != nil && c.Auth.Enabled {
+		errs = append(errs, c.Auth.validate()...)
+	}
+	if c.RateLimit != nil && c.RateLimit.Enabled {
+		errs = append(errs, c.RateLimit.validate()...)
+	}
+	if c.Tenancy != nil && c.Tenancy.Enabled && (c.Auth == nil || !c.Auth.Enabled) {
+		errs = append(errs, errors.New("tenancy.enabled requires auth.enabled, tenants are bound to principals"))
+	}
+	if c.Log != nil {
+		errs = append(errs, c.Log.validate()...)
+	}
+	if c.Partitions != nil && c.Partitions.Enabled {
+		errs = append(errs, c.Partitions.validate()...)
+	}
+	if c.Cache != nil && c.Cache.Enabled {
+		errs = append(errs, c.Cache.validate()...)
+	}
+	if c.Tracing != nil {
+		errs = append(errs, c.Tracing.validate()...)
+	}
+	if c.Metrics != nil {
+		errs = append(errs, c.Metrics.validate()...)
+	}
+	if c.Webhooks != nil && c.Webhooks.Enabled {
+		errs = append(errs, c.Webhooks.validate()...)
+	}
+
+	return errors.Join(errs...)
+}
+
+func required(key, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is required", key)
+	}
+
+	return nil
+}
+
+func (a *API) validate() []error {
+	errs := []error{required("api.bind", a.Bind)}
+	if a.TLS != nil {
+		errs = append(errs,
+			required("api.tls.cert_file", a.TLS.CertFile),
+			required("api.tls.key_file", a.TLS.KeyFile),
+		)
+		if a.TLS.RequireClientCert {
+			errs = append(errs, required("api.tls.client_ca_file", a.TLS.ClientCAFile))
+		}
+	}
+
+	return errs
+}
+
+func (d *DB) validate() []error {
+	errs := []error{
+		required("db.conn_string", d.ConnString),
+		required("db.migration_table", d.MigrationTable),
+	}
+	if d.MaxOpenConns < 0 || d.MaxOpenConns > math.MaxInt32 {
+		errs = append(errs, errors.New("db.max_open_conns must be between 0 and 2147483647"))
+	}
+	switch d.Backend {
+	case "", BackendSQL:
+	case BackendPgx:
+		if len(d.ReplicaConnStrings) > 0 {
+			errs = append(errs, errors.New("db.replica_conn_strings are not supported by pgx backend"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("db.backend must be %q or %q", BackendSQL, BackendPgx))
+	}
+	for i, connString := range d.ReplicaConnStrings {
+		errs = append(errs, required(fmt.Sprintf("db.replica_conn_strings[%d]", i), connString))
+	}
+	for i, connString := range d.ShardConnStrings {
+		errs = append(errs, required(fmt.Sprintf("db.shard_conn_strings[%d]", i), connString))
+	}
+	if len(d.ShardConnStrings) > 0 && len(d.ReplicaConnStrings) > 0 {
+		errs = append(errs, errors.New("db.replica_conn_strings are not supported with db.shard_conn_strings"))
+	}
+	if len(d.ShardConnStrings) >= MaxShards {
+		errs = append(errs, fmt.Errorf("db.shard_conn_strings must have less than %d entries", MaxShards))
+	}
+	if d.MigrationLockTimeout < 0 {
+		errs = append(errs, errors.New("db.migration_lock_timeout must not be negative"))
+	}
+	if r := d.ConnectRetry; r != nil {
+		if r.InitialInterval < 0 || r.MaxInterval < 0 || r.MaxElapsedTime < 0 {
+			errs = append(errs, errors.New("db.connect_retry intervals must not be negative"))
+		}
+		if r.Multiplier != 0 && r.Multiplier < 1 {
+			errs = append(errs, errors.New("db.connect_retry.multiplier must be at least 1"))
+		}
+	}
+
+	return errs
+}
+
+func (a *Auth) validate() []error {
+	var errs []error
+	if a.JWKSFile == "" && len(a.StaticKeys) == 0 {
+		errs = append(errs, errors.New("auth.jwks_file or auth.static_keys is required"))
+	}
+	for i, key := range a.StaticKeys {
+		if (key.Secret == "") == (key.PublicKeyFile == "") {
+			errs = append(errs, fmt.Errorf("auth.static_keys[%d]: exactly one of secret and public_key_file is required", i))
+		}
+	}
+	for i, client := range a.ClientTenants {
+		errs = append(errs, required(fmt.Sprintf("auth.client_tenants[%d].common_name", i), client.CommonName))
+		errs = append(errs, required(fmt.Sprintf("auth.client_tenants[%d].tenant", i), client.Tenant))
+	}
+
+	return errs
+}
+
+func (r *RateLimit) validate() []error {
+	var errs []error
+	for i, rule := range r.Methods {
+		errs = append(errs, required(fmt.Sprintf("rate_limit.methods[%d].method", i), rule.Method))
+		errs = append(errs, rule.validate(fmt.Sprintf("rate_limit.methods[%d]", i)))
+	}
+	if r.PerClient != nil {
+		errs = append(errs, r.PerClient.validate("rate_limit.per_client"))
+	}
+	if r.PerUser != nil {
+		errs = append(errs, r.PerUser.validate("rate_limit.per_user"))
+	}
+
+	return errs
+}
+
+func (r RateLimitRule) validate(key string) error {
+	if r.RPS <= 0 || r.Burst <= 0 {
+		return fmt.Errorf("%s: rps and burst must be positive", key)
+	}
+
+	return nil
+}
+
+// LogLevels lists accepted values of log.level.
+var LogLevels = []string{"trace", "debug", "info", "warn", "error"}
+
+func (l *Log) validate() []error {
+	var errs []error
+	if l.Level != "" && !slices.Contains(LogLevels, l.Level) {
+		errs = append(errs, fmt.Errorf("log.level must be one of %v", LogLevels))
+	}
+	if l.Format != "" && l.Format != LogFormatText && l.Format != LogFormatJSON {
+		errs = append(errs, fmt.Errorf("log.format must be %q or %q", LogFormatText, LogFormatJSON))
+	}
+	if l.Payloads != nil {
+		for i, method := range l.Payloads.Methods {
+			if !strings.HasPrefix(method, "/") {
+				errs = append(errs, fmt.Errorf("log.payloads.methods[%d] must be a full grpc method name like /pb.OrdersManagerService/GetOrder", i))
+			}
+		}
+	}
+
+	return errs
+}
+
+func (p *Partitions) validate() []error {
+	var errs []error
+	if p.Premake < 0 || p.Retention < 0 {
+		errs = append(errs, errors.New("partitions.premake and partitions.retention must not be negative"))
+	}
+	if p.CheckInterval < 0 || p.LockTimeout < 0 {
+		errs = append(errs, errors.New("partitions intervals must not be negative"))
+	}
+	switch p.RetentionPolicy {
+	case RetentionDetach:
+	case RetentionArchive:
+		errs = append(errs, required("partitions.archive_schema", p.ArchiveSchema))
+	case "":
+		if p.Retention > 0 {
+			errs = append(errs, errors.New("partitions.retention_policy is required with partitions.retention"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("partitions.retention_policy must be %q or %q", RetentionDetach, RetentionArchive))
+	}
+
+	return errs
+}
+
+func (c *Cache) validate() []error {
+	var errs []error
+	if c.Backend != CacheLRU {
+		errs = append(errs, fmt.Errorf("cache.backend must be %q", CacheLRU))
+	}
+	if c.MaxEntries <= 0 {
+		errs = append(errs, errors.New("cache.max_entries must be positive"))
+	}
+	if c.TTL <= 0 {
+		errs = append(errs, errors.New("cache.ttl must be positive"))
+	}
+
+	return errs
+}
+
+// Exporters lists accepted values of tracing.exporter and metrics.exporter.
+var Exporters = []string{ExporterOTLPGRPC, ExporterOTLPHTTP, ExporterStdout, ExporterFile, ExporterNone}
+
+// Samplers lists accepted values of tracing.sampler.
+var Samplers = []string{SamplerAlways, SamplerNever, SamplerRatio}
+
+func (t *Tracing) validate() []error {
+	var errs []error
+	if t.Exporter != "" && !slices.Contains(Exporters, t.Exporter) {
+		errs = append(errs, fmt.Errorf("tracing.exporter must be one of %v", Exporters))
+	}
+	if t.Exporter == ExporterFile {
+		errs = append(errs, required("tracing.file", t.File))
+	}
+	if t.Sampler != "" && !slices.Contains(Samplers, t.Sampler) {
+		errs = append(errs, fmt.Errorf("tracing.sampler must be one of %v", Samplers))
+	}
+	if t.Sampler == SamplerRatio && (t.Ratio <= 0 || t.Ratio > 1) {
+		errs = append(errs, errors.New("tracing.ratio must be in (0, 1] with ratio sampler"))
+	}
+
+	return errs
+}
+
+func (m *Metrics) validate() []error {
+	var errs []error
+	if m.Exporter != "" && !slices.Contains(Exporters, m.Exporter) {
+		errs = append(errs, fmt.Errorf("metrics.exporter must be one of %v", Exporters))
+	}
+	if m.Exporter == ExporterFile {
+		errs = append(errs, required("metrics.file", m.File))
+	}
+	if m.Interval < 0 {
+		errs = append(errs, errors.New("metrics.interval must not be negative"))
+	}
+
+	return errs
+}
+
+func (w *Webhooks) validate() []error {
+	var errs []error
+	if w.MaxAttempts < 0 || w.BatchSize < 0 {
+		errs = append(errs, errors.New("webhooks.max_attempts and webhooks.batch_size must not be negative"))
+	}
+	if w.Timeout < 0 || w.PollInterval < 0 {
+		errs = append(errs, errors.New("webhooks intervals must not be negative"))
+	}
+
+	return errs
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"maps"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Watcher re-reads config file when it changes and applies settings that are safe to change at runtime.
+// Settings are safe when there is a subscription for them, changes of other settings are only logged
+// and their previous values stay in Config until restart.
+type Watcher struct {
+	path   string
+	logger logging.Logger
+
+	mu            sync.Mutex
+	current       *AppConfig
+	settings      map[string]any
+	subscriptions []subscription
+}
+
+type subscription struct {
+	key   string
+	apply func(config *AppConfig)
+}
+
+// NewWatcher reads config from path. Use Config to get it and Subscribe to follow changes.
+func NewWatcher(logger logging.Logger, path string) (*Watcher, error) {
+	config, settings, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Watcher{
+		path:     path,
+		logger:   logger,
+		current:  config,
+		settings: settings,
+	}, nil
+}
+
+// Config returns the applied config: the latest valid one with restart-only settings the process started with.
+func (w *Watcher) Config() *AppConfig {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.current
+}
+
+// Subscribe calls apply with the section selected by get every time settings under key change.
+// Settings under key are considered safe to change at runtime.
+func Subscribe[T any](w *Watcher, key string, get func(*AppConfig) T, apply func(T)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscriptions = append(w.subscriptions, subscription{
+		key: key,
+		apply: func(config *AppConfig) {
+			apply(get(config))
+		},
+	})
+}
+
+// Watch starts watching the config file.
+func (w *Watcher) Watch() {
+	v := viper.New()
+	v.SetConfigFile(w.path)
+	v.OnConfigChange(func(fsnotify.Event) {
+		w.Reload()
+	})
+	v.WatchConfig()
+}
+
+// Reload re-reads config file and applies changed settings. Invalid config is ignored.
+func (w *Watcher) Reload() {
+	config, settings, err := load(w.path)
+	if err != nil {
+		w.logger.WithError(err).Error("config reload failed, keeping previous config")
+
+		return
+	}
+
+	w.mu.Lock()
+	changed := changedKeys(w.settings, settings)
+	if len(changed) == 0 {
+		w.mu.Unlock()
+
+		return
+	}
+
+	var toApply []subscription
+	restartRequired := make([]string, 0, len(changed))
+	for _, key := range changed {
+		covered := false
+		for _, sub := range w.subscriptions {
+			if isUnder(key, sub.key) {
+				covered = true
+			}
+		}
+		if !covered {
+			restartRequired = append(restartRequired, key)
+		}
+	}
+	if len(restartRequired) > 0 {
+		// running components keep restart-only settings they were started with, so does Config
+		settings = keepSettings(settings, w.settings, restartRequired)
+		if config, err = fromSettings(settings); err != nil {
+			w.mu.Unlock()
+			w.logger.WithError(err).WithField("keys", strings.Join(restartRequired, ",")).
+				Error("config reload failed with previous values of restart-only settings, keeping previous config")
+
+			return
+		}
+	}
+	w.current = config
+	w.settings = settings
+	for _, sub := range w.subscriptions {
+		for _, key := range changed {
+			if isUnder(key, sub.key) {
+				toApply = append(toApply, sub)
+
+				break
+			}
+		}
+	}
+	w.mu.Unlock()
+
+	for _, sub := range toApply {
+		sub.apply(config)
+	}
+	w.logger.WithField("keys", strings.Join(changed, ",")).Info("config reloaded")
+	if len(restartRequired) > 0 {
+		w.logger.WithField("keys", strings.Join(restartRequired, ",")).
+			Warn("changed settings require restart to take effect, previous values stay applied")
+	}
+}
+
+// keepSettings returns next settings with values of keys taken from prev.
+func keepSettings(next, prev map[string]any, keys []string) map[string]any {
+	kept := maps.Clone(next)
+	for _, key := range keys {
+		if value, ok := prev[key]; ok {
+			kept[key] = value
+		} else {
+			delete(kept, key)
+		}
+	}
+
+	return kept
+}
+
+func isUnder(key, prefix string) bool {
+	return key == prefix || strings.HasPrefix(key, prefix+".")
+}
+
+func changedKeys(prev, next map[string]any) []string {
+	var changed []string
+	for key, value := range next {
+		if !reflect.DeepEqual(prev[key], value) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range prev {
+		if _, ok := next[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+
+	return changed
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcher_Reload(t *testing.T) {
+	path := writeConfig(t, validConfig)
+	watcher, err := NewWatcher(logging.GetLogger(), path)
+	if err != nil {
+		t.Fatalf("NewWatcher returned error: %v", err)
+	}
+
+	var maxOpenConns []int
+	Subscribe(watcher, "db.max_open_conns",
+		func(c *AppConfig) int { return c.Db.MaxOpenConns },
+		func(value int) { maxOpenConns = append(maxOpenConns, value) })
+	var logCalls []string
+	Subscribe(watcher, "log",
+		func(c *AppConfig) *Log { return c.Log },
+		func(*Log) { logCalls = append(logCalls, "log") })
+
+	// unchanged file does not trigger subscriptions
+	watcher.Reload()
+	assert.Empty(t, maxOpenConns)
+
+	changed := strings.Replace(validConfig, "max_open_conns: 10", "max_open_conns: 20", 1)
+	changed = strings.Replace(changed, `conn_string: "default"`, `conn_string: "other"`, 1)
+	if err = os.WriteFile(path, []byte(changed), 0o644); err != nil {
+		t.Fatalf("write config failed: %v", err)
+	}
+	watcher.Reload()
+	assert.Equal(t, []int{20}, maxOpenConns)
+	assert.Empty(t, logCalls, "subscription for unchanged section must not be called")
+	assert.Equal(t, 20, watcher.Config().Db.MaxOpenConns)
+	assert.Equal(t, "default", watcher.Config().Db.ConnString, "restart-only settings keep applied values")
+	assert.Nil(t, watcher.Config().API.TLS)
+	assert.Equal(t, 5*time.Second, watcher.Config().Db.ConnMaxLifetime)
+
+	// restart-only settings stay applied after following reloads
+	changed = strings.Replace(changed, "max_open_conns: 20", "max_open_conns: 30", 1)
+	if err = os.WriteFile(path, []byte(changed), 0o644); err != nil {
+		t.Fatalf("write config failed: %v", err)
+	}
+	watcher.Reload()
+	assert.Equal(t, []int{20, 30}, maxOpenConns)
+	assert.Equal(t, "default", watcher.Config().Db.ConnString)
+
+	// invalid config is ignored
+	if err = os.WriteFile(path, []byte("api:\n  bind: \":8080\"\n"), 0o644); err != nil {
+		t.Fatalf("write config failed: %v", err)
+	}
+	watcher.Reload()
+	assert.Equal(t, []int{20, 30}, maxOpenConns)
+	assert.Equal(t, 30, watcher.Config().Db.MaxOpenConns)
+}
+
+func TestChangedKeys(t *testing.T) {
+	prev := map[string]any{"a": 1, "b": "x", "c": true}
+	next := map[string]any{"a": 1, "b": "y", "d": 2}
+	assert.Equal(t, []string{"b", "c", "d"}, changedKeys(prev, next))
+}
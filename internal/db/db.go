@@ -2,9 +2,16 @@ package db
 
 import (
 	"context"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"strings"
 
 	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	_ "github.com/jackc/pgx/v4/stdlib"
 	"github.com/jmoiron/sqlx"
 	"github.com/uptrace/opentelemetry-go-extra/otelsql"
@@ -12,14 +19,36 @@ import (
 	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 
 	"github.com/Sugar-pack/orders-manager/internal/config"
+	applog "github.com/Sugar-pack/orders-manager/internal/logger"
+	"github.com/Sugar-pack/orders-manager/internal/retry"
 )
 
-// Connect creates new db connection.
+// Connect creates new db connection. Unreachable DB is retried according to conf.ConnectRetry.
 func Connect(ctx context.Context, conf *config.DB) (*sqlx.DB, error) {
+	conn, err := Open(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	if err = Ping(ctx, conn, conf); err != nil {
+		_ = conn.Close()
+
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// Open creates connection pool without connecting, use Ping to wait for DB.
+func Open(ctx context.Context, conf *config.DB) (*sqlx.DB, error) {
 	logger := logging.FromContext(ctx)
-	logger.WithField("conn_string", conf.ConnString).Trace("connecting to db")
+	logger.WithField("conn_string", applog.RedactDSN(conf.ConnString)).Trace("connecting to db")
+
+	if _, err := pgx.ParseConfig(conf.ConnString); err != nil {
+		logger.WithError(err).Error("invalid connection string")
 
-	conn, err := otelsqlx.ConnectContext(ctx, "pgx", conf.ConnString,
+		return nil, fmt.Errorf("unable to connect to database %w", err)
+	}
+	conn, err := otelsqlx.Open("pgx", conf.ConnString,
 		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
 	)
 	if err != nil {
@@ -27,12 +56,58 @@ func Connect(ctx context.Context, conf *config.DB) (*sqlx.DB, error) {
 
 		return nil, fmt.Errorf("unable to connect to database %w", err)
 	}
-	conn.SetMaxOpenConns(conf.MaxOpenCons)
+	conn.SetMaxOpenConns(conf.MaxOpenConns)
 	conn.SetConnMaxLifetime(conf.ConnMaxLifetime)
 
 	return conn, nil
 }
 
+// Ping waits until DB accepts connections, retrying according to conf.ConnectRetry.
+func Ping(ctx context.Context, conn *sqlx.DB, conf *config.DB) error {
+	return waitReachable(ctx, conf, conn.PingContext)
+}
+
+func waitReachable(ctx context.Context, conf *config.DB, ping func(ctx context.Context) error) error {
+	err := retry.Do(ctx, retry.NewPolicy(conf.ConnectRetry), "db connect", func(ctx context.Context) error {
+		pingErr := ping(ctx)
+		if pingErr != nil && !IsTransient(ctx, pingErr) {
+			return retry.Permanent(pingErr)
+		}
+
+		return pingErr //nolint:wrapcheck
+	})
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("unable to connect to database")
+
+		return fmt.Errorf("unable to connect to database %w", err)
+	}
+
+	return nil
+}
+
+// IsTransient reports whether err is caused by DB being unreachable or not ready yet,
+// so the operation may succeed later. Errors of canceled or expired ctx are not transient,
+// context.DeadlineExceeded would pass for a timeout of the network otherwise.
+func IsTransient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "08") || // connection exception
+			pgErr.Code == "57P01" || // admin_shutdown
+			pgErr.Code == "57P02" || // crash_shutdown
+			pgErr.Code == "57P03" || // cannot_connect_now, DB is starting up
+			pgErr.Code == "53300" // too_many_connections
+	}
+	var netErr net.Error
+
+	return errors.As(err, &netErr) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
 // Disconnect drops db connection.
 func Disconnect(ctx context.Context, dbConn *sqlx.DB) error {
 	logger := logging.FromContext(ctx)
@@ -2,10 +2,18 @@ package db
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/Sugar-pack/orders-manager/internal/config"
+	"github.com/jackc/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 )
@@ -29,3 +37,39 @@ func TestDisconnect(t *testing.T) {
 	assert.NoError(t, Disconnect(ctx, db))
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestPing_Retry(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	db := sqlx.NewDb(sqlDB, "sqlmock")
+	conf := &config.DB{ConnectRetry: &config.Retry{InitialInterval: time.Millisecond, MaxElapsedTime: time.Second}}
+
+	mock.ExpectPing().WillReturnError(&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED})
+	mock.ExpectPing().WillReturnError(&pgconn.PgError{Code: "57P03"})
+	mock.ExpectPing()
+	assert.NoError(t, Ping(context.Background(), db, conf))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectPing().WillReturnError(&pgconn.PgError{Code: "28P01"})
+	assert.Error(t, Ping(context.Background(), db, conf), "authentication failure is not retried")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIsTransient(t *testing.T) {
+	ctx := context.Background()
+	dialErr := fmt.Errorf("wrapped: %w", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED})
+	assert.True(t, IsTransient(ctx, dialErr))
+	assert.True(t, IsTransient(ctx, &pgconn.PgError{Code: "08006"}))
+	assert.True(t, IsTransient(ctx, io.ErrUnexpectedEOF))
+	assert.True(t, IsTransient(ctx, &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}), "network timeout")
+	assert.False(t, IsTransient(ctx, &pgconn.PgError{Code: "42P01"}))
+	assert.False(t, IsTransient(ctx, errors.New("syntax error")))
+	assert.False(t, IsTransient(ctx, fmt.Errorf("query: %w", context.DeadlineExceeded)), "deadline of the caller")
+	assert.False(t, IsTransient(ctx, context.Canceled))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, IsTransient(canceled, dialErr), "nothing succeeds with canceled ctx")
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	applog "github.com/Sugar-pack/orders-manager/internal/logger"
+)
+
+// OpenPool creates native pgx connection pool without connecting, use PingPool to wait for DB.
+func OpenPool(ctx context.Context, conf *config.DB) (*pgxpool.Pool, error) {
+	logger := logging.FromContext(ctx)
+	logger.WithField("conn_string", applog.RedactDSN(conf.ConnString)).Trace("connecting to db")
+
+	poolConf, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		logger.WithError(err).Error("invalid connection string")
+
+		return nil, fmt.Errorf("unable to connect to database %w", err)
+	}
+	poolConf.LazyConnect = true
+	if conf.MaxOpenConns > 0 {
+		poolConf.MaxConns = int32(conf.MaxOpenConns) //nolint:gosec // validated to fit
+	}
+	if conf.ConnMaxLifetime > 0 {
+		poolConf.MaxConnLifetime = conf.ConnMaxLifetime
+	}
+	pool, err := pgxpool.ConnectConfig(ctx, poolConf)
+	if err != nil {
+		logger.WithError(err).Error("unable to connect to database")
+
+		return nil, fmt.Errorf("unable to connect to database %w", err)
+	}
+
+	return pool, nil
+}
+
+// PingPool waits until DB accepts connections, retrying according to conf.ConnectRetry.
+func PingPool(ctx context.Context, pool *pgxpool.Pool, conf *config.DB) error {
+	return waitReachable(ctx, conf, pool.Ping)
+}
@@ -0,0 +1,116 @@
+// Package export writes orders in formats consumed outside of the service.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+)
+
+// Supported formats.
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// Formats lists supported formats.
+var Formats = []string{FormatCSV, FormatNDJSON, FormatParquet}
+
+// parquetRowGroup bounds rows buffered by parquet writer before they are written out.
+const parquetRowGroup = 64 * 1024
+
+// Record is an exported order, its fields are named like fields of pb.Order.
+type Record struct {
+	ID        string    `json:"id"         parquet:"id"`
+	UserID    string    `json:"user_id"    parquet:"user_id"`
+	Label     string    `json:"label"      parquet:"label"`
+	CreatedAt time.Time `json:"created_at" parquet:"created_at,timestamp(microsecond)"`
+}
+
+// header is the CSV header, columns follow fields of Record.
+var header = []string{"id", "user_id", "label", "created_at"}
+
+func newRecord(order *repository.Order) Record {
+	return Record{
+		ID:        order.ID.String(),
+		UserID:    order.UserID.String(),
+		Label:     order.Label,
+		CreatedAt: order.CreatedAt.UTC(),
+	}
+}
+
+// Writer writes orders one by one. Close flushes buffered orders, it does not close the underlying writer.
+type Writer interface {
+	Write(order *repository.Order) error
+	Close() error
+}
+
+// NewWriter creates Writer of format writing to output.
+func NewWriter(format string, output io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(output)
+		if err := writer.Write(header); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+
+		return &csvWriter{writer: writer}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(output)}, nil
+	case FormatParquet:
+		return &parquetWriter{writer: parquet.NewGenericWriter[Record](output, parquet.MaxRowsPerRowGroup(parquetRowGroup))}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q, supported are %v", format, Formats)
+	}
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvWriter) Write(order *repository.Order) error {
+	record := newRecord(order)
+
+	return w.writer.Write([]string{ //nolint:wrapcheck
+		record.ID, record.UserID, record.Label, record.CreatedAt.Format(time.RFC3339Nano),
+	})
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+
+	return w.writer.Error() //nolint:wrapcheck
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(order *repository.Order) error {
+	return w.encoder.Encode(newRecord(order)) //nolint:wrapcheck
+}
+
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
+
+type parquetWriter struct {
+	writer *parquet.GenericWriter[Record]
+}
+
+func (w *parquetWriter) Write(order *repository.Order) error {
+	_, err := w.writer.Write([]Record{newRecord(order)})
+
+	return err //nolint:wrapcheck
+}
+
+func (w *parquetWriter) Close() error {
+	return w.writer.Close() //nolint:wrapcheck
+}
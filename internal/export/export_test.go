@@ -0,0 +1,74 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+)
+
+func testOrders() []*repository.Order {
+	createdAt := time.Date(2026, time.September, 15, 10, 30, 0, 123456000, time.UTC)
+
+	return []*repository.Order{
+		{ID: uuid.New(), UserID: uuid.New(), Label: "first, with comma", CreatedAt: createdAt},
+		{ID: uuid.New(), UserID: uuid.New(), Label: "second", CreatedAt: createdAt.Add(time.Hour)},
+	}
+}
+
+func writeAll(t *testing.T, format string, orders []*repository.Order) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer, err := NewWriter(format, &buf)
+	if err != nil {
+		t.Fatalf("new writer: %v", err)
+	}
+	for _, order := range orders {
+		assert.NoError(t, writer.Write(order))
+	}
+	assert.NoError(t, writer.Close())
+
+	return buf.Bytes()
+}
+
+func TestWriter_CSV(t *testing.T) {
+	orders := testOrders()
+	rows, err := csv.NewReader(bytes.NewReader(writeAll(t, FormatCSV, orders))).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		header,
+		{orders[0].ID.String(), orders[0].UserID.String(), orders[0].Label, "2026-09-15T10:30:00.123456Z"},
+		{orders[1].ID.String(), orders[1].UserID.String(), orders[1].Label, "2026-09-15T11:30:00.123456Z"},
+	}, rows)
+}
+
+func TestWriter_NDJSON(t *testing.T) {
+	orders := testOrders()
+	decoder := json.NewDecoder(bytes.NewReader(writeAll(t, FormatNDJSON, orders)))
+	for _, order := range orders {
+		var record Record
+		assert.NoError(t, decoder.Decode(&record))
+		assert.Equal(t, newRecord(order), record)
+	}
+	assert.False(t, decoder.More())
+}
+
+func TestWriter_Parquet(t *testing.T) {
+	orders := testOrders()
+	data := writeAll(t, FormatParquet, orders)
+	records, err := parquet.Read[Record](bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	assert.Equal(t, []Record{newRecord(orders[0]), newRecord(orders[1])}, records)
+}
+
+func TestNewWriter_UnknownFormat(t *testing.T) {
+	_, err := NewWriter("xml", &bytes.Buffer{})
+	assert.ErrorContains(t, err, "unknown export format")
+}
@@ -0,0 +1,79 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Sugar-pack/orders-manager/internal/auth"
+)
+
+var errNoCredentials = errors.New("neither bearer token nor trusted client certificate provided")
+
+// WithAuthentication rejects calls without valid bearer token or trusted client certificate.
+// Principal of the caller is put to the context.
+func WithAuthentication(authenticator *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (
+		resp interface{}, err error,
+	) {
+		if isHealthMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		logger := logging.FromContext(ctx)
+		principal, err := authenticate(ctx, authenticator)
+		if err != nil {
+			logger.WithError(err).Warn("authentication failed")
+
+			return nil, status.Error(codes.Unauthenticated, "authentication required") //nolint:wrapcheck // should be wrapped as is
+		}
+
+		ctx = auth.WithPrincipal(ctx, principal)
+		ctx = logging.WithContext(ctx, logger.WithField("principal", principal.Subject))
+
+		return handler(ctx, req)
+	}
+}
+
+func authenticate(ctx context.Context, authenticator *auth.Authenticator) (*auth.Principal, error) {
+	if token, ok := bearerToken(ctx); ok {
+		return authenticator.Authenticate(token) //nolint:wrapcheck // logged as is
+	}
+	// REST callers need a token, certificates verified by the gateway are not trusted as principals
+	if identity, ok := ClientIdentityFromContext(ctx); ok && !identity.ViaGateway {
+		if principal, trusted := authenticator.PrincipalFromClientCN(identity.CommonName); trusted {
+			return principal, nil
+		}
+	}
+
+	return nil, errNoCredentials
+}
+
+func bearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	for _, value := range md.Get("authorization") {
+		scheme, token, found := strings.Cut(value, " ")
+		if found && strings.EqualFold(scheme, "bearer") && token != "" {
+			return strings.TrimSpace(token), true
+		}
+	}
+
+	return "", false
+}
+
+// authzError converts policy decision to grpc status.
+func authzError(err error) error {
+	if errors.Is(err, auth.ErrUnauthenticated) {
+		return status.Error(codes.Unauthenticated, "authentication required") //nolint:wrapcheck // should be wrapped as is
+	}
+
+	return status.Error(codes.PermissionDenied, "access denied") //nolint:wrapcheck // should be wrapped as is
+}
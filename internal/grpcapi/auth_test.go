@@ -0,0 +1,173 @@
+package grpcapi
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Sugar-pack/orders-manager/internal/auth"
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	"github.com/Sugar-pack/orders-manager/internal/mock"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+	"github.com/Sugar-pack/orders-manager/pkg/pb"
+)
+
+const testAuthSecret = "secret"
+
+// startServer serves repo on a random local port and returns connection to it.
+func startServer(t *testing.T, repo repository.OrderRepoWith2PC, opts ...Option) *grpc.ClientConn {
+	t.Helper()
+	srv, err := CreateServer(logging.GetLogger(), repo, opts...)
+	if err != nil {
+		t.Fatalf("CreateServer error: %v", err)
+	}
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func withAuthOption(t *testing.T) Option {
+	t.Helper()
+	conf := &config.Auth{
+		Enabled:    true,
+		StaticKeys: []config.StaticKey{{KeyID: "test", Secret: testAuthSecret}},
+	}
+	authenticator, err := auth.NewAuthenticator(conf)
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+
+	return WithAuth(authenticator, auth.NewPolicy(conf))
+}
+
+func testToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testAuthSecret))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	return signed
+}
+
+func withBearer(t *testing.T, subject string, roles ...string) context.Context {
+	t.Helper()
+	token := testToken(t, jwt.MapClaims{"sub": subject, "roles": roles})
+
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestAuth_Unauthenticated(t *testing.T) {
+	conn := startServer(t, &mock.OrderRepoWith2PC{}, withAuthOption(t))
+	client := pb.NewOrdersManagerServiceClient(conn)
+
+	_, err := client.GetOrder(context.Background(), &pb.GetOrderRequest{Id: uuid.NewString()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer garbage")
+	_, err = client.GetOrder(ctx, &pb.GetOrderRequest{Id: uuid.NewString()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAuth_GetOrder(t *testing.T) {
+	repo := &mock.OrderRepoWith2PC{}
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+	missing := uuid.New()
+	repo.On("GetOrder", testify.Anything, order.ID).Return(order, nil)
+	repo.On("GetOrder", testify.Anything, missing).Return(nil, sql.ErrNoRows)
+	client := pb.NewOrdersManagerServiceClient(startServer(t, repo, withAuthOption(t)))
+	request := &pb.GetOrderRequest{Id: order.ID.String()}
+
+	_, err := client.GetOrder(withBearer(t, order.UserID.String()), request)
+	assert.NoError(t, err, "owner may read own order")
+
+	_, foreignErr := client.GetOrder(withBearer(t, uuid.NewString()), request)
+	_, missingErr := client.GetOrder(withBearer(t, uuid.NewString()), &pb.GetOrderRequest{Id: missing.String()})
+	assert.Equal(t, codes.NotFound, status.Code(missingErr))
+	assert.Equal(t, status.Convert(missingErr).Proto(), status.Convert(foreignErr).Proto(),
+		"others' orders look missing")
+
+	_, err = client.GetOrder(withBearer(t, "tx-coordinator", auth.DefaultCoordinatorRole), request)
+	assert.NoError(t, err, "coordinator may read any order")
+}
+
+func TestAuth_BatchGetOrders(t *testing.T) {
+	repo := &mock.OrderRepoWith2PC{}
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+	repo.On("GetOrders", testify.Anything, []uuid.UUID{order.ID}).Return([]*repository.Order{order}, nil)
+	client := pb.NewOrdersManagerServiceClient(startServer(t, repo, withAuthOption(t)))
+	request := &pb.BatchGetOrdersRequest{Ids: []string{order.ID.String()}}
+
+	response, err := client.BatchGetOrders(withBearer(t, order.UserID.String()), request)
+	assert.NoError(t, err, "owner may read own orders")
+	assert.Len(t, response.GetOrders(), 1)
+
+	response, err = client.BatchGetOrders(withBearer(t, uuid.NewString()), request)
+	assert.NoError(t, err)
+	assert.Empty(t, response.GetOrders(), "user may not read others' orders")
+	assert.Equal(t, []string{order.ID.String()}, response.GetMissingIds(), "others' orders look missing")
+}
+
+func TestAuth_SendConfirmation(t *testing.T) {
+	repo := &mock.OrderRepoWith2PC{}
+	txID := uuid.New()
+	repo.On("CommitInsertTransaction", testify.Anything, txID).Return(nil).Once()
+	client := pb.NewTnxConfirmingServiceClient(startServer(t, repo, withAuthOption(t)))
+	confirmation := &pb.Confirmation{Tnx: txID.String(), Commit: true}
+
+	_, err := client.SendConfirmation(withBearer(t, uuid.NewString()), confirmation)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "end user may not confirm")
+
+	_, err = client.SendConfirmation(withBearer(t, "tx-coordinator", auth.DefaultCoordinatorRole), confirmation)
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestAuthenticate_ClientCN(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator(&config.Auth{
+		Enabled:              true,
+		StaticKeys:           []config.StaticKey{{KeyID: "test", Secret: testAuthSecret}},
+		CoordinatorClientCNs: []string{"tx-coordinator"},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+
+	direct := context.WithValue(context.Background(), clientIdentityCtx{}, &ClientIdentity{CommonName: "tx-coordinator"})
+	principal, err := authenticate(direct, authenticator)
+	if assert.NoError(t, err) {
+		assert.True(t, principal.HasRole(auth.DefaultCoordinatorRole))
+	}
+
+	viaGateway := context.WithValue(context.Background(), clientIdentityCtx{},
+		&ClientIdentity{CommonName: "tx-coordinator", ViaGateway: true})
+	_, err = authenticate(viaGateway, authenticator)
+	assert.ErrorIs(t, err, errNoCredentials, "REST callers need a token")
+}
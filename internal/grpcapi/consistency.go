@@ -0,0 +1,51 @@
+package grpcapi
+
+import (
+	"context"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/Sugar-pack/orders-manager/internal/cache"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+)
+
+// ReadYourWritesHeader set to true makes reads of the call go to primary DB instead of a replica.
+const ReadYourWritesHeader = "x-read-your-writes"
+
+// WithReadYourWrites marks context of calls carrying ReadYourWritesHeader for repository.Router.
+func WithReadYourWrites(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (
+	resp interface{}, err error,
+) {
+	if headerFlag(ctx, ReadYourWritesHeader) {
+		ctx = repository.WithReadYourWrites(ctx)
+	}
+
+	return handler(ctx, req)
+}
+
+// CacheBypassHeader set to true makes reads of the call skip the orders cache.
+const CacheBypassHeader = "x-cache-bypass"
+
+// WithCacheBypass marks context of calls carrying CacheBypassHeader for cache.Repository.
+func WithCacheBypass(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (
+	resp interface{}, err error,
+) {
+	if headerFlag(ctx, CacheBypassHeader) {
+		ctx = cache.WithBypass(ctx)
+	}
+
+	return handler(ctx, req)
+}
+
+// headerFlag reports whether boolean header is set to true in incoming metadata.
+func headerFlag(ctx context.Context, header string) bool {
+	for _, value := range metadata.ValueFromIncomingContext(ctx, header) {
+		if flag, err := strconv.ParseBool(value); err == nil && flag {
+			return true
+		}
+	}
+
+	return false
+}
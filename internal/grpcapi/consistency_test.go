@@ -0,0 +1,76 @@
+package grpcapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/Sugar-pack/orders-manager/internal/cache"
+	"github.com/Sugar-pack/orders-manager/internal/mock"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+	"github.com/Sugar-pack/orders-manager/pkg/pb"
+)
+
+func expectGetOrder(repo *mock.OrderRepoWith2PC, order *repository.Order, readYourWrites bool) {
+	repo.On("GetOrder", testify.MatchedBy(func(ctx context.Context) bool {
+		return repository.ReadYourWritesRequired(ctx) == readYourWrites
+	}), order.ID).Return(order, nil).Once()
+}
+
+func TestReadYourWrites(t *testing.T) {
+	repo := &mock.OrderRepoWith2PC{}
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+	client := pb.NewOrdersManagerServiceClient(startServer(t, repo))
+	request := &pb.GetOrderRequest{Id: order.ID.String()}
+
+	expectGetOrder(repo, order, false)
+	_, err := client.GetOrder(context.Background(), request)
+	assert.NoError(t, err)
+
+	expectGetOrder(repo, order, true)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), ReadYourWritesHeader, "true")
+	_, err = client.GetOrder(ctx, request)
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestGateway_ReadYourWrites(t *testing.T) {
+	repo := &mock.OrderRepoWith2PC{}
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+	expectGetOrder(repo, order, true)
+	handler := gatewayHandler(t, repo)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/orders/"+order.ID.String(), nil)
+	req.Header.Set("X-Read-Your-Writes", "true")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	repo.AssertExpectations(t)
+}
+
+func TestCacheBypass(t *testing.T) {
+	repo := &mock.OrderRepoWith2PC{}
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+	client := pb.NewOrdersManagerServiceClient(startServer(t, repo))
+	request := &pb.GetOrderRequest{Id: order.ID.String()}
+
+	for _, bypass := range []bool{false, true} {
+		repo.On("GetOrder", testify.MatchedBy(func(ctx context.Context) bool {
+			return cache.Bypassed(ctx) == bypass
+		}), order.ID).Return(order, nil).Once()
+	}
+	_, err := client.GetOrder(context.Background(), request)
+	assert.NoError(t, err)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), CacheBypassHeader, "true")
+	_, err = client.GetOrder(ctx, request)
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,149 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	"github.com/Sugar-pack/orders-manager/internal/tlsconfig"
+	"github.com/Sugar-pack/orders-manager/pkg/pb"
+)
+
+const (
+	// OpenAPIPath is a path the OpenAPI spec of the gateway is served on.
+	OpenAPIPath = "/openapi.json"
+
+	gatewayShutdownTimeout = 5 * time.Second
+	gatewayHeaderTimeout   = 10 * time.Second
+
+	// forwardedAddrHeader carries address of a REST caller, it is trusted only from the gateway.
+	forwardedAddrHeader = "x-orders-forwarded-addr"
+)
+
+// createGateway returns http handler translating REST/JSON calls to the gRPC server serving gateway.
+// Calls are proxied through the gRPC server, so they pass the same interceptors as native gRPC calls.
+func createGateway(ctx context.Context, gateway *gatewayListener) (http.Handler, error) {
+	conn, err := grpc.NewClient("passthrough:///gateway",
+		grpc.WithContextDialer(gateway.dial),
+		// the connection does not leave the process
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return nil, err //nolint:wrapcheck //should be wrapped in main
+	}
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	gwMux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(gatewayHeaderMatcher),
+		runtime.WithMetadata(forwardCaller),
+	)
+	for _, register := range []func(context.Context, *runtime.ServeMux, *grpc.ClientConn) error{
+		pb.RegisterOrdersManagerServiceHandler,
+		pb.RegisterTnxConfirmingServiceHandler,
+		pb.RegisterWebhookAdminServiceHandler,
+	} {
+		if err = register(ctx, gwMux, conn); err != nil {
+			return nil, err //nolint:wrapcheck //should be wrapped in main
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(OpenAPIPath, serveOpenAPI)
+	mux.Handle("/", gwMux)
+
+	return mux, nil
+}
+
+// forwardCaller passes verified certificate and address of the REST caller to the gRPC server.
+func forwardCaller(_ context.Context, r *http.Request) metadata.MD {
+	md := metadata.Pairs(forwardedAddrHeader, r.RemoteAddr)
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		identity, err := json.Marshal(identityFromCert(r.TLS.VerifiedChains[0][0]))
+		if err == nil {
+			md.Set(forwardedIdentityHeader, string(identity))
+		}
+	}
+
+	return md
+}
+
+// gatewayHeaderMatcher passes headers of the service as metadata in addition to the default ones.
+// Metadata forwarded by the gateway itself cannot be sent by REST callers.
+func gatewayHeaderMatcher(key string) (string, bool) {
+	for _, header := range []string{ReadYourWritesHeader, CacheBypassHeader, TenantHeader} {
+		if strings.EqualFold(key, header) {
+			return header, true
+		}
+	}
+	name, ok := runtime.DefaultHeaderMatcher(key)
+	if strings.EqualFold(name, forwardedIdentityHeader) || strings.EqualFold(name, forwardedAddrHeader) {
+		return "", false
+	}
+
+	return name, ok
+}
+
+func serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(pb.OpenAPISpec)
+}
+
+// serveGateway starts REST/JSON gateway on appConfig.HTTPBind reaching server through gateway.
+// With reloader the gateway serves HTTPS with the certificates of the gRPC listener and verifies
+// REST callers the same way. Returned func gracefully stops it.
+func serveGateway(ctx context.Context, appConfig *config.API, reloader *tlsconfig.Reloader, gateway *gatewayListener,
+) (func(), error) {
+	logger := logging.FromContext(ctx)
+	lis, err := net.Listen("tcp", appConfig.HTTPBind)
+	if err != nil {
+		return nil, err //nolint:wrapcheck //should be wrapped in main
+	}
+	handler, err := createGateway(ctx, gateway)
+	if err != nil {
+		_ = lis.Close()
+
+		return nil, err
+	}
+	if reloader != nil {
+		lis = tls.NewListener(lis, reloader.ServerConfig())
+	}
+
+	httpServer := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: gatewayHeaderTimeout,
+	}
+	go func() {
+		logger.WithField("bind", lis.Addr().String()).Info("serving REST gateway")
+		if serveErr := httpServer.Serve(lis); serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+			logger.WithError(serveErr).Error("REST gateway failed")
+		}
+	}()
+
+	return func() {
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), gatewayShutdownTimeout)
+		defer cancel()
+		if shutdownErr := httpServer.Shutdown(shutdownCtx); shutdownErr != nil {
+			logger.WithError(shutdownErr).Error("shutting down REST gateway failed")
+		}
+	}, nil
+}
@@ -0,0 +1,109 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// gatewayListener connects the REST gateway to the gRPC server of the same process over net.Pipe.
+// Connections it accepts are known to come from the gateway, see fromGateway.
+type gatewayListener struct {
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newGatewayListener() *gatewayListener {
+	return &gatewayListener{conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+func (l *gatewayListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return &gatewayConn{Conn: conn}, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *gatewayListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+
+	return nil
+}
+
+func (l *gatewayListener) Addr() net.Addr {
+	return gatewayAddr{}
+}
+
+func (l *gatewayListener) dial(ctx context.Context, _ string) (net.Conn, error) {
+	client, server := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err() //nolint:wrapcheck // returned to grpc client as is
+	}
+}
+
+// gatewayAddr is the address of both ends of gatewayListener connections.
+type gatewayAddr struct{}
+
+func (gatewayAddr) Network() string {
+	return "pipe"
+}
+
+func (gatewayAddr) String() string {
+	return "gateway"
+}
+
+// gatewayConn is a server side connection of gatewayListener.
+type gatewayConn struct {
+	net.Conn
+}
+
+// gatewayAuthInfo marks calls of the REST gateway.
+type gatewayAuthInfo struct {
+	credentials.CommonAuthInfo
+}
+
+func (gatewayAuthInfo) AuthType() string {
+	return "gateway"
+}
+
+// gatewayCredentials are server credentials passing connections of gatewayListener without handshake.
+// The gateway does not leave the process, it terminates TLS of REST callers itself.
+type gatewayCredentials struct {
+	credentials.TransportCredentials
+}
+
+func (c *gatewayCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	if _, ok := conn.(*gatewayConn); ok {
+		return conn, gatewayAuthInfo{CommonAuthInfo: credentials.CommonAuthInfo{
+			SecurityLevel: credentials.PrivacyAndIntegrity,
+		}}, nil
+	}
+
+	return c.TransportCredentials.ServerHandshake(conn) //nolint:wrapcheck // returned to grpc server as is
+}
+
+func (c *gatewayCredentials) Clone() credentials.TransportCredentials {
+	return &gatewayCredentials{TransportCredentials: c.TransportCredentials.Clone()}
+}
+
+// fromGateway reports whether the call was made by the REST gateway of this process.
+func fromGateway(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	_, ok = p.AuthInfo.(gatewayAuthInfo)
+
+	return ok
+}
@@ -0,0 +1,165 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	"github.com/Sugar-pack/orders-manager/internal/mock"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+	"github.com/Sugar-pack/orders-manager/internal/tlsconfig"
+	"github.com/Sugar-pack/orders-manager/pkg/pb"
+)
+
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCertificate creates certificate signed by issuer, self-signed CA without issuer.
+func newTestCertificate(t *testing.T, issuer *testCertificate, commonName string, usage ...x509.ExtKeyUsage,
+) *testCertificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  usage,
+	}
+	parent, signer := tmpl, key
+	if issuer == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid, tmpl.KeyUsage = true, true, x509.KeyUsageCertSign
+	} else {
+		tmpl.DNSNames = []string{"localhost"}
+		tmpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+		parent, signer = issuer.cert, issuer.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	return &testCertificate{cert: cert, key: key}
+}
+
+func (c *testCertificate) write(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	assert.NoError(t, err)
+	certPath, keyPath := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	assert.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600))
+	assert.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certPath, keyPath
+}
+
+func (c *testCertificate) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+// captureIdentity records client identity seen by handlers.
+func captureIdentity(identity **ClientIdentity) Option {
+	return WithGRPCOptions(grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		*identity, _ = ClientIdentityFromContext(ctx)
+
+		return handler(ctx, req)
+	}))
+}
+
+func TestGateway_MutualTLS(t *testing.T) {
+	// server certificate is not valid for client authentication and is issued by another CA than clients
+	serverCA := newTestCertificate(t, nil, "server-ca")
+	clientCA := newTestCertificate(t, nil, "client-ca")
+	server := newTestCertificate(t, serverCA, "orders-manager", x509.ExtKeyUsageServerAuth)
+	client := newTestCertificate(t, clientCA, "coordinator", x509.ExtKeyUsageClientAuth)
+	dir := t.TempDir()
+	certFile, keyFile := server.write(t, dir, "server")
+	clientCAFile, _ := clientCA.write(t, dir, "client-ca")
+	reloader, err := tlsconfig.NewReloader(logging.GetLogger(), &config.TLS{
+		CertFile: certFile, KeyFile: keyFile, ClientCAFile: clientCAFile, RequireClientCert: true,
+	})
+	assert.NoError(t, err)
+
+	repo := &mock.OrderRepoWith2PC{}
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now()}
+	repo.On("GetOrder", testify.Anything, order.ID).Return(order, nil)
+	var identity *ClientIdentity
+	restServer := httptest.NewUnstartedServer(gatewayHandler(t, repo, WithTLS(reloader), captureIdentity(&identity)))
+	restServer.TLS = reloader.ServerConfig()
+	restServer.StartTLS()
+	defer restServer.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+	restClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		RootCAs:      roots,
+		ServerName:   "localhost",
+		Certificates: []tls.Certificate{client.tlsCertificate()},
+	}}}
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+		restServer.URL+"/v1/orders/"+order.ID.String(), nil)
+	assert.NoError(t, err)
+	// REST callers cannot forge identity forwarded by the gateway
+	request.Header.Set("Grpc-Metadata-"+forwardedIdentityHeader, `{"common_name":"admin"}`)
+	response, err := restClient.Do(request)
+	if assert.NoError(t, err) {
+		defer response.Body.Close()
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+	}
+	if assert.NotNil(t, identity, "identity of the REST caller is forwarded") {
+		assert.Equal(t, "coordinator", identity.CommonName)
+		assert.True(t, identity.ViaGateway)
+	}
+}
+
+func TestForwardedIdentity_NotTrustedFromNetwork(t *testing.T) {
+	logger := logging.GetLogger()
+	repo := &mock.OrderRepoWith2PC{}
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now()}
+	repo.On("GetOrder", testify.Anything, order.ID).Return(order, nil)
+	var identity *ClientIdentity
+	srv, err := CreateServer(logger, repo, captureIdentity(&identity))
+	assert.NoError(t, err)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer conn.Close()
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		forwardedIdentityHeader, `{"common_name":"coordinator"}`, forwardedAddrHeader, "10.0.0.1:1234")
+	_, err = pb.NewOrdersManagerServiceClient(conn).GetOrder(ctx, &pb.GetOrderRequest{Id: order.ID.String()})
+	assert.NoError(t, err)
+	assert.Nil(t, identity, "forwarded identity is trusted only from the gateway")
+}
@@ -0,0 +1,119 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	"github.com/Sugar-pack/orders-manager/internal/mock"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+)
+
+func gatewayHandler(t *testing.T, repo *mock.OrderRepoWith2PC, opts ...Option) http.Handler {
+	t.Helper()
+	logger := logging.GetLogger()
+	ctx, cancel := context.WithCancel(logging.WithContext(context.Background(), logger))
+	t.Cleanup(cancel)
+
+	srv, err := CreateServer(logger, repo, opts...)
+	if err != nil {
+		t.Fatalf("CreateServer error: %v", err)
+	}
+	gateway := newGatewayListener()
+	go func() {
+		_ = srv.Serve(gateway)
+	}()
+	t.Cleanup(srv.Stop)
+
+	handler, err := createGateway(ctx, gateway)
+	if err != nil {
+		t.Fatalf("createGateway error: %v", err)
+	}
+
+	return handler
+}
+
+func TestGateway_GetOrder(t *testing.T) {
+	repo := &mock.OrderRepoWith2PC{}
+	orderID := uuid.New()
+	orderDB := &repository.Order{
+		ID:        orderID,
+		UserID:    uuid.New(),
+		Label:     "label",
+		CreatedAt: time.Now().UTC(),
+	}
+	repo.On("GetOrder", testify.Anything, orderID).Return(orderDB, nil)
+	handler := gatewayHandler(t, repo)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/orders/"+orderID.String(), nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var body map[string]any
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, orderID.String(), body["id"])
+	assert.Equal(t, orderDB.UserID.String(), body["userId"])
+	assert.Equal(t, orderDB.Label, body["label"])
+}
+
+func TestGateway_SendConfirmation(t *testing.T) {
+	repo := &mock.OrderRepoWith2PC{}
+	txID := uuid.New()
+	repo.On("CommitInsertTransaction", testify.Anything, txID).Return(nil)
+	handler := gatewayHandler(t, repo)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/transactions/"+txID.String()+":commit",
+		strings.NewReader(`{"commit": true}`))
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	repo.AssertExpectations(t)
+}
+
+func TestGateway_InsertOrder_BadUserID(t *testing.T) {
+	handler := gatewayHandler(t, &mock.OrderRepoWith2PC{})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/orders",
+		strings.NewReader(`{"userId": "definitely not a uuid", "label": "label"}`))
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestGateway_OpenAPI(t *testing.T) {
+	handler := gatewayHandler(t, &mock.OrderRepoWith2PC{})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, OpenAPIPath, nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var spec map[string]any
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &spec))
+	assert.Contains(t, spec["paths"], "/v1/orders/{id}")
+}
+
+func TestServeWithTrace_Gateway(t *testing.T) {
+	logger := logging.GetLogger()
+	ctx := logging.WithContext(context.Background(), logger)
+	srv, err := CreateServer(logger, &mock.OrderRepoWith2PC{})
+	if err != nil {
+		t.Fatalf("CreateServer error: %v", err)
+	}
+	cfg := &config.API{Bind: "localhost:0", HTTPBind: "localhost:999999"}
+	if err := ServeWithTrace(ctx, srv, cfg, nil, &config.Tracing{Exporter: config.ExporterNone}); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -6,44 +6,101 @@ import (
 
 	"github.com/Sugar-pack/users-manager/pkg/logging"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 
 	"github.com/Sugar-pack/orders-manager/internal/config"
 	"github.com/Sugar-pack/orders-manager/internal/repository"
+	"github.com/Sugar-pack/orders-manager/internal/tlsconfig"
 	"github.com/Sugar-pack/orders-manager/internal/tracing"
 	"github.com/Sugar-pack/orders-manager/pkg/pb"
 )
 
-func CreateServer(logger logging.Logger, repo repository.OrderRepoWith2PC) (*grpc.Server, error) {
-	grpcServer := grpc.NewServer(
-		grpc.ChainUnaryInterceptor(
-			logging.WithLogger(logger),
-			logging.WithUniqTraceID,
-			logging.LogBoundaries,
-		),
+func CreateServer(logger logging.Logger, repo repository.OrderRepoWith2PC, opts ...Option) (*grpc.Server, error) {
+	options := &serverOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.meterProvider == nil {
+		options.meterProvider = otel.GetMeterProvider()
+	}
+	panics, err := newPanicsCounter(options.meterProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	interceptors := []grpc.UnaryServerInterceptor{
+		logging.WithLogger(logger),
+		logging.WithUniqTraceID,
+		WithRecovery(panics),
+		WithClientIdentity,
+		WithReadYourWrites,
+		WithCacheBypass,
+		logging.LogBoundaries,
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		StreamWithLogger(logger),
+		StreamWithUniqTraceID,
+		StreamWithRecovery(panics),
+		StreamWithClientIdentity,
+		StreamWithReadYourWrites,
+		StreamWithCacheBypass,
+		StreamLogBoundaries,
+	}
+	transport := insecure.NewCredentials()
+	if options.tls != nil {
+		transport = credentials.NewTLS(options.tls.ServerConfig())
+	}
+	serverOpts := []grpc.ServerOption{
+		grpc.Creds(&gatewayCredentials{TransportCredentials: transport}),
+		grpc.ChainUnaryInterceptor(append(interceptors, options.interceptors...)...),
+		grpc.ChainStreamInterceptor(append(streamInterceptors, options.streams...)...),
 		grpc.StatsHandler(otelgrpc.NewServerHandler()),
-	)
+	}
+	grpcServer := grpc.NewServer(append(serverOpts, options.grpcOpts...)...)
 
 	orderService := &OrderService{
-		Repo: repo,
+		Repo:   repo,
+		Policy: options.policy,
 	}
 	pb.RegisterOrdersManagerServiceServer(grpcServer, orderService)
 
 	transactionService := &TnxConfirmingService{
-		Repo: repo,
+		Repo:     repo,
+		Policy:   options.policy,
+		Webhooks: options.webhooks,
 	}
 	pb.RegisterTnxConfirmingServiceServer(grpcServer, transactionService)
 
+	if options.webhookStore != nil {
+		webhookService := &WebhookAdminService{
+			Store:  options.webhookStore,
+			Policy: options.policy,
+		}
+		pb.RegisterWebhookAdminServiceServer(grpcServer, webhookService)
+	}
+
+	if options.readiness != nil {
+		healthpb.RegisterHealthServer(grpcServer, options.readiness.Server())
+	}
+
 	return grpcServer, nil
 }
 
-func ServeWithTrace(ctx context.Context, server *grpc.Server, appConfig *config.API) error {
+// ServeWithTrace serves server on the api listeners with tracing configured by tracingConf.
+// The REST gateway serves TLS with certificates of reloader, it should be the one passed to WithTLS.
+func ServeWithTrace(ctx context.Context, server *grpc.Server, appConfig *config.API, reloader *tlsconfig.Reloader,
+	tracingConf *config.Tracing,
+) error {
 	logger := logging.FromContext(ctx)
 	lis, err := net.Listen("tcp", appConfig.Bind)
 	if err != nil {
 		return err //nolint:wrapcheck //should be wrapped in main
 	}
-	tracingProvider, err := tracing.InitTracing(ctx, logger)
+	tracingProvider, err := tracing.InitTracing(ctx, logger, tracingConf)
 	if err != nil {
 		return err //nolint:wrapcheck //should be wrapped in main
 	}
@@ -53,6 +110,20 @@ func ServeWithTrace(ctx context.Context, server *grpc.Server, appConfig *config.
 		}
 	}()
 
+	if appConfig.HTTPBind != "" {
+		gateway := newGatewayListener()
+		go func() {
+			if serveErr := server.Serve(gateway); serveErr != nil {
+				logger.WithError(serveErr).Error("serving REST gateway connections failed")
+			}
+		}()
+		stopGateway, gwErr := serveGateway(ctx, appConfig, reloader, gateway)
+		if gwErr != nil {
+			return gwErr
+		}
+		defer stopGateway()
+	}
+
 	if serveErr := server.Serve(lis); serveErr != nil {
 		return serveErr //nolint:wrapcheck //should be wrapped in main
 	}
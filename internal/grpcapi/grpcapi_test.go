@@ -33,7 +33,7 @@ func TestServeWithTrace(t *testing.T) {
 	cfg := &config.API{Bind: "localhost:0"}
 	done := make(chan error)
 	go func() {
-		done <- ServeWithTrace(ctx, srv, cfg)
+		done <- ServeWithTrace(ctx, srv, cfg, nil, &config.Tracing{Exporter: config.ExporterNone})
 	}()
 	time.Sleep(100 * time.Millisecond)
 	srv.GracefulStop()
@@ -47,7 +47,7 @@ func TestServeWithTrace_ListenErr(t *testing.T) {
 	ctx := logging.WithContext(context.Background(), logger)
 	srv, _ := CreateServer(logger, &mock.OrderRepoWith2PC{})
 	cfg := &config.API{Bind: "localhost:999999"}
-	if err := ServeWithTrace(ctx, srv, cfg); err == nil {
+	if err := ServeWithTrace(ctx, srv, cfg, nil, &config.Tracing{Exporter: config.ExporterNone}); err == nil {
 		t.Fatal("expected error")
 	}
 }
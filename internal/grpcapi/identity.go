@@ -0,0 +1,101 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// forwardedIdentityHeader carries verified certificate of a REST caller, it is trusted only from the gateway.
+const forwardedIdentityHeader = "x-orders-forwarded-identity"
+
+// ClientIdentity describes a client authenticated by verified TLS certificate.
+type ClientIdentity struct {
+	CommonName   string   `json:"common_name"`
+	DNSNames     []string `json:"dns_names,omitempty"`
+	URIs         []string `json:"uris,omitempty"`
+	SerialNumber string   `json:"serial_number"`
+	// ViaGateway is set for REST callers, their certificate was verified by the gateway
+	ViaGateway bool `json:"-"`
+}
+
+type clientIdentityCtx struct{}
+
+// ClientIdentityFromContext returns identity of the TLS client, if its certificate was verified.
+func ClientIdentityFromContext(ctx context.Context) (*ClientIdentity, bool) {
+	identity, ok := ctx.Value(clientIdentityCtx{}).(*ClientIdentity)
+
+	return identity, ok
+}
+
+func identityFromCert(cert *x509.Certificate) *ClientIdentity {
+	uris := make([]string, 0, len(cert.URIs))
+	for _, uri := range cert.URIs {
+		uris = append(uris, uri.String())
+	}
+
+	return &ClientIdentity{
+		CommonName:   cert.Subject.CommonName,
+		DNSNames:     cert.DNSNames,
+		URIs:         uris,
+		SerialNumber: cert.SerialNumber.String(),
+	}
+}
+
+func peerIdentity(ctx context.Context) (*ClientIdentity, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+
+	return identityFromCert(tlsInfo.State.VerifiedChains[0][0]), true
+}
+
+// forwardedIdentity returns identity of the REST caller forwarded by the gateway.
+func forwardedIdentity(ctx context.Context) (*ClientIdentity, bool) {
+	values := metadata.ValueFromIncomingContext(ctx, forwardedIdentityHeader)
+	if len(values) != 1 {
+		return nil, false
+	}
+	identity := new(ClientIdentity)
+	if err := json.Unmarshal([]byte(values[0]), identity); err != nil {
+		return nil, false
+	}
+	identity.ViaGateway = true
+
+	return identity, true
+}
+
+// callerIdentity returns identity of TLS client, for calls of the gateway the one of the REST caller.
+func callerIdentity(ctx context.Context) (*ClientIdentity, bool) {
+	if fromGateway(ctx) {
+		return forwardedIdentity(ctx)
+	}
+
+	return peerIdentity(ctx)
+}
+
+// WithClientIdentity puts identity of verified TLS client to the context and to the logger.
+// Calls of the REST gateway get identity of the REST caller.
+func WithClientIdentity(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (
+	resp interface{}, err error,
+) {
+	identity, ok := callerIdentity(ctx)
+	if ok {
+		logger := logging.FromContext(ctx).WithField("client_cn", identity.CommonName)
+		ctx = logging.WithContext(ctx, logger)
+		ctx = context.WithValue(ctx, clientIdentityCtx{}, identity)
+	}
+
+	return handler(ctx, req)
+}
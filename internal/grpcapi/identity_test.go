@@ -0,0 +1,62 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/url"
+	"testing"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+func TestWithClientIdentity_Verified(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://orders/coordinator")
+	cert := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "coordinator"},
+		DNSNames:     []string{"coordinator.local"},
+		URIs:         []*url.URL{spiffe},
+	}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{cert}},
+		}},
+	})
+	ctx = logging.WithContext(ctx, logging.GetLogger())
+
+	var got *ClientIdentity
+	_, err := WithClientIdentity(ctx, nil, &grpc.UnaryServerInfo{},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			got, _ = ClientIdentityFromContext(ctx)
+
+			return nil, nil
+		})
+	assert.NoError(t, err)
+	if assert.NotNil(t, got) {
+		assert.Equal(t, "coordinator", got.CommonName)
+		assert.Equal(t, []string{"coordinator.local"}, got.DNSNames)
+		assert.Equal(t, []string{"spiffe://orders/coordinator"}, got.URIs)
+		assert.Equal(t, "42", got.SerialNumber)
+	}
+}
+
+func TestWithClientIdentity_Plaintext(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{})
+
+	var ok bool
+	_, err := WithClientIdentity(ctx, nil, &grpc.UnaryServerInfo{},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			_, ok = ClientIdentityFromContext(ctx)
+
+			return nil, nil
+		})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,100 @@
+package grpcapi
+
+import (
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc"
+
+	"github.com/Sugar-pack/orders-manager/internal/auth"
+	"github.com/Sugar-pack/orders-manager/internal/health"
+	applog "github.com/Sugar-pack/orders-manager/internal/logger"
+	"github.com/Sugar-pack/orders-manager/internal/ratelimit"
+	"github.com/Sugar-pack/orders-manager/internal/tlsconfig"
+)
+
+// Option configures server created by CreateServer.
+type Option func(*serverOptions)
+
+type serverOptions struct {
+	grpcOpts      []grpc.ServerOption
+	interceptors  []grpc.UnaryServerInterceptor
+	streams       []grpc.StreamServerInterceptor
+	policy        *auth.Policy
+	readiness     *health.Readiness
+	webhookStore  WebhookStore
+	webhooks      WebhookNotifier
+	tls           *tlsconfig.Reloader
+	meterProvider metric.MeterProvider
+}
+
+// WithGRPCOptions passes options to grpc.NewServer as is.
+func WithGRPCOptions(opts ...grpc.ServerOption) Option {
+	return func(o *serverOptions) {
+		o.grpcOpts = append(o.grpcOpts, opts...)
+	}
+}
+
+// WithMeterProvider creates metrics of the server with provider, the global one is used without it.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(o *serverOptions) {
+		o.meterProvider = provider
+	}
+}
+
+// WithTLS serves TLS with certificates of reloader, plaintext is served without it.
+func WithTLS(reloader *tlsconfig.Reloader) Option {
+	return func(o *serverOptions) {
+		o.tls = reloader
+	}
+}
+
+// WithAuth requires callers to authenticate and enforces policy in handlers.
+func WithAuth(authenticator *auth.Authenticator, policy *auth.Policy) Option {
+	return func(o *serverOptions) {
+		o.interceptors = append(o.interceptors, WithAuthentication(authenticator))
+		o.streams = append(o.streams, StreamWithAuthentication(authenticator))
+		o.policy = policy
+	}
+}
+
+// WithRateLimiter rejects calls exceeding limits. It should follow WithAuth to limit by principal.
+func WithRateLimiter(limiter *ratelimit.Limiter) Option {
+	return func(o *serverOptions) {
+		o.interceptors = append(o.interceptors, WithRateLimit(limiter))
+		o.streams = append(o.streams, StreamWithRateLimit(limiter))
+	}
+}
+
+// WithReadiness serves grpc health service reporting readiness and rejects other calls until it is ready.
+// It should precede other options to reject calls before any work is done.
+func WithReadiness(readiness *health.Readiness) Option {
+	return func(o *serverOptions) {
+		o.interceptors = append(o.interceptors, WithReadinessCheck(readiness))
+		o.streams = append(o.streams, StreamWithReadinessCheck(readiness))
+		o.readiness = readiness
+	}
+}
+
+// WithTenancy requires callers to name the tenant their principal is bound to and isolates orders of tenants.
+// It should follow WithAuth.
+func WithTenancy() Option {
+	return func(o *serverOptions) {
+		o.interceptors = append(o.interceptors, WithTenantFromMetadata)
+		o.streams = append(o.streams, StreamWithTenantFromMetadata)
+	}
+}
+
+// WithPayloadLogger logs payloads of unary calls chosen by payloads. It should follow WithAuth to log authorized
+// calls only.
+func WithPayloadLogger(payloads *applog.Payloads) Option {
+	return func(o *serverOptions) {
+		o.interceptors = append(o.interceptors, WithPayloadLog(payloads))
+	}
+}
+
+// WithWebhooks serves WebhookAdminService managing subscriptions in store and notifies webhooks of confirmed transactions.
+func WithWebhooks(store WebhookStore, webhooks WebhookNotifier) Option {
+	return func(o *serverOptions) {
+		o.webhookStore = store
+		o.webhooks = webhooks
+	}
+}
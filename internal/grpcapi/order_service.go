@@ -2,37 +2,72 @@ package grpcapi
 
 import (
 	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Sugar-pack/users-manager/pkg/logging"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/Sugar-pack/orders-manager/internal/auth"
 	"github.com/Sugar-pack/orders-manager/internal/repository"
 	"github.com/Sugar-pack/orders-manager/internal/tracing"
 	"github.com/Sugar-pack/orders-manager/pkg/pb"
 )
 
+// MaxBatchGetOrders limits ids of a BatchGetOrders call.
+const MaxBatchGetOrders = 100
+
+// batchSizeKey is the number of ids requested from BatchGetOrders.
+const batchSizeKey = attribute.Key("orders.requested")
+
+var errTooManyIDs = fmt.Errorf("at most %d ids are allowed", MaxBatchGetOrders)
+
+const (
+	// DefaultListOrders is the page size of ListOrders without page_size.
+	DefaultListOrders = 50
+	// MaxListOrders limits page size of ListOrders.
+	MaxListOrders = 500
+)
+
+var (
+	errPageSize         = fmt.Errorf("page_size must be at most %d", MaxListOrders)
+	errInvalidPageToken = errors.New("invalid page token")
+)
+
 type OrderService struct {
 	pb.OrdersManagerServiceServer
-	Repo repository.OrderRepoWith2PC
+	Repo   repository.OrderRepoWith2PC
+	Policy *auth.Policy
 }
 
 func (s *OrderService) InsertOrder(ctx context.Context, order *pb.Order) (*pb.OrderTnxResponse, error) {
 	ctx, span := otel.Tracer(tracing.TracerName).Start(ctx, "InsertOrder")
 	defer span.End()
 	logger := logging.FromContext(ctx)
-	logger.Info("ReceiveOrder")
-	orderID := uuid.New()
-	txID := uuid.New()
 	parseUserID, err := uuid.Parse(order.UserId)
 	if err != nil {
 		logger.WithError(err).Error("Error parsing user id")
+		tracing.RecordError(span, err)
 
 		return nil, status.Error(codes.Internal, "error parsing user id") //nolint:wrapcheck // should be wrapped as is
 	}
+	orderID, txID := newIDs(s.Repo, parseUserID)
+	span.SetAttributes(
+		tracing.UserIDKey.String(parseUserID.String()),
+		tracing.OrderIDKey.String(orderID.String()),
+		tracing.TxIDKey.String(txID.String()),
+	)
+	ctx, logger = withOrderLogger(ctx, orderID.String(), txID.String())
+	logger.Info("ReceiveOrder")
 
 	dbOrder := &repository.Order{
 		ID:        orderID,
@@ -44,6 +79,7 @@ func (s *OrderService) InsertOrder(ctx context.Context, order *pb.Order) (*pb.Or
 	err = s.Repo.PrepareInsertOrder(ctx, dbOrder, txID)
 	if err != nil {
 		logger.WithError(err).Error("Error preparing insert order")
+		tracing.RecordError(span, err)
 
 		return nil, status.Error(codes.Internal, "error preparing insert order") //nolint:wrapcheck // should be wrapped as is
 	}
@@ -57,21 +93,40 @@ func (s *OrderService) InsertOrder(ctx context.Context, order *pb.Order) (*pb.Or
 func (s *OrderService) GetOrder(ctx context.Context, request *pb.GetOrderRequest) (*pb.OrderResponse, error) {
 	ctx, span := otel.Tracer(tracing.TracerName).Start(ctx, "GetOrder")
 	defer span.End()
-	logger := logging.FromContext(ctx)
-	logger.Info("GetOrder")
 	orderID := request.GetId()
+	ctx, logger := withOrderLogger(ctx, orderID, "")
+	logger.Info("GetOrder")
+	span.SetAttributes(tracing.OrderIDKey.String(orderID))
 	parseOrderID, err := uuid.Parse(orderID)
 	if err != nil {
 		logger.WithError(err).Error("Error parsing order id")
+		tracing.RecordError(span, err)
 
 		return nil, status.Error(codes.Internal, "error parsing order id") //nolint:wrapcheck // should be wrapped as is
 	}
 	order, err := s.Repo.GetOrder(ctx, parseOrderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		tracing.RecordError(span, err)
+
+		return nil, status.Error(codes.NotFound, "order not found") //nolint:wrapcheck // should be wrapped as is
+	}
 	if err != nil {
 		logger.WithError(err).Error("GetOrder error")
+		tracing.RecordError(span, err)
 
 		return nil, status.Error(codes.Internal, "Cant get order by id") //nolint:wrapcheck // should be wrapped as is
 	}
+	span.SetAttributes(tracing.UserIDKey.String(order.UserID.String()))
+	if err = s.Policy.AuthorizeOrderRead(ctx, order.UserID); err != nil {
+		logger.WithError(err).Warn("GetOrder access denied")
+		tracing.RecordError(span, err)
+		if errors.Is(err, auth.ErrForbidden) {
+			// orders of others look missing, callers cannot tell whether they exist
+			return nil, status.Error(codes.NotFound, "order not found") //nolint:wrapcheck // should be wrapped as is
+		}
+
+		return nil, authzError(err)
+	}
 
 	return &pb.OrderResponse{
 		Id:        orderID,
@@ -80,3 +135,188 @@ func (s *OrderService) GetOrder(ctx context.Context, request *pb.GetOrderRequest
 		CreatedAt: timestamppb.New(order.CreatedAt),
 	}, nil
 }
+
+// BatchGetOrders returns orders in the order of request ids, ids without an order are listed as missing.
+// Repeated ids are returned once. Orders the caller may not read are listed as missing too.
+func (s *OrderService) BatchGetOrders(ctx context.Context, request *pb.BatchGetOrdersRequest,
+) (*pb.BatchGetOrdersResponse, error) {
+	ctx, span := otel.Tracer(tracing.TracerName).Start(ctx, "BatchGetOrders")
+	defer span.End()
+	logger := logging.FromContext(ctx)
+	logger.WithField("ids", len(request.GetIds())).Info("BatchGetOrders")
+	span.SetAttributes(batchSizeKey.Int(len(request.GetIds())))
+	if len(request.GetIds()) > MaxBatchGetOrders {
+		tracing.RecordError(span, errTooManyIDs)
+
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d ids are allowed", MaxBatchGetOrders) //nolint:wrapcheck // should be wrapped as is
+	}
+	ids := make([]uuid.UUID, 0, len(request.GetIds()))
+	requested := make(map[uuid.UUID]bool, len(request.GetIds()))
+	for _, rawID := range request.GetIds() {
+		id, err := uuid.Parse(rawID)
+		if err != nil {
+			logger.WithError(err).Error("Error parsing order id")
+			tracing.RecordError(span, err)
+
+			return nil, status.Error(codes.InvalidArgument, "error parsing order id") //nolint:wrapcheck // should be wrapped as is
+		}
+		if !requested[id] {
+			requested[id] = true
+			ids = append(ids, id)
+		}
+	}
+	response := &pb.BatchGetOrdersResponse{}
+	if len(ids) == 0 {
+		return response, nil
+	}
+
+	orders, err := s.Repo.GetOrders(ctx, ids)
+	if err != nil {
+		logger.WithError(err).Error("GetOrders error")
+		tracing.RecordError(span, err)
+
+		return nil, status.Error(codes.Internal, "Cant get orders by ids") //nolint:wrapcheck // should be wrapped as is
+	}
+	found := make(map[uuid.UUID]bool, len(orders))
+	for _, order := range orders {
+		if err = s.Policy.AuthorizeOrderRead(ctx, order.UserID); err != nil {
+			logger.WithField("order_id", order.ID.String()).WithError(err).Warn("BatchGetOrders access denied")
+			span.SetAttributes(tracing.OrderIDKey.String(order.ID.String()), tracing.UserIDKey.String(order.UserID.String()))
+			tracing.RecordError(span, err)
+			if errors.Is(err, auth.ErrForbidden) {
+				continue
+			}
+
+			return nil, authzError(err)
+		}
+		found[order.ID] = true
+		response.Orders = append(response.Orders, &pb.OrderResponse{
+			Id:        order.ID.String(),
+			UserId:    order.UserID.String(),
+			Label:     order.Label,
+			CreatedAt: timestamppb.New(order.CreatedAt),
+		})
+	}
+	for _, id := range ids {
+		if !found[id] {
+			response.MissingIds = append(response.MissingIds, id.String())
+		}
+	}
+
+	return response, nil
+}
+
+// ListOrders returns a page of orders of the user, the next page follows the last order of this one.
+func (s *OrderService) ListOrders(ctx context.Context, request *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
+	ctx, span := otel.Tracer(tracing.TracerName).Start(ctx, "ListOrders")
+	defer span.End()
+	logger := logging.FromContext(ctx).WithField("user_id", request.GetUserId())
+	logger.Info("ListOrders")
+	span.SetAttributes(tracing.UserIDKey.String(request.GetUserId()))
+	userID, err := uuid.Parse(request.GetUserId())
+	if err != nil {
+		logger.WithError(err).Error("Error parsing user id")
+		tracing.RecordError(span, err)
+
+		return nil, status.Error(codes.InvalidArgument, "error parsing user id") //nolint:wrapcheck // should be wrapped as is
+	}
+	if err = s.Policy.AuthorizeOrderRead(ctx, userID); err != nil {
+		logger.WithError(err).Warn("ListOrders access denied")
+		tracing.RecordError(span, err)
+
+		return nil, authzError(err)
+	}
+	pageSize := int(request.GetPageSize())
+	switch {
+	case pageSize < 0 || pageSize > MaxListOrders:
+		tracing.RecordError(span, errPageSize)
+
+		return nil, status.Error(codes.InvalidArgument, errPageSize.Error()) //nolint:wrapcheck // should be wrapped as is
+	case pageSize == 0:
+		pageSize = DefaultListOrders
+	}
+	after, err := parsePageToken(request.GetPageToken())
+	if err != nil {
+		logger.WithError(err).Error("Error parsing page token")
+		tracing.RecordError(span, err)
+
+		return nil, status.Error(codes.InvalidArgument, errInvalidPageToken.Error()) //nolint:wrapcheck // should be wrapped as is
+	}
+
+	// one more order tells whether there is a next page
+	orders, err := s.Repo.ListOrders(ctx, userID, after, pageSize+1)
+	if err != nil {
+		logger.WithError(err).Error("ListOrders error")
+		tracing.RecordError(span, err)
+
+		return nil, status.Error(codes.Internal, "Cant list orders") //nolint:wrapcheck // should be wrapped as is
+	}
+	response := &pb.ListOrdersResponse{}
+	if len(orders) > pageSize {
+		orders = orders[:pageSize]
+		response.NextPageToken = pageToken(repository.CursorOf(orders[pageSize-1]))
+	}
+	for _, order := range orders {
+		response.Orders = append(response.Orders, &pb.OrderResponse{
+			Id:        order.ID.String(),
+			UserId:    order.UserID.String(),
+			Label:     order.Label,
+			CreatedAt: timestamppb.New(order.CreatedAt),
+		})
+	}
+
+	return response, nil
+}
+
+// pageToken is cursor as an opaque string.
+func pageToken(cursor repository.OrderCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + " " + cursor.ID.String()))
+}
+
+// parsePageToken is the reverse of pageToken, empty token is the zero cursor.
+func parsePageToken(token string) (repository.OrderCursor, error) {
+	if token == "" {
+		return repository.OrderCursor{}, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return repository.OrderCursor{}, errInvalidPageToken
+	}
+	rawCreatedAt, rawID, ok := strings.Cut(string(decoded), " ")
+	if !ok {
+		return repository.OrderCursor{}, errInvalidPageToken
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, rawCreatedAt)
+	if err != nil {
+		return repository.OrderCursor{}, errInvalidPageToken
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return repository.OrderCursor{}, errInvalidPageToken
+	}
+
+	return repository.OrderCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// withOrderLogger adds order and transaction IDs known to the handler to its log lines
+// and to log lines of the calls it makes. Empty IDs are not known.
+func withOrderLogger(ctx context.Context, orderID, txID string) (context.Context, logging.Logger) {
+	logger := logging.FromContext(ctx)
+	if orderID != "" {
+		logger = logger.WithField("order_id", orderID)
+	}
+	if txID != "" {
+		logger = logger.WithField("tx_id", txID)
+	}
+
+	return logging.WithContext(ctx, logger), logger
+}
+
+// newIDs lets repo choose IDs carrying placement of the order, see repository.IDAssigner.
+func newIDs(repo repository.OrderRepoWith2PC, userID uuid.UUID) (orderID, txID uuid.UUID) {
+	if assigner, ok := repo.(repository.IDAssigner); ok {
+		return assigner.NewIDs(userID)
+	}
+
+	return uuid.New(), uuid.New()
+}
@@ -11,11 +11,19 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	testify "github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/Sugar-pack/orders-manager/internal/db"
 	"github.com/Sugar-pack/orders-manager/internal/mock"
 	"github.com/Sugar-pack/orders-manager/internal/repository"
+	"github.com/Sugar-pack/orders-manager/internal/tracing"
 	"github.com/Sugar-pack/orders-manager/pkg/pb"
 )
 
@@ -170,6 +178,125 @@ func TestOrderService_InsertOrder_PrepareError(t *testing.T) {
 	assert.Nil(t, insertOrder)
 }
 
+// assigningRepo is a repository choosing IDs, like repository.ShardedRepository.
+type assigningRepo struct {
+	*mock.OrderRepoWith2PC
+	orderID, txID uuid.UUID
+}
+
+func (r *assigningRepo) NewIDs(uuid.UUID) (orderID, txID uuid.UUID) {
+	return r.orderID, r.txID
+}
+
+func TestOrderService_InsertOrder_AssignedIDs(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	repo := &assigningRepo{OrderRepoWith2PC: &mock.OrderRepoWith2PC{}, orderID: uuid.New(), txID: uuid.New()}
+	orderService := OrderService{Repo: repo}
+
+	repo.On("PrepareInsertOrder", testify.Anything,
+		testify.MatchedBy(func(order *repository.Order) bool { return order.ID == repo.orderID }),
+		repo.txID).Return(nil)
+
+	response, err := orderService.InsertOrder(ctx, &pb.Order{UserId: uuid.NewString(), CreatedAt: timestamppb.Now()})
+	assert.NoError(t, err)
+	assert.Equal(t, repo.orderID.String(), response.Id)
+	assert.Equal(t, repo.txID.String(), response.Tnx)
+	repo.AssertExpectations(t)
+}
+
+// recordSpans makes spans of the test recorded, like tracing.InitTracing does with an exporter.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+// spanAttributes returns attributes of the only span ended with name.
+func spanAttributes(t *testing.T, recorder *tracetest.SpanRecorder, name string) (sdktrace.ReadOnlySpan, map[attribute.Key]string) {
+	t.Helper()
+	for _, span := range recorder.Ended() {
+		if span.Name() != name {
+			continue
+		}
+		attributes := make(map[attribute.Key]string)
+		for _, kv := range span.Attributes() {
+			attributes[kv.Key] = kv.Value.Emit()
+		}
+
+		return span, attributes
+	}
+	t.Fatalf("span %s was not ended", name)
+
+	return nil, nil
+}
+
+func TestOrderService_InsertOrder_Span(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	repo := &assigningRepo{OrderRepoWith2PC: &mock.OrderRepoWith2PC{}, orderID: uuid.New(), txID: uuid.New()}
+	orderService := OrderService{Repo: repo}
+	userID := uuid.New()
+	repo.On("PrepareInsertOrder", testify.Anything, testify.Anything, repo.txID).Return(errors.New("prepare error"))
+
+	_, err := orderService.InsertOrder(ctx, &pb.Order{UserId: userID.String(), CreatedAt: timestamppb.Now()})
+	assert.Error(t, err)
+
+	span, attributes := spanAttributes(t, recorder, "InsertOrder")
+	assert.Equal(t, map[attribute.Key]string{
+		tracing.OrderIDKey: repo.orderID.String(),
+		tracing.UserIDKey:  userID.String(),
+		tracing.TxIDKey:    repo.txID.String(),
+	}, attributes)
+	assert.Equal(t, otelcodes.Error, span.Status().Code)
+	assert.Equal(t, "prepare error", span.Status().Description)
+	if assert.Len(t, span.Events(), 1) {
+		assert.Equal(t, "exception", span.Events()[0].Name)
+	}
+}
+
+func TestOrderService_InsertOrder_LogFields(t *testing.T) {
+	ctx, logs := jsonLogs(t)
+	repo := &assigningRepo{OrderRepoWith2PC: &mock.OrderRepoWith2PC{}, orderID: uuid.New(), txID: uuid.New()}
+	orderService := OrderService{Repo: repo}
+	repo.On("PrepareInsertOrder", testify.MatchedBy(func(ctx context.Context) bool {
+		logging.FromContext(ctx).Info("logged by repository")
+
+		return true
+	}), testify.Anything, repo.txID).Return(errors.New("prepare error"))
+
+	_, err := orderService.InsertOrder(ctx, &pb.Order{UserId: uuid.NewString(), CreatedAt: timestamppb.Now()})
+	assert.Error(t, err)
+
+	lines := logs()
+	assert.NotEmpty(t, lines)
+	for _, line := range lines {
+		assert.Equal(t, repo.orderID.String(), line["order_id"], line["msg"])
+		assert.Equal(t, repo.txID.String(), line["tx_id"], line["msg"])
+	}
+}
+
+func TestOrderService_GetOrder_Span(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	mockRepo := &mock.OrderRepoWith2PC{}
+	orderService := OrderService{Repo: mockRepo}
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), CreatedAt: time.Now()}
+	mockRepo.On("GetOrder", testify.Anything, order.ID).Return(order, nil)
+
+	_, err := orderService.GetOrder(ctx, &pb.GetOrderRequest{Id: order.ID.String()})
+	assert.NoError(t, err)
+
+	span, attributes := spanAttributes(t, recorder, "GetOrder")
+	assert.Equal(t, order.ID.String(), attributes[tracing.OrderIDKey])
+	assert.Equal(t, order.UserID.String(), attributes[tracing.UserIDKey])
+	assert.Equal(t, otelcodes.Unset, span.Status().Code)
+	assert.Empty(t, span.Events())
+}
+
 func TestOrderService_InsertOrder_OK(t *testing.T) {
 	ctx := context.Background()
 	logger := logging.GetLogger()
@@ -275,3 +402,115 @@ func TestOrderService_GetOrder_OK(t *testing.T) {
 	assert.Equal(t, orderDB.Label, orderResponse.Label)
 	assert.Equal(t, orderDB.CreatedAt.Format(time.RFC3339), orderResponse.CreatedAt.AsTime().Format(time.RFC3339))
 }
+
+func TestOrderService_BatchGetOrders(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	mockRepo := &mock.OrderRepoWith2PC{}
+	orderService := OrderService{Repo: mockRepo}
+
+	first := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "first", CreatedAt: time.Now().UTC()}
+	second := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "second", CreatedAt: time.Now().UTC()}
+	missing := uuid.New()
+	mockRepo.On("GetOrders", testify.Anything, []uuid.UUID{second.ID, missing, first.ID}).
+		Return([]*repository.Order{second, first}, nil).Once()
+
+	response, err := orderService.BatchGetOrders(ctx, &pb.BatchGetOrdersRequest{
+		Ids: []string{second.ID.String(), missing.String(), first.ID.String(), second.ID.String()},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, response.Orders, 2) {
+		assert.Equal(t, second.ID.String(), response.Orders[0].Id)
+		assert.Equal(t, "second", response.Orders[0].Label)
+		assert.Equal(t, first.ID.String(), response.Orders[1].Id)
+	}
+	assert.Equal(t, []string{missing.String()}, response.MissingIds)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestOrderService_BatchGetOrders_Errors(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	mockRepo := &mock.OrderRepoWith2PC{}
+	orderService := OrderService{Repo: mockRepo}
+
+	tooMany := make([]string, MaxBatchGetOrders+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.New().String()
+	}
+	_, err := orderService.BatchGetOrders(ctx, &pb.BatchGetOrdersRequest{Ids: tooMany})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = orderService.BatchGetOrders(ctx, &pb.BatchGetOrdersRequest{Ids: []string{"definitely not a uuid"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	response, err := orderService.BatchGetOrders(ctx, &pb.BatchGetOrdersRequest{})
+	assert.NoError(t, err)
+	assert.Empty(t, response.Orders)
+
+	id := uuid.New()
+	mockRepo.On("GetOrders", testify.Anything, []uuid.UUID{id}).Return(nil, errors.New("get error")).Once()
+	_, err = orderService.BatchGetOrders(ctx, &pb.BatchGetOrdersRequest{Ids: []string{id.String()}})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestOrderService_ListOrders(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	mockRepo := &mock.OrderRepoWith2PC{}
+	orderService := OrderService{Repo: mockRepo}
+	userID := uuid.New()
+	now := time.Now().UTC()
+	orders := []*repository.Order{
+		{ID: uuid.New(), UserID: userID, Label: "first", CreatedAt: now},
+		{ID: uuid.New(), UserID: userID, Label: "second", CreatedAt: now.Add(time.Second)},
+		{ID: uuid.New(), UserID: userID, Label: "third", CreatedAt: now.Add(2 * time.Second)},
+	}
+	mockRepo.On("ListOrders", testify.Anything, userID, repository.OrderCursor{}, 3).Return(orders, nil).Once()
+	mockRepo.On("ListOrders", testify.Anything, userID, repository.CursorOf(orders[1]), 3).Return(orders[2:], nil).Once()
+
+	page, err := orderService.ListOrders(ctx, &pb.ListOrdersRequest{UserId: userID.String(), PageSize: 2})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Orders, 2) {
+		assert.Equal(t, "first", page.Orders[0].Label)
+		assert.Equal(t, "second", page.Orders[1].Label)
+	}
+	assert.NotEmpty(t, page.NextPageToken)
+
+	page, err = orderService.ListOrders(ctx, &pb.ListOrdersRequest{UserId: userID.String(), PageSize: 2, PageToken: page.NextPageToken})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Orders, 1) {
+		assert.Equal(t, "third", page.Orders[0].Label)
+	}
+	assert.Empty(t, page.NextPageToken, "the last page")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestOrderService_ListOrders_Errors(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	mockRepo := &mock.OrderRepoWith2PC{}
+	orderService := OrderService{Repo: mockRepo}
+	userID := uuid.New()
+
+	_, err := orderService.ListOrders(ctx, &pb.ListOrdersRequest{UserId: "user"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = orderService.ListOrders(ctx, &pb.ListOrdersRequest{UserId: userID.String(), PageSize: MaxListOrders + 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = orderService.ListOrders(ctx, &pb.ListOrdersRequest{UserId: userID.String(), PageToken: "bm90IGEgY3Vyc29y"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	mockRepo.On("ListOrders", testify.Anything, userID, repository.OrderCursor{}, DefaultListOrders+1).
+		Return(nil, errors.New("list error")).Once()
+	_, err = orderService.ListOrders(ctx, &pb.ListOrdersRequest{UserId: userID.String()})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPageToken(t *testing.T) {
+	cursor := repository.OrderCursor{CreatedAt: time.Date(2026, 10, 19, 12, 0, 0, 123456000, time.UTC), ID: uuid.New()}
+	parsed, err := parsePageToken(pageToken(cursor))
+	assert.NoError(t, err)
+	assert.Equal(t, cursor, parsed)
+
+	parsed, err = parsePageToken("")
+	assert.NoError(t, err)
+	assert.Equal(t, repository.OrderCursor{}, parsed)
+}
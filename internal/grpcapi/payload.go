@@ -0,0 +1,32 @@
+package grpcapi
+
+import (
+	"context"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	applog "github.com/Sugar-pack/orders-manager/internal/logger"
+)
+
+// WithPayloadLog logs requests and responses of methods chosen by payloads at debug level, masking their fields.
+func WithPayloadLog(payloads *applog.Payloads) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (
+		resp interface{}, err error,
+	) {
+		if !payloads.Logged(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		logger := logging.FromContext(ctx).WithField("request", info.FullMethod)
+		if message, ok := req.(proto.Message); ok {
+			logger.WithField("payload", payloads.Mask(message)).Debug("request payload")
+		}
+		resp, err = handler(ctx, req)
+		if message, ok := resp.(proto.Message); ok && err == nil {
+			logger.WithField("payload", payloads.Mask(message)).Debug("response payload")
+		}
+
+		return resp, err
+	}
+}
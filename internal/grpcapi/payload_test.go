@@ -0,0 +1,78 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	applog "github.com/Sugar-pack/orders-manager/internal/logger"
+	"github.com/Sugar-pack/orders-manager/pkg/pb"
+)
+
+// jsonLogs returns context with a logger writing JSON lines and a function reading lines logged so far.
+func jsonLogs(t *testing.T) (context.Context, func() []map[string]any) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "orders-manager.log")
+	logger, handle, err := applog.New(&config.Log{Format: config.LogFormatJSON, Output: path})
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	t.Cleanup(func() { _ = handle.Close() })
+
+	return logging.WithContext(context.Background(), logger), func() []map[string]any {
+		data, readErr := os.ReadFile(path)
+		assert.NoError(t, readErr)
+		var lines []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var fields map[string]any
+			assert.NoError(t, json.Unmarshal([]byte(line), &fields))
+			lines = append(lines, fields)
+		}
+
+		return lines
+	}
+}
+
+func TestWithPayloadLog(t *testing.T) {
+	ctx, logs := jsonLogs(t)
+	payloads := applog.NewPayloads(&config.LogPayloads{
+		Methods:      []string{"/pb.OrdersManagerService/GetOrder"},
+		MaskedFields: []string{"user_id"},
+	})
+	interceptor := WithPayloadLog(payloads)
+	handler := func(context.Context, interface{}) (interface{}, error) {
+		return &pb.OrderResponse{Id: "1", UserId: "3f1c", Label: "label"}, nil
+	}
+
+	_, err := interceptor(ctx, &pb.GetOrderRequest{Id: "1"},
+		&grpc.UnaryServerInfo{FullMethod: "/pb.OrdersManagerService/GetOrder"}, handler)
+	assert.NoError(t, err)
+	_, err = interceptor(ctx, &pb.Order{UserId: "3f1c"},
+		&grpc.UnaryServerInfo{FullMethod: "/pb.OrdersManagerService/InsertOrder"}, handler)
+	assert.NoError(t, err)
+	_, err = interceptor(ctx, &pb.GetOrderRequest{Id: "2"},
+		&grpc.UnaryServerInfo{FullMethod: "/pb.OrdersManagerService/GetOrder"},
+		func(context.Context, interface{}) (interface{}, error) {
+			return nil, status.Error(codes.Internal, "failed")
+		})
+	assert.Error(t, err)
+
+	lines := logs()
+	if assert.Len(t, lines, 3, "payloads of other methods and failed responses are not logged") {
+		assert.Equal(t, "request payload", lines[0]["msg"])
+		assert.JSONEq(t, `{"id":"1"}`, lines[0]["payload"].(string))
+		assert.Equal(t, "response payload", lines[1]["msg"])
+		assert.JSONEq(t, `{"id":"1","user_id":"xxxxx","label":"label"}`, lines[1]["payload"].(string))
+		assert.JSONEq(t, `{"id":"2"}`, lines[2]["payload"].(string))
+	}
+}
@@ -0,0 +1,105 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/Sugar-pack/orders-manager/internal/auth"
+	"github.com/Sugar-pack/orders-manager/internal/ratelimit"
+)
+
+// userIDRequest is implemented by requests carrying user_id.
+type userIDRequest interface {
+	GetUserId() string
+}
+
+// WithRateLimit rejects calls exceeding limits with ResourceExhausted status carrying RetryInfo.
+func WithRateLimit(limiter *ratelimit.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (
+		resp interface{}, err error,
+	) {
+		if isHealthMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		key := ratelimit.Key{
+			Method: info.FullMethod,
+			Client: clientKey(ctx),
+		}
+		if userReq, ok := req.(userIDRequest); ok {
+			key.User = userReq.GetUserId()
+		}
+
+		decision := limiter.Allow(key)
+		if !decision.Allowed {
+			logging.FromContext(ctx).
+				WithField("limit", decision.Dimension).
+				WithField("retry_after", decision.RetryAfter.String()).
+				Warn("rate limit exceeded")
+
+			return nil, rateLimitError(decision)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// clientKey identifies caller by principal, verified client certificate or address, in that order.
+// Calls of the REST gateway are keyed by the REST caller.
+func clientKey(ctx context.Context) string {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		return "principal:" + principal.Subject
+	}
+	if identity, ok := ClientIdentityFromContext(ctx); ok {
+		return "cert:" + identity.CommonName
+	}
+	if addr := callerAddr(ctx); addr != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+
+		return "addr:" + host
+	}
+
+	return ""
+}
+
+// callerAddr returns address of the peer, for calls of the gateway the one of the REST caller.
+func callerAddr(ctx context.Context) string {
+	if fromGateway(ctx) {
+		if values := metadata.ValueFromIncomingContext(ctx, forwardedAddrHeader); len(values) == 1 {
+			return values[0]
+		}
+
+		return ""
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+
+	return ""
+}
+
+func rateLimitError(decision ratelimit.Decision) error {
+	st := status.New(codes.ResourceExhausted, "rate limit exceeded: "+string(decision.Dimension))
+	if decision.RetryAfter == rate.InfDuration {
+		// the call exceeds burst and will never pass, retrying makes no sense
+		return st.Err() //nolint:wrapcheck // should be wrapped as is
+	}
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(decision.RetryAfter)})
+	if err != nil {
+		return st.Err() //nolint:wrapcheck // should be wrapped as is
+	}
+
+	return detailed.Err() //nolint:wrapcheck // should be wrapped as is
+}
@@ -0,0 +1,95 @@
+package grpcapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	"github.com/Sugar-pack/orders-manager/internal/mock"
+	"github.com/Sugar-pack/orders-manager/internal/ratelimit"
+	"github.com/Sugar-pack/orders-manager/pkg/pb"
+)
+
+func TestRateLimit_PerUser(t *testing.T) {
+	repo := &mock.OrderRepoWith2PC{}
+	repo.On("PrepareInsertOrder", testify.Anything, testify.Anything, testify.Anything).Return(nil)
+	limiter := ratelimit.NewLimiter(&config.RateLimit{
+		Enabled: true,
+		PerUser: &config.RateLimitRule{RPS: 0.1, Burst: 1},
+	})
+	client := pb.NewOrdersManagerServiceClient(startServer(t, repo, WithRateLimiter(limiter)))
+	userID := uuid.NewString()
+	order := &pb.Order{UserId: userID, Label: "label", CreatedAt: timestamppb.Now()}
+
+	_, err := client.InsertOrder(context.Background(), order)
+	assert.NoError(t, err)
+
+	_, err = client.InsertOrder(context.Background(), order)
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	var retryInfo *errdetails.RetryInfo
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			retryInfo = info
+		}
+	}
+	if assert.NotNil(t, retryInfo, "RetryInfo detail expected") {
+		assert.InDelta(t, 10*time.Second, retryInfo.RetryDelay.AsDuration(), float64(time.Second))
+	}
+
+	order.UserId = uuid.NewString()
+	_, err = client.InsertOrder(context.Background(), order)
+	assert.NoError(t, err, "other users are not affected")
+}
+
+func TestRateLimit_PerClient(t *testing.T) {
+	repo := &mock.OrderRepoWith2PC{}
+	orderID := uuid.New()
+	repo.On("GetOrder", testify.Anything, orderID).Return(nil, assert.AnError)
+	limiter := ratelimit.NewLimiter(&config.RateLimit{
+		Enabled:   true,
+		PerClient: &config.RateLimitRule{RPS: 0.1, Burst: 1},
+	})
+	client := pb.NewOrdersManagerServiceClient(startServer(t, repo, WithRateLimiter(limiter)))
+	request := &pb.GetOrderRequest{Id: orderID.String()}
+
+	_, err := client.GetOrder(context.Background(), request)
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	_, err = client.GetOrder(context.Background(), request)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestRateLimit_PerClientViaGateway(t *testing.T) {
+	repo := &mock.OrderRepoWith2PC{}
+	orderID := uuid.New()
+	repo.On("GetOrder", testify.Anything, orderID).Return(nil, assert.AnError)
+	limiter := ratelimit.NewLimiter(&config.RateLimit{
+		Enabled:   true,
+		PerClient: &config.RateLimitRule{RPS: 0.1, Burst: 1},
+	})
+	handler := gatewayHandler(t, repo, WithRateLimiter(limiter))
+	get := func(remoteAddr string) int {
+		request := httptest.NewRequest(http.MethodGet, "/v1/orders/"+orderID.String(), nil)
+		request.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, request)
+
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusInternalServerError, get("192.0.2.1:1234"))
+	assert.Equal(t, http.StatusTooManyRequests, get("192.0.2.1:4321"), "REST caller is limited by its address")
+	assert.Equal(t, http.StatusInternalServerError, get("192.0.2.2:1234"), "other REST callers are not affected")
+}
@@ -0,0 +1,31 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/Sugar-pack/orders-manager/internal/health"
+)
+
+// isHealthMethod reports whether method belongs to grpc health service, which is open to everyone.
+func isHealthMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+}
+
+// WithReadinessCheck rejects calls with Unavailable status until the service is ready.
+func WithReadinessCheck(readiness *health.Readiness) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (
+		resp interface{}, err error,
+	) {
+		if !readiness.Ready() && !isHealthMethod(info.FullMethod) {
+			return nil, status.Error(codes.Unavailable, "service is starting") //nolint:wrapcheck // should be wrapped as is
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,44 @@
+package grpcapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/Sugar-pack/orders-manager/internal/health"
+	"github.com/Sugar-pack/orders-manager/internal/mock"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+	"github.com/Sugar-pack/orders-manager/pkg/pb"
+)
+
+func TestReadiness(t *testing.T) {
+	repo := &mock.OrderRepoWith2PC{}
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+	repo.On("GetOrder", testify.Anything, order.ID).Return(order, nil)
+	readiness := health.NewReadiness()
+	// health checks pass auth, it is enabled to make sure of that
+	conn := startServer(t, repo, WithReadiness(readiness), withAuthOption(t))
+	healthClient := healthpb.NewHealthClient(conn)
+	ordersClient := pb.NewOrdersManagerServiceClient(conn)
+	ctx := withBearer(t, order.UserID.String())
+
+	resp, err := healthClient.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.GetStatus())
+	_, err = ordersClient.GetOrder(ctx, &pb.GetOrderRequest{Id: order.ID.String()})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	readiness.SetReady()
+	resp, err = healthClient.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+	_, err = ordersClient.GetOrder(ctx, &pb.GetOrderRequest{Id: order.ID.String()})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,69 @@
+package grpcapi
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Sugar-pack/orders-manager/internal/tracing"
+)
+
+// newPanicsCounter creates the counter of recovered panics, a server creates it once with meter provider of its options.
+func newPanicsCounter(provider metric.MeterProvider) (metric.Int64Counter, error) {
+	return provider.Meter(tracing.TracerName).Int64Counter("grpc.server.panics", //nolint:wrapcheck // too simple to wrap
+		metric.WithDescription("Number of panics recovered in grpc handlers"),
+	)
+}
+
+// recoverPanic turns recovered value into Internal status, logs the stack and counts the panic.
+func recoverPanic(ctx context.Context, panics metric.Int64Counter, method string, recovered interface{}) error {
+	logger := logging.FromContext(ctx).
+		WithField("request", method).
+		WithField("panic", recovered).
+		WithField("stack", string(debug.Stack()))
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.HasTraceID() {
+		logger = logger.WithField("trace_id", spanCtx.TraceID().String())
+	}
+	logger.Error("panic recovered")
+
+	panics.Add(ctx, 1, metric.WithAttributes(attribute.String("rpc.method", method)))
+
+	return status.Error(codes.Internal, "internal error") //nolint:wrapcheck // should be wrapped as is
+}
+
+// WithRecovery converts panics in handlers and following interceptors into Internal status counted by panics.
+func WithRecovery(panics metric.Int64Counter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (
+		resp interface{}, err error,
+	) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				resp, err = nil, recoverPanic(ctx, panics, info.FullMethod, recovered)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamWithRecovery is a stream equivalent of WithRecovery.
+func StreamWithRecovery(panics metric.Int64Counter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) (err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = recoverPanic(stream.Context(), panics, info.FullMethod, recovered)
+			}
+		}()
+
+		return handler(srv, stream)
+	}
+}
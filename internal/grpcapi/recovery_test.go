@@ -0,0 +1,107 @@
+package grpcapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Sugar-pack/orders-manager/internal/mock"
+	"github.com/Sugar-pack/orders-manager/pkg/pb"
+)
+
+func panicsRecorded(t *testing.T, reader *sdkmetric.ManualReader) int64 {
+	t.Helper()
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	var total int64
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == "grpc.server.panics" {
+				for _, point := range sum.DataPoints {
+					total += point.Value
+				}
+			}
+		}
+	}
+
+	return total
+}
+
+func TestRecovery_Unary(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	repo := &mock.OrderRepoWith2PC{}
+	orderID := uuid.New()
+	repo.On("GetOrder", testify.Anything, orderID).Run(func(testify.Arguments) {
+		var order *pb.Order
+		_ = order.UserId // nil dereference
+	}).Return(nil, nil)
+	client := pb.NewOrdersManagerServiceClient(startServer(t, repo, WithMeterProvider(provider)))
+
+	_, err := client.GetOrder(context.Background(), &pb.GetOrderRequest{Id: orderID.String()})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, int64(1), panicsRecorded(t, reader))
+
+	// server survives the panic
+	_, err = client.GetOrder(context.Background(), &pb.GetOrderRequest{Id: "not a uuid"})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, int64(1), panicsRecorded(t, reader))
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context //nolint:containedctx // test stream
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestRecovery_Stream(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	panics, err := newPanicsCounter(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	assert.NoError(t, err)
+	stream := &testServerStream{ctx: context.Background()}
+	err = StreamWithRecovery(panics)(nil, stream, &grpc.StreamServerInfo{FullMethod: "/pb.Test/Stream"},
+		func(srv interface{}, stream grpc.ServerStream) error {
+			panic("boom")
+		})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, int64(1), panicsRecorded(t, reader))
+}
+
+func TestStreamInterceptors_Context(t *testing.T) {
+	logger := logging.GetLogger()
+	stream := &testServerStream{ctx: context.Background()}
+	info := &grpc.StreamServerInfo{FullMethod: "/pb.Test/Stream"}
+
+	var gotLogger logging.Logger
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		gotLogger = logging.FromContext(stream.Context())
+
+		return nil
+	}
+	chained := func(srv interface{}, stream grpc.ServerStream) error {
+		return StreamWithUniqTraceID(srv, stream, info, func(srv interface{}, stream grpc.ServerStream) error {
+			return StreamLogBoundaries(srv, stream, info, func(srv interface{}, stream grpc.ServerStream) error {
+				return StreamWithClientIdentity(srv, stream, info, handler)
+			})
+		})
+	}
+	err := StreamWithLogger(logger)(nil, stream, info, chained)
+	assert.NoError(t, err)
+	assert.NotNil(t, gotLogger)
+	assert.NotSame(t, logger, gotLogger, "logger with x_request_id expected")
+}
@@ -0,0 +1,111 @@
+package grpcapi
+
+import (
+	"context"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+
+	"github.com/Sugar-pack/orders-manager/internal/auth"
+	"github.com/Sugar-pack/orders-manager/internal/health"
+	"github.com/Sugar-pack/orders-manager/internal/ratelimit"
+)
+
+// Stream equivalents of unary interceptors from users-manager logging package and this package.
+
+// wrappedStream overrides context of grpc.ServerStream.
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context //nolint:containedctx // stream context is replaced by interceptors
+}
+
+func (w *wrappedStream) Context() context.Context {
+	return w.ctx
+}
+
+func withStreamContext(stream grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	return &wrappedStream{ServerStream: stream, ctx: ctx}
+}
+
+// StreamWithLogger puts logger to the stream context.
+func StreamWithLogger(logger logging.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, withStreamContext(stream, logging.WithContext(stream.Context(), logger)))
+	}
+}
+
+// StreamWithUniqTraceID adds unique x_request_id to the logger of the stream.
+func StreamWithUniqTraceID(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx := stream.Context()
+	uniqLogger := logging.FromContext(ctx).WithField("x_request_id", uuid.New().String())
+
+	return handler(srv, withStreamContext(stream, logging.WithContext(ctx, uniqLogger)))
+}
+
+// StreamLogBoundaries logs start and finish of the stream.
+func StreamLogBoundaries(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	logger := logging.FromContext(stream.Context())
+	logger.WithField("request", info.FullMethod).Trace("stream started")
+	err := handler(srv, stream)
+	logger.WithField("request", info.FullMethod).Trace("stream finished")
+
+	return err
+}
+
+// StreamWithClientIdentity is a stream equivalent of WithClientIdentity.
+func StreamWithClientIdentity(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx := stream.Context()
+	identity, ok := callerIdentity(ctx)
+	if !ok {
+		return handler(srv, stream)
+	}
+	ctx = logging.WithContext(ctx, logging.FromContext(ctx).WithField("client_cn", identity.CommonName))
+	ctx = context.WithValue(ctx, clientIdentityCtx{}, identity)
+
+	return handler(srv, withStreamContext(stream, ctx))
+}
+
+// streamFromUnary runs interceptor once when the stream starts, with nil request, and passes the context
+// it produces on as the stream context. It suits interceptors deciding by method and metadata only.
+func streamFromUnary(interceptor grpc.UnaryServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		unaryInfo := &grpc.UnaryServerInfo{Server: srv, FullMethod: info.FullMethod}
+		_, err := interceptor(stream.Context(), nil, unaryInfo, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			return nil, handler(srv, withStreamContext(stream, ctx))
+		})
+
+		return err //nolint:wrapcheck // interceptors return grpc statuses
+	}
+}
+
+// StreamWithAuthentication is a stream equivalent of WithAuthentication.
+func StreamWithAuthentication(authenticator *auth.Authenticator) grpc.StreamServerInterceptor {
+	return streamFromUnary(WithAuthentication(authenticator))
+}
+
+// StreamWithRateLimit is a stream equivalent of WithRateLimit. Streams are limited by principal or peer,
+// user of the request is unknown when they start.
+func StreamWithRateLimit(limiter *ratelimit.Limiter) grpc.StreamServerInterceptor {
+	return streamFromUnary(WithRateLimit(limiter))
+}
+
+// StreamWithReadinessCheck is a stream equivalent of WithReadinessCheck.
+func StreamWithReadinessCheck(readiness *health.Readiness) grpc.StreamServerInterceptor {
+	return streamFromUnary(WithReadinessCheck(readiness))
+}
+
+// StreamWithTenantFromMetadata is a stream equivalent of WithTenantFromMetadata.
+var StreamWithTenantFromMetadata = streamFromUnary(WithTenantFromMetadata)
+
+// StreamWithReadYourWrites is a stream equivalent of WithReadYourWrites.
+var StreamWithReadYourWrites = streamFromUnary(WithReadYourWrites)
+
+// StreamWithCacheBypass is a stream equivalent of WithCacheBypass.
+var StreamWithCacheBypass = streamFromUnary(WithCacheBypass)
@@ -0,0 +1,49 @@
+package grpcapi
+
+import (
+	"context"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Sugar-pack/orders-manager/internal/auth"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+)
+
+// TenantHeader carries tenant of the call, orders of other tenants are invisible to it.
+const TenantHeader = "x-tenant-id"
+
+// WithTenantFromMetadata requires calls other than health checks to carry exactly one TenantHeader
+// naming the tenant of the authenticated principal, and limits repository calls to orders of that tenant,
+// see repository.WithTenant. It must follow WithAuthentication.
+func WithTenantFromMetadata(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (
+	resp interface{}, err error,
+) {
+	if isHealthMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	values := metadata.ValueFromIncomingContext(ctx, TenantHeader)
+	if len(values) != 1 {
+		return nil, status.Error(codes.InvalidArgument, "exactly one "+TenantHeader+" is required") //nolint:wrapcheck // should be wrapped as is
+	}
+	tenant, err := repository.ParseTenant(values[0])
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error()) //nolint:wrapcheck // should be wrapped as is
+	}
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required") //nolint:wrapcheck // should be wrapped as is
+	}
+	logger := logging.FromContext(ctx).WithField("tenant", tenant)
+	if principal.Tenant != tenant {
+		logger.WithField("principal_tenant", principal.Tenant).Warn("tenant of the call is not the tenant of the principal")
+
+		return nil, status.Error(codes.PermissionDenied, "access denied") //nolint:wrapcheck // should be wrapped as is
+	}
+	ctx = logging.WithContext(ctx, logger)
+
+	return handler(repository.WithTenant(ctx, tenant), req)
+}
@@ -0,0 +1,148 @@
+package grpcapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Sugar-pack/orders-manager/internal/mock"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+	"github.com/Sugar-pack/orders-manager/pkg/pb"
+)
+
+func expectTenantGetOrder(repo *mock.OrderRepoWith2PC, order *repository.Order, tenant string) {
+	repo.On("GetOrder", testify.MatchedBy(func(ctx context.Context) bool {
+		return repository.TenantFromContext(ctx) == tenant
+	}), order.ID).Return(order, nil).Once()
+}
+
+// withTenantBearer authenticates the call as owner of the orders bound to tenant.
+func withTenantBearer(t *testing.T, owner uuid.UUID, tenant string) context.Context {
+	t.Helper()
+	token := testToken(t, jwt.MapClaims{"sub": owner.String(), "tenant": tenant})
+
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestWithTenancy(t *testing.T) {
+	repo := &mock.OrderRepoWith2PC{}
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+	client := pb.NewOrdersManagerServiceClient(startServer(t, repo, withAuthOption(t), WithTenancy()))
+	request := &pb.GetOrderRequest{Id: order.ID.String()}
+	acme := withTenantBearer(t, order.UserID, "acme")
+
+	_, err := client.GetOrder(acme, request)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "tenant is required")
+
+	ctx := metadata.AppendToOutgoingContext(acme, TenantHeader, "a'b")
+	_, err = client.GetOrder(ctx, request)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	ctx = metadata.AppendToOutgoingContext(acme, TenantHeader, "acme", TenantHeader, "other")
+	_, err = client.GetOrder(ctx, request)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "tenant must be unambiguous")
+
+	expectTenantGetOrder(repo, order, "acme")
+	ctx = metadata.AppendToOutgoingContext(acme, TenantHeader, "acme")
+	_, err = client.GetOrder(ctx, request)
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestWithTenancy_BoundToPrincipal(t *testing.T) {
+	repo := &mock.OrderRepoWith2PC{}
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+	client := pb.NewOrdersManagerServiceClient(startServer(t, repo, withAuthOption(t), WithTenancy()))
+	request := &pb.GetOrderRequest{Id: order.ID.String()}
+
+	ctx := metadata.AppendToOutgoingContext(withTenantBearer(t, order.UserID, "acme"), TenantHeader, "other")
+	_, err := client.GetOrder(ctx, request)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "token of acme can not act for another tenant")
+
+	ctx = metadata.AppendToOutgoingContext(withBearer(t, order.UserID.String()), TenantHeader, "acme")
+	_, err = client.GetOrder(ctx, request)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "token without tenant can not act for any")
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), TenantHeader, "acme")
+	_, err = client.GetOrder(ctx, request)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	repo.AssertNotCalled(t, "GetOrder", testify.Anything, testify.Anything)
+}
+
+func TestWithoutTenancy(t *testing.T) {
+	repo := &mock.OrderRepoWith2PC{}
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+	client := pb.NewOrdersManagerServiceClient(startServer(t, repo))
+
+	expectTenantGetOrder(repo, order, "")
+	ctx := metadata.AppendToOutgoingContext(context.Background(), TenantHeader, "acme")
+	_, err := client.GetOrder(ctx, &pb.GetOrderRequest{Id: order.ID.String()})
+	assert.NoError(t, err, "header is ignored")
+	repo.AssertExpectations(t)
+}
+
+func TestGateway_Tenant(t *testing.T) {
+	repo := &mock.OrderRepoWith2PC{}
+	order := &repository.Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+	expectTenantGetOrder(repo, order, "acme")
+	handler := gatewayHandler(t, repo, withAuthOption(t), WithTenancy())
+	get := func(tenant string) int {
+		req := httptest.NewRequest(http.MethodGet, "/v1/orders/"+order.ID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+testToken(t, jwt.MapClaims{"sub": order.UserID.String(), "tenant": "acme"}))
+		req.Header.Set("X-Tenant-Id", tenant)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get("acme"))
+	assert.Equal(t, http.StatusForbidden, get("other"))
+	repo.AssertExpectations(t)
+}
+
+func TestWithTenancy_Stream(t *testing.T) {
+	options := &serverOptions{}
+	withAuthOption(t)(options)
+	WithTenancy()(options)
+	assert.Len(t, options.streams, 2)
+	info := &grpc.StreamServerInfo{FullMethod: "/pb.Test/Stream"}
+	owner := uuid.New()
+
+	serve := func(outgoing context.Context) (string, error) {
+		md, _ := metadata.FromOutgoingContext(outgoing)
+		var tenant string
+		handler := func(srv interface{}, stream grpc.ServerStream) error {
+			tenant = repository.TenantFromContext(stream.Context())
+
+			return nil
+		}
+		stream := &testServerStream{ctx: metadata.NewIncomingContext(context.Background(), md)}
+		err := options.streams[0](nil, stream, info, func(srv interface{}, stream grpc.ServerStream) error {
+			return options.streams[1](srv, stream, info, handler)
+		})
+
+		return tenant, err
+	}
+
+	_, err := serve(metadata.AppendToOutgoingContext(context.Background(), TenantHeader, "acme"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = serve(metadata.AppendToOutgoingContext(withTenantBearer(t, owner, "acme"), TenantHeader, "other"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	tenant, err := serve(metadata.AppendToOutgoingContext(withTenantBearer(t, owner, "acme"), TenantHeader, "acme"))
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", tenant)
+}
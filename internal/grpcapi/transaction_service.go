@@ -2,23 +2,48 @@ package grpcapi
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"github.com/Sugar-pack/users-manager/pkg/logging"
 	"github.com/google/uuid"
 
+	"github.com/Sugar-pack/orders-manager/internal/auth"
 	"github.com/Sugar-pack/orders-manager/internal/repository"
 
-	"github.com/Sugar-pack/users-manager/pkg/logging"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/Sugar-pack/orders-manager/internal/tracing"
+	"github.com/Sugar-pack/orders-manager/internal/webhook"
 	"github.com/Sugar-pack/orders-manager/pkg/pb"
 )
 
+// commitKey tells whether a transaction is committed or rolled back.
+const commitKey = attribute.Key("tx.commit")
+
+var (
+	errNoPreparedTxLister = errors.New("prepared transactions can not be listed")
+	errInvalidMinAge      = errors.New("min_age must be a valid non-negative duration")
+)
+
+// WebhookNotifier queues webhook deliveries, it is implemented by webhook.Dispatcher.
+type WebhookNotifier interface {
+	// Hold queues deliveries of event about transaction txID before it is confirmed.
+	Hold(ctx context.Context, event string, txID string) error
+	// Settle releases deliveries held for txID when it is confirmed, otherwise they are discarded.
+	Settle(ctx context.Context, txID string, confirmed bool) error
+}
+
 type TnxConfirmingService struct {
 	pb.TnxConfirmingServiceServer
-	Repo repository.OrderRepoWith2PC
+	Repo   repository.OrderRepoWith2PC
+	Policy *auth.Policy
+	// Webhooks are notified of confirmed transactions, nil disables webhooks.
+	Webhooks WebhookNotifier
 }
 
 func (s *TnxConfirmingService) SendConfirmation(ctx context.Context,
@@ -27,21 +52,39 @@ func (s *TnxConfirmingService) SendConfirmation(ctx context.Context,
 	ctx, span := otel.Tracer(tracing.TracerName).Start(ctx, "SendConfirmation")
 	defer span.End()
 
-	logger := logging.FromContext(ctx)
-	logger.Info("Confirmation request received")
 	TnxID := confirmation.Tnx
+	span.SetAttributes(tracing.TxIDKey.String(TnxID), commitKey.Bool(confirmation.Commit))
+	// the order is not known before commit, prepared transactions do not tell it
+	ctx, logger := withOrderLogger(ctx, "", TnxID)
+	logger.Info("Confirmation request received")
+	if err := s.Policy.AuthorizeConfirmation(ctx); err != nil {
+		logger.WithError(err).Warn("confirmation access denied")
+		tracing.RecordError(span, err)
+
+		return nil, authzError(err)
+	}
 	TnxIdParsed, err := uuid.Parse(TnxID)
 	if err != nil {
 		logger.WithError(err).Error("Failed to parse TnxID as UUID")
+		tracing.RecordError(span, err)
 
 		return nil,
 			status.Error(codes.InvalidArgument, "Failed to parse TnxID as UUID") //nolint:wrapcheck //should be wrapped as is
 	}
 
+	// webhooks are queued before the outcome is applied, so a confirmed transaction is never left without them
+	if errHold := s.holdWebhooks(ctx, confirmation.Commit, TnxID); errHold != nil {
+		logger.WithError(errHold).Error("queueing webhooks failed")
+		tracing.RecordError(span, errHold)
+
+		return nil, status.Error(codes.Unavailable, "queueing webhooks failed") //nolint:wrapcheck // should be wrapped as is
+	}
 	if confirmation.Commit {
 		errCommit := s.Repo.CommitInsertTransaction(ctx, TnxIdParsed)
 		if errCommit != nil {
 			logger.WithError(errCommit).Error("commit tx failed")
+			tracing.RecordError(span, errCommit)
+			s.settleWebhooks(ctx, TnxID, false)
 
 			return nil, status.Error(codes.Internal, "commit tx failed") //nolint:wrapcheck // should be wrapped as is
 		}
@@ -49,10 +92,90 @@ func (s *TnxConfirmingService) SendConfirmation(ctx context.Context,
 		errRollback := s.Repo.RollbackInsertTransaction(ctx, TnxIdParsed)
 		if errRollback != nil {
 			logger.WithError(errRollback).Error("rollback tx failed")
+			tracing.RecordError(span, errRollback)
+			s.settleWebhooks(ctx, TnxID, false)
 
 			return nil, status.Error(codes.Internal, "rollback tx failed") //nolint:wrapcheck // should be wrapped as is
 		}
 	}
+	s.settleWebhooks(ctx, TnxID, true)
 
 	return &pb.ConfirmationResponse{}, nil
 }
+
+// ListPreparedTransactions lists transactions of the caller's tenant prepared at least min_age ago.
+func (s *TnxConfirmingService) ListPreparedTransactions(ctx context.Context, request *pb.ListPreparedTransactionsRequest,
+) (*pb.ListPreparedTransactionsResponse, error) {
+	ctx, span := otel.Tracer(tracing.TracerName).Start(ctx, "ListPreparedTransactions")
+	defer span.End()
+	logger := logging.FromContext(ctx)
+	logger.Info("ListPreparedTransactions")
+	if err := s.Policy.AuthorizeConfirmation(ctx); err != nil {
+		logger.WithError(err).Warn("prepared transactions access denied")
+		tracing.RecordError(span, err)
+
+		return nil, authzError(err)
+	}
+	lister, ok := s.Repo.(repository.PreparedTxLister)
+	if !ok {
+		tracing.RecordError(span, errNoPreparedTxLister)
+
+		return nil, status.Error(codes.Unimplemented, errNoPreparedTxLister.Error()) //nolint:wrapcheck // should be wrapped as is
+	}
+	var minAge time.Duration
+	if request.GetMinAge() != nil {
+		if err := request.GetMinAge().CheckValid(); err != nil || request.GetMinAge().AsDuration() < 0 {
+			tracing.RecordError(span, errInvalidMinAge)
+
+			return nil, status.Error(codes.InvalidArgument, errInvalidMinAge.Error()) //nolint:wrapcheck // should be wrapped as is
+		}
+		minAge = request.GetMinAge().AsDuration()
+	}
+
+	transactions, err := lister.ListPreparedTransactions(ctx, time.Now().Add(-minAge))
+	if err != nil {
+		logger.WithError(err).Error("list prepared transactions failed")
+		tracing.RecordError(span, err)
+
+		return nil, status.Error(codes.Internal, "list prepared transactions failed") //nolint:wrapcheck // should be wrapped as is
+	}
+	// gids of other tenants can not be confirmed by the caller, so they are not shown either
+	tenant := repository.TenantFromContext(ctx)
+	response := &pb.ListPreparedTransactionsResponse{}
+	for _, tx := range transactions {
+		if tx.Tenant != tenant {
+			continue
+		}
+		response.Transactions = append(response.Transactions, &pb.PreparedTransaction{
+			Tnx:        tx.TxID.String(),
+			PreparedAt: timestamppb.New(tx.Prepared),
+		})
+	}
+
+	return response, nil
+}
+
+// holdWebhooks queues webhooks about the outcome of transaction txID before it is applied.
+func (s *TnxConfirmingService) holdWebhooks(ctx context.Context, commit bool, txID string) error {
+	if s.Webhooks == nil {
+		return nil
+	}
+	event := webhook.EventOrderAborted
+	if commit {
+		event = webhook.EventOrderCommitted
+	}
+
+	return s.Webhooks.Hold(ctx, event, txID) //nolint:wrapcheck // logged by caller
+}
+
+// settleWebhooks releases or discards webhooks held for transaction txID. The outcome is applied already,
+// so failures are only logged: the dispatcher settles deliveries of transactions which are not prepared anymore.
+func (s *TnxConfirmingService) settleWebhooks(ctx context.Context, txID string, confirmed bool) {
+	if s.Webhooks == nil {
+		return
+	}
+	if err := s.Webhooks.Settle(ctx, txID, confirmed); err != nil {
+		logging.FromContext(ctx).WithError(err).WithField("confirmed", confirmed).
+			Error("settling webhooks failed, they are reconciled by the dispatcher")
+	}
+}
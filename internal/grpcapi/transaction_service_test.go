@@ -20,13 +20,19 @@ import (
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 	"github.com/stretchr/testify/assert"
+	otelcodes "go.opentelemetry.io/otel/codes"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	"github.com/Sugar-pack/orders-manager/internal/config"
 	"github.com/Sugar-pack/orders-manager/internal/db"
 	"github.com/Sugar-pack/orders-manager/internal/migration"
 	"github.com/Sugar-pack/orders-manager/internal/repository"
+	"github.com/Sugar-pack/orders-manager/internal/tracing"
+	"github.com/Sugar-pack/orders-manager/internal/webhook"
 	"github.com/Sugar-pack/orders-manager/pkg/pb"
 )
 
@@ -95,7 +101,7 @@ func DBConnection(ctx context.Context, t *testing.T, pool *dockertest.Pool, reso
 		ConnString:       fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=%s", dbHost, dbPort, dbUser, dbName, sslMode),
 		MigrationDirPath: "../../sql-migrations",
 		MigrationTable:   "migrations",
-		MaxOpenCons:      20,
+		MaxOpenConns:     20,
 		ConnMaxLifetime:  10 * time.Second,
 	}
 
@@ -345,6 +351,40 @@ func TestTnxConfirmingService_SendConfirmation_CommitError(t *testing.T) {
 	assert.Nil(t, sendConfirmation)
 }
 
+func TestTnxConfirmingService_SendConfirmation_Span(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	mockRepo := &mock.OrderRepoWith2PC{}
+	transactionService := TnxConfirmingService{Repo: mockRepo}
+	txID := uuid.New()
+	mockRepo.On("RollbackInsertTransaction", testify.Anything, txID).Return(errors.New("rollback error"))
+
+	_, err := transactionService.SendConfirmation(ctx, &pb.Confirmation{Tnx: txID.String()})
+	assert.Error(t, err)
+
+	span, attributes := spanAttributes(t, recorder, "SendConfirmation")
+	assert.Equal(t, txID.String(), attributes[tracing.TxIDKey])
+	assert.Equal(t, "false", attributes[commitKey])
+	assert.Equal(t, otelcodes.Error, span.Status().Code)
+}
+
+func TestTnxConfirmingService_SendConfirmation_LogFields(t *testing.T) {
+	ctx, logs := jsonLogs(t)
+	mockRepo := &mock.OrderRepoWith2PC{}
+	transactionService := TnxConfirmingService{Repo: mockRepo}
+	txID := uuid.New()
+	mockRepo.On("CommitInsertTransaction", testify.Anything, txID).Return(errors.New("commit error"))
+
+	_, err := transactionService.SendConfirmation(ctx, &pb.Confirmation{Tnx: txID.String(), Commit: true})
+	assert.Error(t, err)
+
+	lines := logs()
+	assert.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.Equal(t, txID.String(), line["tx_id"], line["msg"])
+	}
+}
+
 func TestTnxConfirmingService_SendConfirmation_RollbackError(t *testing.T) {
 	ctx := context.Background()
 	logger := logging.GetLogger()
@@ -404,3 +444,93 @@ func TestTnxConfirmingService_SendConfirmation_RollbackOk(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, sendConfirmation)
 }
+
+func TestTnxConfirmingService_SendConfirmation_Webhooks(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	mockRepo := mock.NewOrderRepoWith2PC(t)
+	notifier := mock.NewWebhookNotifier(t)
+	transactionService := TnxConfirmingService{Repo: mockRepo, Webhooks: notifier}
+	committed, aborted := uuid.New(), uuid.New()
+	notifier.On("Hold", testify.Anything, webhook.EventOrderCommitted, committed.String()).Return(nil)
+	mockRepo.On("CommitInsertTransaction", testify.Anything, committed).Return(nil)
+	notifier.On("Settle", testify.Anything, committed.String(), true).Return(nil)
+	notifier.On("Hold", testify.Anything, webhook.EventOrderAborted, aborted.String()).Return(nil)
+	mockRepo.On("RollbackInsertTransaction", testify.Anything, aborted).Return(nil)
+	notifier.On("Settle", testify.Anything, aborted.String(), true).Return(errors.New("db is down"))
+
+	_, err := transactionService.SendConfirmation(ctx, &pb.Confirmation{Tnx: committed.String(), Commit: true})
+	assert.NoError(t, err)
+	_, err = transactionService.SendConfirmation(ctx, &pb.Confirmation{Tnx: aborted.String()})
+	assert.NoError(t, err, "held webhooks are reconciled by the dispatcher")
+}
+
+func TestTnxConfirmingService_SendConfirmation_WebhooksNotQueued(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	mockRepo := mock.NewOrderRepoWith2PC(t)
+	notifier := mock.NewWebhookNotifier(t)
+	transactionService := TnxConfirmingService{Repo: mockRepo, Webhooks: notifier}
+	txID := uuid.New()
+	notifier.On("Hold", testify.Anything, webhook.EventOrderCommitted, txID.String()).Return(errors.New("db is down"))
+
+	_, err := transactionService.SendConfirmation(ctx, &pb.Confirmation{Tnx: txID.String(), Commit: true})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	mockRepo.AssertNotCalled(t, "CommitInsertTransaction", testify.Anything, testify.Anything)
+}
+
+func TestTnxConfirmingService_SendConfirmation_WebhooksDiscardedOnError(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	mockRepo := mock.NewOrderRepoWith2PC(t)
+	notifier := mock.NewWebhookNotifier(t)
+	transactionService := TnxConfirmingService{Repo: mockRepo, Webhooks: notifier}
+	txID := uuid.New()
+	notifier.On("Hold", testify.Anything, webhook.EventOrderCommitted, txID.String()).Return(nil)
+	mockRepo.On("CommitInsertTransaction", testify.Anything, txID).Return(errors.New("commit error"))
+	notifier.On("Settle", testify.Anything, txID.String(), false).Return(nil)
+
+	_, err := transactionService.SendConfirmation(ctx, &pb.Confirmation{Tnx: txID.String(), Commit: true})
+	assert.Error(t, err)
+}
+
+// preparedRepo lists transactions in addition to the mocked repository calls.
+type preparedRepo struct {
+	*mock.OrderRepoWith2PC
+	transactions  []repository.PreparedTransaction
+	preparedSince time.Duration
+}
+
+func (r *preparedRepo) ListPreparedTransactions(_ context.Context, preparedBefore time.Time,
+) ([]repository.PreparedTransaction, error) {
+	r.preparedSince = time.Since(preparedBefore)
+
+	return r.transactions, nil
+}
+
+func TestTnxConfirmingService_ListPreparedTransactions(t *testing.T) {
+	ctx := repository.WithTenant(logging.WithContext(context.Background(), logging.GetLogger()), "acme")
+	prepared := time.Now().Add(-time.Hour)
+	own, foreign := uuid.New(), uuid.New()
+	repo := &preparedRepo{transactions: []repository.PreparedTransaction{
+		{TxID: own, Tenant: "acme", Prepared: prepared},
+		{TxID: foreign, Tenant: "globex", Prepared: prepared},
+	}}
+	transactionService := TnxConfirmingService{Repo: repo}
+
+	response, err := transactionService.ListPreparedTransactions(ctx, &pb.ListPreparedTransactionsRequest{
+		MinAge: durationpb.New(time.Minute),
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, response.Transactions, 1, "transactions of other tenants are not listed") {
+		assert.Equal(t, own.String(), response.Transactions[0].Tnx)
+		assert.True(t, prepared.Equal(response.Transactions[0].PreparedAt.AsTime()))
+	}
+	assert.GreaterOrEqual(t, repo.preparedSince, time.Minute)
+
+	_, err = transactionService.ListPreparedTransactions(ctx, &pb.ListPreparedTransactionsRequest{
+		MinAge: durationpb.New(-time.Minute),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	transactionService.Repo = &mock.OrderRepoWith2PC{}
+	_, err = transactionService.ListPreparedTransactions(ctx, &pb.ListPreparedTransactionsRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
@@ -0,0 +1,238 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/Sugar-pack/orders-manager/internal/auth"
+	"github.com/Sugar-pack/orders-manager/internal/tracing"
+	"github.com/Sugar-pack/orders-manager/internal/webhook"
+	"github.com/Sugar-pack/orders-manager/pkg/pb"
+)
+
+const (
+	// DefaultDeadLetters is returned by ListDeadLetters without limit.
+	DefaultDeadLetters = 100
+	// MaxDeadLetters limits dead letters of a ListDeadLetters call.
+	MaxDeadLetters = 1000
+
+	secretSize = 32
+)
+
+// WebhookStore keeps webhook subscriptions of the tenant from context, it is implemented by webhook.Store.
+type WebhookStore interface {
+	CreateSubscription(ctx context.Context, subscription *webhook.Subscription) error
+	ListSubscriptions(ctx context.Context) ([]*webhook.Subscription, error)
+	// DeleteSubscription returns webhook.ErrNotFound for unknown subscriptions.
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	ListDeadLetters(ctx context.Context, limit int) ([]*webhook.DeadLetter, error)
+}
+
+type WebhookAdminService struct {
+	pb.WebhookAdminServiceServer
+	Store  WebhookStore
+	Policy *auth.Policy
+}
+
+func (s *WebhookAdminService) CreateWebhook(ctx context.Context, request *pb.CreateWebhookRequest) (*pb.Webhook, error) {
+	ctx, span := otel.Tracer(tracing.TracerName).Start(ctx, "CreateWebhook")
+	defer span.End()
+	logger := logging.FromContext(ctx)
+	if err := s.Policy.AuthorizeAdmin(ctx); err != nil {
+		logger.WithError(err).Warn("webhook access denied")
+		tracing.RecordError(span, err)
+
+		return nil, authzError(err)
+	}
+	if err := validateWebhookURL(request.GetUrl()); err != nil {
+		logger.WithError(err).Error("invalid webhook url")
+		tracing.RecordError(span, err)
+
+		return nil, status.Error(codes.InvalidArgument, "url must be absolute http or https url of a public host") //nolint:wrapcheck // should be wrapped as is
+	}
+	events := request.GetEvents()
+	if len(events) == 0 {
+		events = webhook.Events
+	}
+	for _, event := range events {
+		if !webhook.IsEvent(event) {
+			tracing.RecordError(span, errors.New("unknown webhook event"))
+
+			return nil, status.Errorf(codes.InvalidArgument, "event %q is not one of %v", event, webhook.Events) //nolint:wrapcheck // should be wrapped as is
+		}
+	}
+	secret := request.GetSecret()
+	if secret == "" {
+		secret = newSecret()
+	}
+
+	subscription := &webhook.Subscription{
+		ID:     uuid.New(),
+		URL:    request.GetUrl(),
+		Secret: secret,
+		Events: slices.Compact(slices.Sorted(slices.Values(events))),
+	}
+	if err := s.Store.CreateSubscription(ctx, subscription); err != nil {
+		logger.WithError(err).Error("create webhook failed")
+		tracing.RecordError(span, err)
+
+		return nil, status.Error(codes.Internal, "create webhook failed") //nolint:wrapcheck // should be wrapped as is
+	}
+	logger.WithField("webhook_id", subscription.ID.String()).Info("webhook created")
+	response := webhookResponse(subscription)
+	response.Secret = subscription.Secret
+
+	return response, nil
+}
+
+func (s *WebhookAdminService) ListWebhooks(ctx context.Context, _ *pb.ListWebhooksRequest) (*pb.ListWebhooksResponse, error) {
+	ctx, span := otel.Tracer(tracing.TracerName).Start(ctx, "ListWebhooks")
+	defer span.End()
+	logger := logging.FromContext(ctx)
+	if err := s.Policy.AuthorizeAdmin(ctx); err != nil {
+		logger.WithError(err).Warn("webhook access denied")
+		tracing.RecordError(span, err)
+
+		return nil, authzError(err)
+	}
+	subscriptions, err := s.Store.ListSubscriptions(ctx)
+	if err != nil {
+		logger.WithError(err).Error("list webhooks failed")
+		tracing.RecordError(span, err)
+
+		return nil, status.Error(codes.Internal, "list webhooks failed") //nolint:wrapcheck // should be wrapped as is
+	}
+	response := &pb.ListWebhooksResponse{Webhooks: make([]*pb.Webhook, 0, len(subscriptions))}
+	for _, subscription := range subscriptions {
+		response.Webhooks = append(response.Webhooks, webhookResponse(subscription))
+	}
+
+	return response, nil
+}
+
+func (s *WebhookAdminService) DeleteWebhook(ctx context.Context, request *pb.DeleteWebhookRequest,
+) (*pb.DeleteWebhookResponse, error) {
+	ctx, span := otel.Tracer(tracing.TracerName).Start(ctx, "DeleteWebhook")
+	defer span.End()
+	logger := logging.FromContext(ctx).WithField("webhook_id", request.GetId())
+	if err := s.Policy.AuthorizeAdmin(ctx); err != nil {
+		logger.WithError(err).Warn("webhook access denied")
+		tracing.RecordError(span, err)
+
+		return nil, authzError(err)
+	}
+	id, err := uuid.Parse(request.GetId())
+	if err != nil {
+		logger.WithError(err).Error("Failed to parse webhook id as UUID")
+		tracing.RecordError(span, err)
+
+		return nil, status.Error(codes.InvalidArgument, "Failed to parse webhook id as UUID") //nolint:wrapcheck // should be wrapped as is
+	}
+	err = s.Store.DeleteSubscription(ctx, id)
+	if errors.Is(err, webhook.ErrNotFound) {
+		tracing.RecordError(span, err)
+
+		return nil, status.Error(codes.NotFound, "webhook not found") //nolint:wrapcheck // should be wrapped as is
+	}
+	if err != nil {
+		logger.WithError(err).Error("delete webhook failed")
+		tracing.RecordError(span, err)
+
+		return nil, status.Error(codes.Internal, "delete webhook failed") //nolint:wrapcheck // should be wrapped as is
+	}
+	logger.Info("webhook deleted")
+
+	return &pb.DeleteWebhookResponse{}, nil
+}
+
+func (s *WebhookAdminService) ListDeadLetters(ctx context.Context, request *pb.ListDeadLettersRequest,
+) (*pb.ListDeadLettersResponse, error) {
+	ctx, span := otel.Tracer(tracing.TracerName).Start(ctx, "ListDeadLetters")
+	defer span.End()
+	logger := logging.FromContext(ctx)
+	if err := s.Policy.AuthorizeAdmin(ctx); err != nil {
+		logger.WithError(err).Warn("webhook access denied")
+		tracing.RecordError(span, err)
+
+		return nil, authzError(err)
+	}
+	limit := int(request.GetLimit())
+	switch {
+	case limit < 0 || limit > MaxDeadLetters:
+		tracing.RecordError(span, errors.New("limit out of range"))
+
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be at most %d", MaxDeadLetters) //nolint:wrapcheck // should be wrapped as is
+	case limit == 0:
+		limit = DefaultDeadLetters
+	}
+	deadLetters, err := s.Store.ListDeadLetters(ctx, limit)
+	if err != nil {
+		logger.WithError(err).Error("list dead letters failed")
+		tracing.RecordError(span, err)
+
+		return nil, status.Error(codes.Internal, "list dead letters failed") //nolint:wrapcheck // should be wrapped as is
+	}
+	response := &pb.ListDeadLettersResponse{DeadLetters: make([]*pb.DeadLetter, 0, len(deadLetters))}
+	for _, deadLetter := range deadLetters {
+		response.DeadLetters = append(response.DeadLetters, &pb.DeadLetter{
+			Id:        deadLetter.ID.String(),
+			WebhookId: deadLetter.SubscriptionID.String(),
+			Url:       deadLetter.URL,
+			Event:     deadLetter.Event,
+			Payload:   string(deadLetter.Payload),
+			Attempts:  int32(deadLetter.Attempts), //nolint:gosec // attempts are bounded by webhooks.max_attempts
+			LastError: deadLetter.LastError,
+			FailedAt:  timestamppb.New(deadLetter.FailedAt),
+		})
+	}
+
+	return response, nil
+}
+
+// webhookResponse returns subscription without its secret.
+func webhookResponse(subscription *webhook.Subscription) *pb.Webhook {
+	return &pb.Webhook{
+		Id:        subscription.ID.String(),
+		Url:       subscription.URL,
+		Events:    subscription.Events,
+		CreatedAt: timestamppb.New(subscription.CreatedAt),
+	}
+}
+
+func validateWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err //nolint:wrapcheck // logged only
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("not an absolute http url")
+	}
+	// names are checked at dial time by the dispatcher, as they may resolve differently later
+	host := parsed.Hostname()
+	if addr, parseErr := netip.ParseAddr(host); (parseErr == nil && !webhook.PublicAddr(addr)) ||
+		strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return errors.New("not a public destination")
+	}
+
+	return nil
+}
+
+func newSecret() string {
+	secret := make([]byte, secretSize)
+	_, _ = rand.Read(secret) // never fails, see rand.Read
+
+	return hex.EncodeToString(secret)
+}
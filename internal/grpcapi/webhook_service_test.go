@@ -0,0 +1,136 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	testify "github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Sugar-pack/orders-manager/internal/auth"
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	"github.com/Sugar-pack/orders-manager/internal/mock"
+	"github.com/Sugar-pack/orders-manager/internal/webhook"
+	"github.com/Sugar-pack/orders-manager/pkg/pb"
+)
+
+func TestWebhookAdminService_CreateWebhook(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	store := mock.NewWebhookStore(t)
+	service := WebhookAdminService{Store: store}
+	var stored *webhook.Subscription
+	store.On("CreateSubscription", testify.Anything, testify.Anything).Run(func(args testify.Arguments) {
+		stored = args.Get(1).(*webhook.Subscription)
+	}).Return(nil)
+
+	created, err := service.CreateWebhook(ctx, &pb.CreateWebhookRequest{Url: "https://partner.example/hooks"})
+	assert.NoError(t, err)
+	assert.Equal(t, stored.ID.String(), created.GetId())
+	assert.ElementsMatch(t, webhook.Events, created.GetEvents(), "all events without events in request")
+	assert.Len(t, created.GetSecret(), 2*secretSize, "secret is generated")
+	assert.Equal(t, stored.Secret, created.GetSecret())
+
+	created, err = service.CreateWebhook(ctx, &pb.CreateWebhookRequest{
+		Url: "http://partner.example/hooks", Events: []string{webhook.EventOrderAborted}, Secret: "s3cret",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{webhook.EventOrderAborted}, created.GetEvents())
+	assert.Equal(t, "s3cret", created.GetSecret())
+}
+
+func TestWebhookAdminService_CreateWebhook_Invalid(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	service := WebhookAdminService{Store: mock.NewWebhookStore(t)}
+
+	for _, request := range []*pb.CreateWebhookRequest{
+		{Url: "partner.example/hooks"},
+		{Url: "ftp://partner.example/hooks"},
+		{Url: "http://169.254.169.254/latest/meta-data"},
+		{Url: "http://[::1]:8081/v1/orders"},
+		{Url: "http://localhost:8081/v1/orders"},
+		{Url: "https://partner.example/hooks", Events: []string{"order.created"}},
+	} {
+		_, err := service.CreateWebhook(ctx, request)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), request.String())
+	}
+}
+
+func TestWebhookAdminService_Authorization(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	service := WebhookAdminService{Store: mock.NewWebhookStore(t), Policy: auth.NewPolicy(&config.Auth{})}
+	coordinator := auth.WithPrincipal(ctx, &auth.Principal{Subject: "tx-coordinator", Roles: []string{"coordinator"}})
+
+	_, err := service.ListWebhooks(ctx, &pb.ListWebhooksRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = service.CreateWebhook(coordinator, &pb.CreateWebhookRequest{Url: "https://partner.example/hooks"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = service.DeleteWebhook(coordinator, &pb.DeleteWebhookRequest{Id: uuid.NewString()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = service.ListDeadLetters(coordinator, &pb.ListDeadLettersRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestWebhookAdminService_ListWebhooks(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	store := mock.NewWebhookStore(t)
+	service := WebhookAdminService{Store: store}
+	subscription := &webhook.Subscription{
+		ID: uuid.New(), URL: "https://partner.example/hooks", Secret: "s3cret", Events: webhook.Events, CreatedAt: time.Now(),
+	}
+	store.On("ListSubscriptions", testify.Anything).Return([]*webhook.Subscription{subscription}, nil).Once()
+
+	listed, err := service.ListWebhooks(ctx, &pb.ListWebhooksRequest{})
+	assert.NoError(t, err)
+	if assert.Len(t, listed.GetWebhooks(), 1) {
+		assert.Equal(t, subscription.URL, listed.GetWebhooks()[0].GetUrl())
+		assert.Empty(t, listed.GetWebhooks()[0].GetSecret(), "secret is returned only on create")
+	}
+
+	store.On("ListSubscriptions", testify.Anything).Return(nil, errors.New("db is down")).Once()
+	_, err = service.ListWebhooks(ctx, &pb.ListWebhooksRequest{})
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestWebhookAdminService_DeleteWebhook(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	store := mock.NewWebhookStore(t)
+	service := WebhookAdminService{Store: store}
+	deleted, missing := uuid.New(), uuid.New()
+	store.On("DeleteSubscription", testify.Anything, deleted).Return(nil)
+	store.On("DeleteSubscription", testify.Anything, missing).Return(webhook.ErrNotFound)
+
+	_, err := service.DeleteWebhook(ctx, &pb.DeleteWebhookRequest{Id: deleted.String()})
+	assert.NoError(t, err)
+	_, err = service.DeleteWebhook(ctx, &pb.DeleteWebhookRequest{Id: missing.String()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = service.DeleteWebhook(ctx, &pb.DeleteWebhookRequest{Id: "hook"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestWebhookAdminService_ListDeadLetters(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	store := mock.NewWebhookStore(t)
+	service := WebhookAdminService{Store: store}
+	deadLetter := &webhook.DeadLetter{
+		ID: uuid.New(), SubscriptionID: uuid.New(), URL: "https://partner.example/hooks", Event: webhook.EventOrderCommitted,
+		Payload: []byte(`{"event":"order.committed"}`), Attempts: 10, LastError: "connection refused", FailedAt: time.Now(),
+	}
+	store.On("ListDeadLetters", testify.Anything, DefaultDeadLetters).Return([]*webhook.DeadLetter{deadLetter}, nil)
+
+	listed, err := service.ListDeadLetters(ctx, &pb.ListDeadLettersRequest{})
+	assert.NoError(t, err)
+	if assert.Len(t, listed.GetDeadLetters(), 1) {
+		assert.Equal(t, deadLetter.SubscriptionID.String(), listed.GetDeadLetters()[0].GetWebhookId())
+		assert.Equal(t, string(deadLetter.Payload), listed.GetDeadLetters()[0].GetPayload())
+		assert.Equal(t, int32(10), listed.GetDeadLetters()[0].GetAttempts())
+	}
+
+	_, err = service.ListDeadLetters(ctx, &pb.ListDeadLettersRequest{Limit: MaxDeadLetters + 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,55 @@
+// Package health tracks readiness of the service and reports it via grpc health service.
+package health
+
+import (
+	"sync/atomic"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Readiness is NOT_SERVING until SetReady is called. It is safe for concurrent use.
+type Readiness struct {
+	server   *health.Server
+	services []string
+	ready    atomic.Bool
+}
+
+// NewReadiness creates not ready Readiness for overall server and services.
+func NewReadiness(services ...string) *Readiness {
+	readiness := &Readiness{
+		server:   health.NewServer(),
+		services: append([]string{""}, services...),
+	}
+	readiness.set(healthpb.HealthCheckResponse_NOT_SERVING)
+
+	return readiness
+}
+
+// Server returns grpc health service to register.
+func (r *Readiness) Server() healthpb.HealthServer {
+	return r.server
+}
+
+// Ready reports whether SetReady was called.
+func (r *Readiness) Ready() bool {
+	return r.ready.Load()
+}
+
+// SetReady reports the service as SERVING.
+func (r *Readiness) SetReady() {
+	r.ready.Store(true)
+	r.set(healthpb.HealthCheckResponse_SERVING)
+}
+
+// Shutdown reports the service as NOT_SERVING and ignores further updates.
+func (r *Readiness) Shutdown() {
+	r.ready.Store(false)
+	r.server.Shutdown()
+}
+
+func (r *Readiness) set(status healthpb.HealthCheckResponse_ServingStatus) {
+	for _, service := range r.services {
+		r.server.SetServingStatus(service, status)
+	}
+}
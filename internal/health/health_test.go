@@ -0,0 +1,35 @@
+package health
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func check(t *testing.T, readiness *Readiness, service string) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+	resp, err := readiness.Server().Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	return resp.GetStatus()
+}
+
+func TestReadiness(t *testing.T) {
+	readiness := NewReadiness("pb.OrdersManagerService")
+	assert.False(t, readiness.Ready())
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(t, readiness, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(t, readiness, "pb.OrdersManagerService"))
+
+	readiness.SetReady()
+	assert.True(t, readiness.Ready())
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check(t, readiness, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check(t, readiness, "pb.OrdersManagerService"))
+
+	readiness.Shutdown()
+	assert.False(t, readiness.Ready())
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(t, readiness, ""))
+}
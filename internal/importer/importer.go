@@ -0,0 +1,233 @@
+// Package importer loads historical orders in bulk, bypassing two-phase commit.
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/google/uuid"
+
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+)
+
+// idNamespace derives IDs of orders imported without id from input name, line and content of the row,
+// so importing the same rows again yields the same IDs and they are skipped, while different rows
+// at the same line of inputs sharing a name get IDs of their own.
+var idNamespace = uuid.MustParse("5d2b8f0e-6c1a-4f4e-9a59-3c0f2f8e7a41")
+
+// Options configure Import.
+type Options struct {
+	Source     string // names input in IDs derived for orders without id
+	ChunkSize  int    // rows loaded at a time
+	Shards     int    // number of shards orders are spread across, 1 without sharding
+	Checkpoint string // file recording progress, empty disables resuming
+}
+
+// Loader stores orders on shard and returns how many of them were not stored before.
+type Loader func(ctx context.Context, shard int, orders []*repository.Order) (int64, error)
+
+// Reject is a row which was not imported.
+type Reject struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Report sums up Import, runs resumed from a checkpoint include rows of the previous ones.
+type Report struct {
+	Source   string `json:"source"`
+	Rows     int    `json:"rows"`     // rows read
+	Imported int64  `json:"imported"` // orders stored
+	Existing int64  `json:"existing"` // valid orders stored before, e.g. by an interrupted run
+	Rejected int    `json:"rejected"` // invalid rows
+}
+
+// Import reads rows, writes invalid ones to rejects as NDJSON and loads valid ones chunk by chunk.
+// Progress is saved to the checkpoint after every chunk, an interrupted import started again
+// skips the rows already loaded. Rows of a chunk interrupted half way are loaded again, stored ones are skipped by load.
+// The checkpoint keeps a digest of the rows it covers, it is refused when the skipped rows differ.
+func Import(ctx context.Context, reader Reader, load Loader, rejects io.Writer, opts Options) (Report, error) {
+	logger := logging.FromContext(ctx)
+	report, digest, err := readCheckpoint(opts.Checkpoint, opts.Source)
+	if err != nil {
+		return report, err
+	}
+	read := sha256.New()
+	for skipped := 0; skipped < report.Rows; skipped++ {
+		row, skipErr := reader.Read()
+		if skipErr != nil {
+			return report, fmt.Errorf("skip %d rows of checkpoint: %w", report.Rows, skipErr)
+		}
+		read.Write(row.Sum[:])
+	}
+	if sum := hex.EncodeToString(read.Sum(nil)); report.Rows > 0 && sum != digest {
+		return Report{Source: opts.Source}, fmt.Errorf("checkpoint %s belongs to other rows of %s", opts.Checkpoint, opts.Source)
+	}
+	if report.Rows > 0 {
+		logger.WithField("rows", report.Rows).Info("resuming import from checkpoint")
+	}
+
+	chunk := newChunk(opts.Shards)
+	encoder := json.NewEncoder(rejects)
+	for {
+		row, readErr := reader.Read()
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return report, readErr //nolint:wrapcheck
+		}
+		if readErr == nil {
+			read.Write(row.Sum[:])
+			chunk.add(row, opts)
+		}
+		if chunk.rows < opts.ChunkSize && readErr == nil {
+			continue
+		}
+
+		if err = chunk.flush(ctx, load, encoder, &report); err != nil {
+			return report, err
+		}
+		if err = writeCheckpoint(opts.Checkpoint, report, read); err != nil {
+			return report, err
+		}
+		logger.WithField("rows", report.Rows).Debug("import chunk loaded")
+		if readErr != nil {
+			return report, nil
+		}
+	}
+}
+
+// chunk is rows read since the last flush.
+type chunk struct {
+	rows    int
+	orders  [][]*repository.Order // by shard
+	rejects []Reject
+}
+
+func newChunk(shards int) *chunk {
+	if shards < 1 {
+		shards = 1
+	}
+
+	return &chunk{orders: make([][]*repository.Order, shards)}
+}
+
+func (c *chunk) add(row Row, opts Options) {
+	c.rows++
+	order := row.Order
+	shard, err := c.shard(order, row, opts)
+	if row.Err != nil || err != nil {
+		c.rejects = append(c.rejects, Reject{Line: row.Line, Error: errors.Join(row.Err, err).Error()})
+
+		return
+	}
+	c.orders[shard] = append(c.orders[shard], order)
+}
+
+// shard assigns ID to order imported without one and returns shard of order. IDs of input are kept,
+// orders with IDs not carrying a shard go to the shard of the user and are looked up on every shard.
+func (c *chunk) shard(order *repository.Order, row Row, opts Options) (int, error) {
+	if order == nil {
+		return 0, nil
+	}
+	derived := order.ID == uuid.Nil
+	if derived {
+		order.ID = uuid.NewSHA1(idNamespace,
+			[]byte(opts.Source+":"+strconv.Itoa(row.Line)+":"+hex.EncodeToString(row.Sum[:])))
+	}
+	if len(c.orders) == 1 {
+		return 0, nil
+	}
+	if shard, ok := repository.ShardOf(order.ID); ok && !derived {
+		if shard >= len(c.orders) {
+			return 0, fmt.Errorf("%w: %d", repository.ErrUnknownShard, shard)
+		}
+
+		return shard, nil
+	}
+	shard := repository.UserShard(order.UserID, len(c.orders))
+	if derived {
+		order.ID = repository.WithShard(order.ID, shard)
+	}
+
+	return shard, nil
+}
+
+// flush loads orders, writes rejects and adds them to report.
+func (c *chunk) flush(ctx context.Context, load Loader, rejects *json.Encoder, report *Report) error {
+	for shard, orders := range c.orders {
+		if len(orders) == 0 {
+			continue
+		}
+		inserted, err := load(ctx, shard, orders)
+		if err != nil {
+			return fmt.Errorf("load rows after %d: %w", report.Rows, err)
+		}
+		report.Imported += inserted
+		report.Existing += int64(len(orders)) - inserted
+		c.orders[shard] = orders[:0]
+	}
+	for _, reject := range c.rejects {
+		if err := rejects.Encode(reject); err != nil {
+			return fmt.Errorf("write rejects: %w", err)
+		}
+	}
+	report.Rows += c.rows
+	report.Rejected += len(c.rejects)
+	c.rows, c.rejects = 0, c.rejects[:0]
+
+	return nil
+}
+
+// checkpoint is progress of an import, Digest is SHA-256 over sums of the rows it covers.
+type checkpoint struct {
+	Report
+	Digest string `json:"digest"`
+}
+
+// readCheckpoint returns progress saved for source and digest of its rows, empty report when there is none.
+func readCheckpoint(path, source string) (Report, string, error) {
+	saved := checkpoint{Report: Report{Source: source}}
+	if path == "" {
+		return saved.Report, "", nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return saved.Report, "", nil
+	}
+	if err != nil {
+		return saved.Report, "", fmt.Errorf("read checkpoint: %w", err)
+	}
+	if err = json.Unmarshal(data, &saved); err != nil {
+		return Report{Source: source}, "", fmt.Errorf("parse checkpoint %s: %w", path, err)
+	}
+	if saved.Source != source {
+		return Report{Source: source}, "", fmt.Errorf("checkpoint %s belongs to %s", path, saved.Source)
+	}
+
+	return saved.Report, saved.Digest, nil
+}
+
+// writeCheckpoint replaces checkpoint at once, so an interruption leaves either the old or the new one.
+func writeCheckpoint(path string, report Report, read hash.Hash) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(checkpoint{Report: report, Digest: hex.EncodeToString(read.Sum(nil))})
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+
+	return os.Rename(tmp, path) //nolint:wrapcheck
+}
@@ -0,0 +1,148 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+)
+
+// store is a Loader keeping orders in memory.
+type store struct {
+	orders map[uuid.UUID]int // shard by id
+	loads  int
+	failAt int // load failing, 0 never fails
+}
+
+func (s *store) load(_ context.Context, shard int, orders []*repository.Order) (int64, error) {
+	s.loads++
+	if s.loads == s.failAt {
+		return 0, errors.New("connection lost")
+	}
+	var inserted int64
+	for _, order := range orders {
+		if _, ok := s.orders[order.ID]; !ok {
+			s.orders[order.ID] = shard
+			inserted++
+		}
+	}
+
+	return inserted, nil
+}
+
+func ndjson(rows int, invalidEvery int) string {
+	var b strings.Builder
+	for i := 1; i <= rows; i++ {
+		if invalidEvery > 0 && i%invalidEvery == 0 {
+			b.WriteString("{}\n")
+
+			continue
+		}
+		fmt.Fprintf(&b, `{"user_id":"%s","label":"order %d","created_at":"2020-01-02T03:04:05Z"}`+"\n", uuid.New(), i)
+	}
+
+	return b.String()
+}
+
+func runImport(t *testing.T, input string, s *store, opts Options) (Report, error, []Reject) {
+	t.Helper()
+	reader, err := NewReader(FormatNDJSON, strings.NewReader(input))
+	assert.NoError(t, err)
+	var rejects bytes.Buffer
+	report, err := Import(context.Background(), reader, s.load, &rejects, opts)
+	var rejected []Reject
+	decoder := json.NewDecoder(&rejects)
+	for decoder.More() {
+		var reject Reject
+		assert.NoError(t, decoder.Decode(&reject))
+		rejected = append(rejected, reject)
+	}
+
+	return report, err, rejected
+}
+
+func TestImport(t *testing.T) {
+	s := &store{orders: map[uuid.UUID]int{}}
+	report, err, rejected := runImport(t, ndjson(10, 4), s, Options{Source: "orders.ndjson", ChunkSize: 3, Shards: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, Report{Source: "orders.ndjson", Rows: 10, Imported: 8, Rejected: 2}, report)
+	assert.Equal(t, 4, s.loads, "rows are loaded in chunks")
+	if assert.Len(t, rejected, 2) {
+		assert.Equal(t, 4, rejected[0].Line)
+		assert.Equal(t, 8, rejected[1].Line)
+		assert.Contains(t, rejected[0].Error, "user_id")
+	}
+}
+
+func TestImport_ResumesFromCheckpoint(t *testing.T) {
+	input := ndjson(10, 0)
+	opts := Options{Source: "orders.ndjson", ChunkSize: 4, Shards: 1, Checkpoint: filepath.Join(t.TempDir(), "checkpoint")}
+	s := &store{orders: map[uuid.UUID]int{}, failAt: 2}
+
+	report, err, _ := runImport(t, input, s, opts)
+	assert.ErrorContains(t, err, "connection lost")
+	assert.Equal(t, 4, report.Rows, "the first chunk is loaded")
+
+	s.failAt = 0
+	report, err, _ = runImport(t, input, s, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, Report{Source: "orders.ndjson", Rows: 10, Imported: 10}, report)
+	assert.Len(t, s.orders, 10)
+
+	// a checkpoint of another input of the same name is refused
+	_, err, _ = runImport(t, ndjson(10, 0), s, opts)
+	assert.ErrorContains(t, err, "belongs to other rows of orders.ndjson")
+
+	// IDs are derived from source, line and row, rows loaded again are not duplicated
+	s.orders = map[uuid.UUID]int{}
+	opts.Checkpoint = ""
+	_, err, _ = runImport(t, input, s, opts)
+	assert.NoError(t, err)
+	report, err, _ = runImport(t, input, s, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), report.Existing)
+	// while other rows of an input of the same name are
+	report, err, _ = runImport(t, ndjson(10, 0), s, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, Report{Source: "orders.ndjson", Rows: 10, Imported: 10}, report)
+
+	opts.Checkpoint = filepath.Join(t.TempDir(), "checkpoint")
+	assert.NoError(t, writeCheckpoint(opts.Checkpoint, Report{Source: "other.ndjson", Rows: 3}, sha256.New()))
+	_, err, _ = runImport(t, input, s, opts)
+	assert.ErrorContains(t, err, "belongs to other.ndjson")
+}
+
+func TestImport_Shards(t *testing.T) {
+	s := &store{orders: map[uuid.UUID]int{}}
+	keptID := uuid.New()
+	unknownShard := repository.WithShard(uuid.New(), 9)
+	input := ndjson(20, 0) +
+		fmt.Sprintf(`{"id":"%s","user_id":"%s","created_at":"2020-01-02T03:04:05Z"}`+"\n", keptID, uuid.New()) +
+		fmt.Sprintf(`{"id":"%s","user_id":"%s","created_at":"2020-01-02T03:04:05Z"}`+"\n", unknownShard, uuid.New())
+	report, err, rejected := runImport(t, input, s, Options{Source: "orders.ndjson", ChunkSize: 100, Shards: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(21), report.Imported)
+	if assert.Len(t, rejected, 1) {
+		assert.Contains(t, rejected[0].Error, "unknown shard")
+	}
+
+	for id, shard := range s.orders {
+		if id == keptID {
+			continue
+		}
+		carried, ok := repository.ShardOf(id)
+		assert.True(t, ok, "derived IDs carry the shard")
+		assert.Equal(t, shard, carried)
+	}
+	assert.Contains(t, s.orders, keptID, "IDs of input are kept")
+}
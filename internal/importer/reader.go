@@ -0,0 +1,196 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Sugar-pack/orders-manager/internal/export"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+)
+
+// Supported formats, they are the ones written by export.
+const (
+	FormatCSV    = export.FormatCSV
+	FormatNDJSON = export.FormatNDJSON
+)
+
+// Row is a row of input. Order is nil and Err tells why when the row is invalid.
+type Row struct {
+	Line  int               // line of input the row starts at
+	Order *repository.Order // ID is uuid.Nil when input has none
+	Err   error
+	Sum   [sha256.Size]byte // of the row as read, identifies its content
+}
+
+// Reader reads rows of input, it returns io.EOF after the last one.
+type Reader interface {
+	Read() (Row, error)
+}
+
+// NewReader creates Reader of format. Rows are shaped like pb.Order with optional id,
+// fields are named like in the proto: id, user_id, label and created_at, or like in its JSON mapping:
+// userId and createdAt.
+func NewReader(format string, input io.Reader) (Reader, error) {
+	switch format {
+	case FormatNDJSON:
+		return &ndjsonReader{reader: bufio.NewReader(input)}, nil
+	case FormatCSV:
+		return newCSVReader(input)
+	default:
+		return nil, fmt.Errorf("unknown import format %q, supported are %v", format, []string{FormatCSV, FormatNDJSON})
+	}
+}
+
+// record is a row before validation.
+type record struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	Label     string `json:"label"`
+	CreatedAt string `json:"created_at"`
+}
+
+// jsonNames maps names of fields in the JSON mapping of pb.Order to the proto ones.
+var jsonNames = map[string]string{"userId": "user_id", "createdAt": "created_at"}
+
+// jsonRecord is a record of NDJSON, fields may be named either way.
+type jsonRecord struct {
+	record
+	UserIDJSON    string `json:"userId"`
+	CreatedAtJSON string `json:"createdAt"`
+}
+
+func (r jsonRecord) merged() record {
+	if r.UserID == "" {
+		r.UserID = r.UserIDJSON
+	}
+	if r.CreatedAt == "" {
+		r.CreatedAt = r.CreatedAtJSON
+	}
+
+	return r.record
+}
+
+// order validates r.
+func (r record) order() (*repository.Order, error) {
+	order := &repository.Order{Label: r.Label}
+	var err error
+	if r.ID != "" {
+		if order.ID, err = uuid.Parse(r.ID); err != nil {
+			return nil, fmt.Errorf("invalid id: %w", err)
+		}
+	}
+	if order.UserID, err = uuid.Parse(r.UserID); err != nil {
+		return nil, fmt.Errorf("invalid user_id: %w", err)
+	}
+	if order.UserID == uuid.Nil {
+		return nil, errors.New("user_id is required")
+	}
+	if r.CreatedAt == "" {
+		return nil, errors.New("created_at is required")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, r.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid created_at: %w", err)
+	}
+	order.CreatedAt = createdAt.UTC()
+
+	return order, nil
+}
+
+type ndjsonReader struct {
+	reader *bufio.Reader
+	line   int
+}
+
+func (r *ndjsonReader) Read() (Row, error) {
+	for {
+		data, err := r.reader.ReadBytes('\n')
+		if len(data) == 0 && err != nil {
+			return Row{}, err //nolint:wrapcheck // io.EOF is returned as is
+		}
+		r.line++
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+		row := Row{Line: r.line, Sum: sha256.Sum256(data)}
+		var rec jsonRecord
+		if row.Err = json.Unmarshal(data, &rec); row.Err == nil {
+			row.Order, row.Err = rec.merged().order()
+		}
+
+		return row, nil
+	}
+}
+
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	width   int
+}
+
+// newCSVReader reads header naming columns, id column is optional.
+func newCSVReader(input io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if protoName, ok := jsonNames[name]; ok {
+			name = protoName
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"user_id", "label", "created_at"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("csv header has no %s column", name)
+		}
+	}
+
+	return &csvReader{reader: reader, columns: columns, width: len(header)}, nil
+}
+
+func (r *csvReader) Read() (Row, error) {
+	fields, err := r.reader.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return Row{Line: parseErr.StartLine, Err: err, Sum: sha256.Sum256([]byte(err.Error()))}, nil
+	}
+	if err != nil {
+		return Row{}, err //nolint:wrapcheck // io.EOF is returned as is
+	}
+	line, _ := r.reader.FieldPos(0)
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+	if len(fields) != r.width {
+		return Row{Line: line, Err: fmt.Errorf("want %d fields, got %d", r.width, len(fields)), Sum: sum}, nil
+	}
+	field := func(name string) string {
+		if i, ok := r.columns[name]; ok {
+			return fields[i]
+		}
+
+		return ""
+	}
+	row := Row{Line: line, Sum: sum}
+	row.Order, row.Err = record{
+		ID:        field("id"),
+		UserID:    field("user_id"),
+		Label:     field("label"),
+		CreatedAt: field("created_at"),
+	}.order()
+
+	return row, nil
+}
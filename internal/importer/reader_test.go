@@ -0,0 +1,102 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func readAll(t *testing.T, reader Reader) []Row {
+	t.Helper()
+	var rows []Row
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows
+		}
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestReader_NDJSON(t *testing.T) {
+	orderID, userID := uuid.New(), uuid.New()
+	input := `{"id":"` + orderID.String() + `","user_id":"` + userID.String() + `","label":"first","created_at":"2020-01-02T03:04:05Z"}
+
+{"user_id":"` + userID.String() + `","label":"second","created_at":"2020-01-02T05:04:05+02:00"}
+not json
+{"user_id":"nope","created_at":"2020-01-02T03:04:05Z"}
+{"user_id":"` + userID.String() + `"}`
+	reader, err := NewReader(FormatNDJSON, strings.NewReader(input))
+	assert.NoError(t, err)
+	rows := readAll(t, reader)
+
+	if assert.Len(t, rows, 5) {
+		assert.NoError(t, rows[0].Err)
+		assert.Equal(t, orderID, rows[0].Order.ID)
+		assert.Equal(t, userID, rows[0].Order.UserID)
+		assert.Equal(t, "first", rows[0].Order.Label)
+		assert.Equal(t, 3, rows[1].Line, "blank lines are skipped")
+		assert.Equal(t, uuid.Nil, rows[1].Order.ID)
+		assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), rows[1].Order.CreatedAt)
+		assert.Error(t, rows[2].Err)
+		assert.ErrorContains(t, rows[3].Err, "invalid user_id")
+		assert.ErrorContains(t, rows[4].Err, "created_at is required")
+		assert.Nil(t, rows[4].Order)
+	}
+}
+
+func TestReader_JSONNames(t *testing.T) {
+	userID := uuid.New()
+	input := `{"userId":"` + userID.String() + `","label":"first","createdAt":"2020-01-02T03:04:05Z"}` + "\n" +
+		`{"user_id":"` + userID.String() + `","label":"first","created_at":"2020-01-02T03:04:05Z"}`
+	reader, err := NewReader(FormatNDJSON, strings.NewReader(input))
+	assert.NoError(t, err)
+	rows := readAll(t, reader)
+	if assert.Len(t, rows, 2) {
+		assert.NoError(t, rows[0].Err)
+		assert.Equal(t, rows[1].Order, rows[0].Order, "names of the JSON mapping are accepted")
+		assert.NotEqual(t, rows[1].Sum, rows[0].Sum)
+	}
+
+	reader, err = NewReader(FormatCSV, strings.NewReader("userId,label,createdAt\n"+userID.String()+",first,2020-01-02T03:04:05Z\n"))
+	assert.NoError(t, err)
+	rows = readAll(t, reader)
+	if assert.Len(t, rows, 1) {
+		assert.NoError(t, rows[0].Err)
+		assert.Equal(t, userID, rows[0].Order.UserID)
+	}
+}
+
+func TestReader_CSV(t *testing.T) {
+	userID := uuid.New()
+	input := "label,created_at,user_id\n" +
+		"\"with, comma\",2020-01-02T03:04:05Z," + userID.String() + "\n" +
+		"short,2020-01-02T03:04:05Z\n" +
+		"bad date,yesterday," + userID.String() + "\n"
+	reader, err := NewReader(FormatCSV, strings.NewReader(input))
+	assert.NoError(t, err)
+	rows := readAll(t, reader)
+
+	if assert.Len(t, rows, 3) {
+		assert.NoError(t, rows[0].Err)
+		assert.Equal(t, "with, comma", rows[0].Order.Label)
+		assert.Equal(t, userID, rows[0].Order.UserID)
+		assert.Equal(t, 2, rows[0].Line)
+		assert.ErrorContains(t, rows[1].Err, "want 3 fields")
+		assert.Equal(t, 4, rows[2].Line)
+		assert.ErrorContains(t, rows[2].Err, "invalid created_at")
+	}
+
+	_, err = NewReader(FormatCSV, strings.NewReader("id,label\n"))
+	assert.ErrorContains(t, err, "no user_id column")
+	_, err = NewReader("xml", strings.NewReader(""))
+	assert.Error(t, err)
+}
@@ -0,0 +1,117 @@
+// Package logger builds logging.Logger of users-manager with settings from config.
+package logger
+
+import (
+	"io"
+	"os"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+// DefaultLevel matches level of logging.GetLogger.
+const DefaultLevel = "trace"
+
+// entry implements logging.Logger.
+type entry struct {
+	*logrus.Entry
+}
+
+func (e *entry) WithError(err error) logging.Logger {
+	return &entry{e.Entry.WithError(err)}
+}
+
+func (e *entry) WithField(key string, value interface{}) logging.Logger {
+	return &entry{e.Entry.WithField(key, value)}
+}
+
+func (e *entry) WithFields(fields logging.Fields) logging.Logger {
+	return &entry{e.Entry.WithFields(logrus.Fields(fields))}
+}
+
+// Handle changes settings of loggers created by New at runtime.
+type Handle struct {
+	logger *logrus.Logger
+	file   *os.File // log file of config.Log.Output, nil for stdout and stderr
+}
+
+// New creates logger configured by conf, nil conf gives settings of logging.GetLogger.
+// Sensitive fields are redacted whatever the settings are, see RedactHook.
+func New(conf *config.Log) (logging.Logger, *Handle, error) {
+	if conf == nil {
+		conf = &config.Log{}
+	}
+	base := logrus.New()
+	if conf.Format == config.LogFormatJSON {
+		base.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		base.SetFormatter(&logrus.TextFormatter{
+			FullTimestamp: true,
+		})
+	}
+	base.AddHook(logging.GetFileLineHook())
+	base.AddHook(RedactHook{})
+
+	handle := &Handle{logger: base}
+	switch conf.Output {
+	case "", config.LogOutputStdout:
+		base.SetOutput(os.Stdout)
+	case config.LogOutputStderr:
+		base.SetOutput(os.Stderr)
+	default:
+		file, err := os.OpenFile(conf.Output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, nil, err //nolint:wrapcheck // too simple to wrap
+		}
+		base.SetOutput(file)
+		handle.file = file
+	}
+	level := DefaultLevel
+	if conf.Level != "" {
+		level = conf.Level
+	}
+	if err := handle.SetLevel(level); err != nil {
+		_ = handle.Close()
+
+		return nil, nil, err
+	}
+
+	return &entry{logrus.NewEntry(base)}, handle, nil
+}
+
+// SetLevel changes level of all loggers derived from the one created with the handle.
+func (h *Handle) SetLevel(level string) error {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err //nolint:wrapcheck // too simple to wrap
+	}
+	h.logger.SetLevel(parsed)
+
+	return nil
+}
+
+// Level returns current level.
+func (h *Handle) Level() string {
+	return h.logger.GetLevel().String()
+}
+
+// SetOutput redirects loggers created with the handle, e.g. to stderr when stdout carries data.
+func (h *Handle) SetOutput(output io.Writer) {
+	h.logger.SetOutput(output)
+}
+
+// ToStdout tells whether loggers created with the handle write to stdout.
+func (h *Handle) ToStdout() bool {
+	return h.logger.Out == os.Stdout
+}
+
+// Close closes log file, loggers must not be used after it.
+func (h *Handle) Close() error {
+	if h.file == nil {
+		return nil
+	}
+
+	return h.file.Close() //nolint:wrapcheck // too simple to wrap
+}
@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+// readLines returns JSON log lines of file.
+func readLines(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var fields map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &fields))
+		lines = append(lines, fields)
+	}
+
+	return lines
+}
+
+func TestNew_FormatAndOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders-manager.log")
+	logger, handle, err := New(&config.Log{Level: "info", Format: config.LogFormatJSON, Output: path})
+	assert.NoError(t, err)
+	assert.False(t, handle.ToStdout())
+
+	logger.Debug("filtered out")
+	logger.WithField("conn_string", "postgres://orders:s3cret@db:5432/orders").
+		WithField("token", "abc").Info("connecting")
+	assert.NoError(t, handle.Close())
+
+	lines := readLines(t, path)
+	if assert.Len(t, lines, 1) {
+		assert.Equal(t, "connecting", lines[0]["msg"])
+		assert.Equal(t, "postgres://orders:xxxxx@db:5432/orders", lines[0]["conn_string"])
+		assert.Equal(t, Redacted, lines[0]["token"])
+	}
+
+	_, _, err = New(&config.Log{Output: filepath.Join(t.TempDir(), "missing", "orders-manager.log")})
+	assert.Error(t, err)
+}
+
+func TestNew_Defaults(t *testing.T) {
+	_, handle, err := New(nil)
+	assert.NoError(t, err)
+	assert.True(t, handle.ToStdout())
+	assert.Equal(t, DefaultLevel, handle.Level())
+	assert.NoError(t, handle.Close())
+}
+
+func TestRedactDSN(t *testing.T) {
+	for dsn, want := range map[string]string{
+		"postgres://orders:s3cret@db:5432/orders?sslmode=disable": "postgres://orders:xxxxx@db:5432/orders?sslmode=disable",
+		"postgres://db/orders?password=s3cret&user=orders":        "postgres://db/orders?password=xxxxx&user=orders",
+		"postgres://orders@db/orders":                             "postgres://orders@db/orders",
+		"host=db user=orders password=s3cret dbname=orders":       "host=db user=orders password=xxxxx dbname=orders",
+		"host=db password = 'with space' dbname=orders":           "host=db password = xxxxx dbname=orders",
+		"postgres://orders:s3cret@db:port/orders":                 Redacted,
+	} {
+		assert.Equal(t, want, RedactDSN(dsn), dsn)
+	}
+}
@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"encoding/json"
+	"sync/atomic"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+// Payloads selects calls whose payloads are logged and masks their fields, see config.LogPayloads.
+type Payloads struct {
+	settings atomic.Pointer[payloadSettings]
+}
+
+type payloadSettings struct {
+	methods map[string]bool
+	masked  map[string]bool
+}
+
+// NewPayloads creates Payloads, nil conf logs no payloads until Update.
+func NewPayloads(conf *config.LogPayloads) *Payloads {
+	payloads := &Payloads{}
+	payloads.Update(conf)
+
+	return payloads
+}
+
+// Update replaces settings, it takes effect from the next call.
+func (p *Payloads) Update(conf *config.LogPayloads) {
+	if conf == nil {
+		conf = &config.LogPayloads{}
+	}
+	settings := &payloadSettings{
+		methods: make(map[string]bool, len(conf.Methods)),
+		masked:  make(map[string]bool, len(conf.MaskedFields)),
+	}
+	for _, method := range conf.Methods {
+		settings.methods[method] = true
+	}
+	for _, field := range conf.MaskedFields {
+		settings.masked[field] = true
+	}
+	p.settings.Store(settings)
+}
+
+// Logged tells whether payloads of full grpc method name are logged.
+func (p *Payloads) Logged(method string) bool {
+	return p.settings.Load().methods[method]
+}
+
+// Mask returns message as JSON with values of masked fields replaced at any depth.
+// Fields are named like in the proto.
+func (p *Payloads) Mask(message proto.Message) string {
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(message)
+	if err != nil {
+		return Redacted
+	}
+	var payload any
+	if err = json.Unmarshal(data, &payload); err != nil {
+		return Redacted
+	}
+	masked, err := json.Marshal(mask(payload, p.settings.Load().masked))
+	if err != nil {
+		return Redacted
+	}
+
+	return string(masked)
+}
+
+func mask(value any, masked map[string]bool) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if masked[key] {
+				value[key] = Redacted
+			} else {
+				value[key] = mask(field, masked)
+			}
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = mask(item, masked)
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,31 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	"github.com/Sugar-pack/orders-manager/pkg/pb"
+)
+
+func TestPayloads(t *testing.T) {
+	payloads := NewPayloads(nil)
+	assert.False(t, payloads.Logged("/pb.OrdersManagerService/InsertOrder"))
+
+	payloads.Update(&config.LogPayloads{
+		Methods:      []string{"/pb.OrdersManagerService/InsertOrder"},
+		MaskedFields: []string{"user_id"},
+	})
+	assert.True(t, payloads.Logged("/pb.OrdersManagerService/InsertOrder"))
+	assert.False(t, payloads.Logged("/pb.OrdersManagerService/GetOrder"))
+
+	order := &pb.Order{UserId: "3f1c", Label: "label", CreatedAt: timestamppb.New(time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))}
+	assert.JSONEq(t, `{"user_id":"xxxxx","label":"label","created_at":"2026-10-19T12:00:00Z"}`, payloads.Mask(order))
+
+	batch := &pb.BatchGetOrdersResponse{Orders: []*pb.OrderResponse{{Id: "1", UserId: "3f1c"}}, MissingIds: []string{"2"}}
+	assert.JSONEq(t, `{"orders":[{"id":"1","user_id":"xxxxx"}],"missing_ids":["2"]}`, payloads.Mask(batch),
+		"nested fields are masked")
+}
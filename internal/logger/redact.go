@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redacted replaces values which must not be logged.
+const Redacted = "xxxxx"
+
+// dsnFields are fields holding connection strings, only their passwords are redacted.
+var dsnFields = map[string]bool{
+	"conn_string": true,
+	"dsn":         true,
+}
+
+// secretFields are fields redacted as a whole.
+var secretFields = map[string]bool{
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+	"authorization": true,
+}
+
+// passwordPattern matches password of key/value connection strings, quoted or not.
+var passwordPattern = regexp.MustCompile(`(?i)(\bpassword\s*=\s*)('(?:\\.|[^'])*'|\S+)`)
+
+// RedactDSN returns connection string in URL or key/value form without password.
+func RedactDSN(dsn string) string {
+	if !strings.Contains(dsn, "://") {
+		return passwordPattern.ReplaceAllString(dsn, "${1}"+Redacted)
+	}
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		// url.Parse errors quote the input
+		return Redacted
+	}
+	query := parsed.Query()
+	for key := range query {
+		if strings.EqualFold(key, "password") {
+			query.Set(key, Redacted)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.Redacted()
+}
+
+// RedactHook redacts fields holding credentials, so they are never logged whoever logs them.
+type RedactHook struct{}
+
+// Levels implements logrus.Hook.
+func (RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook.
+func (RedactHook) Fire(entry *logrus.Entry) error {
+	for key, value := range entry.Data {
+		name := strings.ToLower(key)
+		switch {
+		case dsnFields[name]:
+			if dsn, ok := value.(string); ok {
+				entry.Data[key] = RedactDSN(dsn)
+			} else {
+				entry.Data[key] = Redacted
+			}
+		case secretFields[name]:
+			entry.Data[key] = Redacted
+		}
+	}
+
+	return nil
+}
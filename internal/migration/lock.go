@@ -0,0 +1,135 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+// DefaultLockTimeout is used when config.DB.MigrationLockTimeout is not set.
+const DefaultLockTimeout = time.Minute
+
+// ErrLockTimeout is returned when migration lock is not acquired within the lock timeout.
+var ErrLockTimeout = errors.New("migration lock wait timed out")
+
+// lockPollInterval is a pause between attempts to take the lock held by another replica.
+var lockPollInterval = time.Second
+
+// lockKey derives advisory lock key from migration table, so services sharing a DB
+// with different migration tables do not block each other.
+func lockKey(table string) int64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte("orders-manager/migrations/" + table))
+
+	return int64(hash.Sum64()) //nolint:gosec // any 64 bits make a key
+}
+
+func lockTimeout(conf *config.DB) time.Duration {
+	if conf.MigrationLockTimeout > 0 {
+		return conf.MigrationLockTimeout
+	}
+
+	return DefaultLockTimeout
+}
+
+// withLock runs fn holding session advisory lock on a dedicated connection.
+// fn itself runs on other connections of dbConn pool.
+func withLock(ctx context.Context, conf *config.DB, dbConn *sql.DB, fn func() error) error {
+	logger := logging.FromContext(ctx)
+	key := lockKey(conf.MigrationTable)
+
+	conn, err := dbConn.Conn(ctx)
+	if err != nil {
+		logger.WithError(err).Error("get connection for migration lock failed")
+
+		return fmt.Errorf("get connection for migration lock failed: %w", err)
+	}
+	defer func() {
+		if closeErr := conn.Close(); closeErr != nil {
+			logger.WithError(closeErr).Error("closing migration lock connection failed")
+		}
+	}()
+
+	started := time.Now()
+	if err = acquireLock(ctx, conn, key, lockTimeout(conf)); err != nil {
+		logger.WithError(err).Error("acquire migration lock failed")
+
+		return err
+	}
+	hostname, _ := os.Hostname()
+	logger.WithField("host", hostname).WithField("waited", time.Since(started).String()).
+		Info("migration lock acquired")
+	defer func() {
+		// session lock is released with the connection anyway, unlock to return it to the pool clean
+		if _, unlockErr := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", key); unlockErr != nil {
+			logger.WithError(unlockErr).Error("release migration lock failed")
+		}
+	}()
+
+	return fn()
+}
+
+// acquireLock polls pg_try_advisory_lock until it succeeds or timeout passes.
+func acquireLock(ctx context.Context, conn *sql.Conn, key int64, timeout time.Duration) error {
+	logger := logging.FromContext(ctx)
+	deadline := time.Now().Add(timeout)
+	waitLogged := false
+	for {
+		var locked bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+			return fmt.Errorf("try migration lock failed: %w", err)
+		}
+		if locked {
+			return nil
+		}
+		if !waitLogged {
+			holder := lockHolder(ctx, conn, key)
+			logger.WithField("holder", holder).WithField("timeout", timeout.String()).
+				Info("migrations are run by another replica, waiting for them to finish")
+			waitLogged = true
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%w after %s", ErrLockTimeout, timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err() //nolint:wrapcheck
+		case <-time.After(min(lockPollInterval, remaining)):
+		}
+	}
+}
+
+// lockIDs splits key the way pg_locks shows advisory locks taken with a bigint key:
+// classid holds high and objid low 32 bits, both unsigned whatever the sign of key.
+func lockIDs(key int64) (uint32, uint32) {
+	return uint32(uint64(key) >> 32), uint32(key) //nolint:gosec // halves of key
+}
+
+// lockHolder describes a session holding the lock, for logs only.
+func lockHolder(ctx context.Context, conn *sql.Conn, key int64) string {
+	const query = `SELECT a.pid, a.application_name, COALESCE(host(a.client_addr), 'local')
+FROM pg_locks l JOIN pg_stat_activity a ON a.pid = l.pid
+WHERE l.locktype = 'advisory' AND l.granted AND l.objsubid = 1
+  AND l.classid = $1::bigint::oid AND l.objid = $2::bigint::oid
+LIMIT 1`
+	var (
+		pid       int
+		app, addr string
+	)
+	classID, objID := lockIDs(key)
+	if err := conn.QueryRowContext(ctx, query, int64(classID), int64(objID)).Scan(&pid, &app, &addr); err != nil {
+		return "unknown"
+	}
+
+	return fmt.Sprintf("pid=%d application=%q addr=%s", pid, app, addr)
+}
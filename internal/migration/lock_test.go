@@ -0,0 +1,73 @@
+package migration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockKey(t *testing.T) {
+	assert.Equal(t, lockKey("migrations"), lockKey("migrations"))
+	assert.NotEqual(t, lockKey("migrations"), lockKey("other_migrations"))
+}
+
+func TestLockIDs(t *testing.T) {
+	classID, objID := lockIDs(-2)
+	assert.Equal(t, uint32(0xffffffff), classID, "high bits of negative key are unsigned")
+	assert.Equal(t, uint32(0xfffffffe), objID)
+	classID, objID = lockIDs(1<<32 | 7)
+	assert.Equal(t, uint32(1), classID)
+	assert.Equal(t, uint32(7), objID)
+}
+
+func TestAcquireLock(t *testing.T) {
+	lockPollInterval = time.Millisecond
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer sqlDB.Close()
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	key := lockKey("migrations")
+	classID, objID := lockIDs(key)
+
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+	mock.ExpectQuery("FROM pg_locks").WithArgs(int64(classID), int64(objID)).
+		WillReturnRows(sqlmock.NewRows([]string{"pid", "application_name", "addr"}).AddRow(42, "orders", "10.0.0.2"))
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+
+	assert.NoError(t, acquireLock(context.Background(), conn, key, time.Second))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcquireLock_Timeout(t *testing.T) {
+	lockPollInterval = time.Millisecond
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer sqlDB.Close()
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	key := lockKey("migrations")
+
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectQuery("FROM pg_locks").WillReturnError(assert.AnError)
+	for range 1000 {
+		mock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(key).
+			WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+	}
+
+	err = acquireLock(context.Background(), conn, key, 5*time.Millisecond)
+	assert.ErrorIs(t, err, ErrLockTimeout)
+}
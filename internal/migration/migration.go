@@ -2,53 +2,322 @@ package migration
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/Sugar-pack/users-manager/pkg/logging"
 	migrate "github.com/rubenv/sql-migrate"
 
 	"github.com/Sugar-pack/orders-manager/internal/config"
 	"github.com/Sugar-pack/orders-manager/internal/db"
+	"github.com/Sugar-pack/orders-manager/internal/retry"
+	sqlmigrations "github.com/Sugar-pack/orders-manager/sql-migrations"
 )
 
-// Apply applies database migrations.
+const dialect = "postgres"
+
+// SourceDir is a directory of the source tree the embedded migrations come from.
+const SourceDir = "sql-migrations"
+
+var (
+	// ErrInvalidName is returned by New for names which are not usable as a part of file name.
+	ErrInvalidName = errors.New("migration name must consist of letters, digits, '_' and '-'")
+	// ErrInvalidCount is returned for non-positive number of migrations to roll back.
+	ErrInvalidCount = errors.New("number of migrations must be positive")
+	// ErrUnknownMigrations is returned by Verify when DB has migrations the source does not have.
+	ErrUnknownMigrations = errors.New("applied migrations are missing in migration source")
+	// ErrPendingMigrations is returned by Verify when some migrations are not applied.
+	ErrPendingMigrations = errors.New("migrations are not applied")
+
+	namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// Status describes a migration known from migration dir or migration table.
+type Status struct {
+	ID        string
+	AppliedAt *time.Time // nil for pending migrations
+	Missing   bool       // applied, but not found in migration dir
+}
+
+// Apply applies all pending database migrations.
+// Runs failed because DB is unreachable are retried according to conf.ConnectRetry.
 func Apply(ctx context.Context, conf *config.DB) error {
+	return retry.Do(ctx, retry.NewPolicy(conf.ConnectRetry), "apply migrations", func(ctx context.Context) error { //nolint:wrapcheck
+		_, err := Up(ctx, conf, 0)
+		if err != nil && !db.IsTransient(ctx, err) {
+			return retry.Permanent(err)
+		}
+
+		return err
+	})
+}
+
+// Up applies at most limit pending migrations, limit 0 applies all of them.
+func Up(ctx context.Context, conf *config.DB, limit int) (int, error) {
+	applied, err := execLocked(ctx, conf, migrate.Up, limit)
+	logger := logging.FromContext(ctx).WithField("migrations", strings.Join(applied, ","))
+	if err != nil {
+		logger.WithError(err).Error("apply migration failed")
+
+		return len(applied), err
+	}
+	if len(applied) == 0 {
+		logger.Info("schema is up to date, no migrations applied")
+	} else {
+		logger.WithField("count", len(applied)).Info("migrations applied")
+	}
+
+	return len(applied), nil
+}
+
+// Down rolls back limit last applied migrations.
+func Down(ctx context.Context, conf *config.DB, limit int) (int, error) {
+	if limit <= 0 {
+		return 0, ErrInvalidCount
+	}
+	rolledBack, err := execLocked(ctx, conf, migrate.Down, limit)
+	logger := logging.FromContext(ctx).WithField("migrations", strings.Join(rolledBack, ","))
+	if err != nil {
+		logger.WithError(err).Error("rollback migration failed")
+
+		return len(rolledBack), err
+	}
+	logger.WithField("count", len(rolledBack)).Info("migrations rolled back")
+
+	return len(rolledBack), nil
+}
+
+// Redo rolls back the last applied migration and applies it again.
+// It returns ID of the migration, empty ID means there is nothing to redo.
+func Redo(ctx context.Context, conf *config.DB) (string, error) {
 	logger := logging.FromContext(ctx)
+	var migrationID string
+	err := withLockedDB(ctx, conf, func(dbConn *sql.DB) error {
+		set := migrationSet(conf)
+		planned, _, planErr := set.PlanMigration(dbConn, dialect, source(conf), migrate.Down, 1)
+		if planErr != nil {
+			return planErr //nolint:wrapcheck
+		}
+		if len(planned) == 0 {
+			return nil
+		}
+		migrationID = planned[0].Id
+		if _, execErr := set.ExecMaxContext(ctx, dbConn, dialect, source(conf), migrate.Down, 1); execErr != nil {
+			return execErr //nolint:wrapcheck
+		}
+		_, execErr := set.ExecMaxContext(ctx, dbConn, dialect, source(conf), migrate.Up, 1)
 
-	dbConn, err := db.Connect(ctx, conf)
+		return execErr //nolint:wrapcheck
+	})
 	if err != nil {
-		logger.WithError(err).Error("db connect failed")
+		logger.WithError(err).WithField("migration", migrationID).Error("redo migration failed")
 
-		return fmt.Errorf("db connect failed: %w", err)
+		return migrationID, err
 	}
-	defer func() {
-		clErr := dbConn.Close()
-		if clErr != nil {
-			logger.WithError(clErr).Error("closing db connection is failed")
+	logger.WithField("migration", migrationID).Info("migration redone")
+
+	return migrationID, nil
+}
+
+// execLocked runs at most limit migrations in direction under migration lock.
+// It returns IDs of the migrations which were run.
+func execLocked(ctx context.Context, conf *config.DB, direction migrate.MigrationDirection, limit int) ([]string, error) {
+	var executed []string
+	err := withLockedDB(ctx, conf, func(dbConn *sql.DB) error {
+		set := migrationSet(conf)
+		// plan under the lock, another replica may have applied migrations while we waited
+		planned, _, err := set.PlanMigration(dbConn, dialect, source(conf), direction, limit)
+		if err != nil {
+			return err //nolint:wrapcheck
 		}
-	}()
+		if len(planned) == 0 {
+			return nil
+		}
+		ids := make([]string, 0, len(planned))
+		for _, migration := range planned {
+			ids = append(ids, migration.Id)
+		}
+		logging.FromContext(ctx).WithField("migrations", strings.Join(ids, ",")).Trace("running migrations")
+		count, err := set.ExecMaxContext(ctx, dbConn, dialect, source(conf), direction, limit)
+		executed = ids[:min(count, len(ids))]
+
+		return err //nolint:wrapcheck
+	})
+
+	return executed, err
+}
 
-	logger.Trace("applying migrations")
-	migrationDirection := migrate.Up
-	migrationCount := -1
-	migrate.SetTable(conf.MigrationTable)
-	count, err := migrate.ExecMax(dbConn.DB, "postgres",
-		&migrate.FileMigrationSource{Dir: conf.MigrationDirPath},
-		migrationDirection, migrationCount,
-	)
+// GetStatus lists migrations from migration dir together with applied ones.
+func GetStatus(ctx context.Context, conf *config.DB) ([]Status, error) {
+	migrations, err := source(conf).FindMigrations()
 	if err != nil {
-		logger.WithError(err).Error("apply migration failed")
+		return nil, fmt.Errorf("find migrations failed: %w", err)
+	}
+	var records []*migrate.MigrationRecord
+	err = withDB(ctx, conf, func(dbConn *sql.DB) error {
+		var recordsErr error
+		records, recordsErr = migrationSet(conf).GetMigrationRecords(dbConn, dialect)
 
-		return err
+		return recordsErr //nolint:wrapcheck
+	})
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("get migration records failed")
+
+		return nil, err
 	}
 
-	logger.WithField("count", count).Info("migrations applied")
+	return mergeStatus(migrations, records), nil
+}
 
-	if err = db.Disconnect(ctx, dbConn); err != nil {
-		logger.WithError(err).Error("disconnect failed")
+// Verify checks that migrations applied to DB match the migration source.
+func Verify(ctx context.Context, conf *config.DB) error {
+	statuses, err := GetStatus(ctx, conf)
+	if err != nil {
+		return err
+	}
+	if err = checkStatus(statuses); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("migrations do not match")
 
-		return fmt.Errorf("disconnect failed: %w", err)
+		return err
 	}
 
 	return nil
 }
+
+func checkStatus(statuses []Status) error {
+	var unknown, pending []string
+	for _, status := range statuses {
+		switch {
+		case status.Missing:
+			unknown = append(unknown, status.ID)
+		case status.AppliedAt == nil:
+			pending = append(pending, status.ID)
+		}
+	}
+	var errs []error
+	if len(unknown) > 0 {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrUnknownMigrations, strings.Join(unknown, ", ")))
+	}
+	if len(pending) > 0 {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrPendingMigrations, strings.Join(pending, ", ")))
+	}
+
+	return errors.Join(errs...)
+}
+
+func mergeStatus(migrations []*migrate.Migration, records []*migrate.MigrationRecord) []Status {
+	applied := make(map[string]time.Time, len(records))
+	for _, record := range records {
+		applied[record.Id] = record.AppliedAt
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		status := Status{ID: m.Id}
+		if appliedAt, ok := applied[m.Id]; ok {
+			status.AppliedAt = &appliedAt
+			delete(applied, m.Id)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range records {
+		if _, ok := applied[record.Id]; ok {
+			appliedAt := record.AppliedAt
+			statuses = append(statuses, Status{ID: record.Id, AppliedAt: &appliedAt, Missing: true})
+		}
+	}
+
+	return statuses
+}
+
+// New creates an empty migration file in dir and returns its path.
+// File name is prefixed with "v" and creation time, so new migrations sort after
+// the initial non-numeric one and in creation order among themselves.
+func New(dir, name string, now time.Time) (string, error) {
+	if !namePattern.MatchString(name) {
+		return "", ErrInvalidName
+	}
+	path := filepath.Join(dir, fmt.Sprintf("v%s-%s.sql", now.UTC().Format("20060102150405"), name))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644) //nolint:gosec // migrations are not secret
+	if err != nil {
+		return "", fmt.Errorf("create migration file failed: %w", err)
+	}
+	_, err = file.WriteString(template)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("write migration file failed: %w", err)
+	}
+
+	return path, nil
+}
+
+const template = `-- +migrate Up
+-- +migrate StatementBegin
+
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+
+-- +migrate StatementEnd
+`
+
+func migrationSet(conf *config.DB) migrate.MigrationSet {
+	return migrate.MigrationSet{TableName: conf.MigrationTable}
+}
+
+// source returns migrations from conf.MigrationDirPath, or embedded ones when it is not set.
+func source(conf *config.DB) migrate.MigrationSource {
+	if conf.MigrationDirPath == "" {
+		return &migrate.EmbedFileSystemMigrationSource{FileSystem: sqlmigrations.FS, Root: "."}
+	}
+
+	return &migrate.FileMigrationSource{Dir: conf.MigrationDirPath}
+}
+
+// migrationMaxOpenConns lets migrations run next to the connection holding the migration lock.
+const migrationMaxOpenConns = 2
+
+func withLockedDB(ctx context.Context, conf *config.DB, fn func(dbConn *sql.DB) error) error {
+	return withDB(ctx, conf, func(dbConn *sql.DB) error {
+		if conf.MaxOpenConns > 0 && conf.MaxOpenConns < migrationMaxOpenConns {
+			dbConn.SetMaxOpenConns(migrationMaxOpenConns)
+		}
+
+		return withLock(ctx, conf, dbConn, func() error {
+			return fn(dbConn)
+		})
+	})
+}
+
+// withDB connects without retries, Apply retries the whole run instead.
+func withDB(ctx context.Context, conf *config.DB, fn func(dbConn *sql.DB) error) error {
+	logger := logging.FromContext(ctx)
+
+	dbConn, err := db.Open(ctx, conf)
+	if err != nil {
+		logger.WithError(err).Error("db connect failed")
+
+		return fmt.Errorf("db connect failed: %w", err)
+	}
+	defer func() {
+		if disconnectErr := db.Disconnect(ctx, dbConn); disconnectErr != nil {
+			logger.WithError(disconnectErr).Error("disconnect failed")
+		}
+	}()
+	if err = dbConn.PingContext(ctx); err != nil {
+		logger.WithError(err).Error("db connect failed")
+
+		return fmt.Errorf("db connect failed: %w", err)
+	}
+
+	return fn(dbConn.DB)
+}
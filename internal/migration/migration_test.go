@@ -1,8 +1,15 @@
 package migration
 
 import (
+	"bytes"
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	migrate "github.com/rubenv/sql-migrate"
+	"github.com/stretchr/testify/assert"
 
 	"github.com/Sugar-pack/orders-manager/internal/config"
 )
@@ -15,3 +22,74 @@ func TestApply_ConnectError(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+func TestDown_InvalidCount(t *testing.T) {
+	_, err := Down(context.Background(), &config.DB{ConnString: "invalid"}, 0)
+	assert.ErrorIs(t, err, ErrInvalidCount)
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 19, 12, 30, 0, 0, time.UTC)
+
+	path, err := New(dir, "add_orders_index", now)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	assert.Equal(t, filepath.Join(dir, "v20261019123000-add_orders_index.sql"), path)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read migration: %v", err)
+	}
+	migration, err := migrate.ParseMigration(filepath.Base(path), bytes.NewReader(content))
+	assert.NoError(t, err, "template must be a valid migration")
+	assert.False(t, migration.Less(&migrate.Migration{Id: "init_orders_db.sql"}), "new migration must sort after initial one")
+
+	_, err = New(dir, "add_orders_index", now)
+	assert.Error(t, err, "existing file must not be overwritten")
+
+	_, err = New(dir, "../escape", now)
+	assert.ErrorIs(t, err, ErrInvalidName)
+}
+
+func TestMergeStatus(t *testing.T) {
+	applied := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	migrations := []*migrate.Migration{{Id: "init_orders_db.sql"}, {Id: "v20261019123000-next.sql"}}
+	records := []*migrate.MigrationRecord{
+		{Id: "init_orders_db.sql", AppliedAt: applied},
+		{Id: "removed.sql", AppliedAt: applied},
+	}
+
+	assert.Equal(t, []Status{
+		{ID: "init_orders_db.sql", AppliedAt: &applied},
+		{ID: "v20261019123000-next.sql"},
+		{ID: "removed.sql", AppliedAt: &applied, Missing: true},
+	}, mergeStatus(migrations, records))
+}
+
+func TestSource_Embedded(t *testing.T) {
+	embedded, err := source(&config.DB{}).FindMigrations()
+	if err != nil {
+		t.Fatalf("find embedded migrations: %v", err)
+	}
+	onDisk, err := source(&config.DB{MigrationDirPath: "../../" + SourceDir}).FindMigrations()
+	if err != nil {
+		t.Fatalf("find migrations on disk: %v", err)
+	}
+	assert.NotEmpty(t, embedded)
+	assert.Equal(t, onDisk, embedded)
+}
+
+func TestCheckStatus(t *testing.T) {
+	applied := time.Now()
+	assert.NoError(t, checkStatus([]Status{{ID: "a", AppliedAt: &applied}}))
+
+	err := checkStatus([]Status{
+		{ID: "a", AppliedAt: &applied},
+		{ID: "b"},
+		{ID: "c", AppliedAt: &applied, Missing: true},
+	})
+	assert.ErrorIs(t, err, ErrPendingMigrations)
+	assert.ErrorIs(t, err, ErrUnknownMigrations)
+	assert.ErrorContains(t, err, "b")
+}
@@ -55,6 +55,52 @@ func (_m *OrderRepoWith2PC) GetOrder(ctx context.Context, id uuid.UUID) (*reposi
 	return r0, r1
 }
 
+// GetOrders provides a mock function with given fields: ctx, ids
+func (_m *OrderRepoWith2PC) GetOrders(ctx context.Context, ids []uuid.UUID) ([]*repository.Order, error) {
+	ret := _m.Called(ctx, ids)
+
+	var r0 []*repository.Order
+	if rf, ok := ret.Get(0).(func(context.Context, []uuid.UUID) []*repository.Order); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*repository.Order)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []uuid.UUID) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListOrders provides a mock function with given fields: ctx, userID, after, limit
+func (_m *OrderRepoWith2PC) ListOrders(ctx context.Context, userID uuid.UUID, after repository.OrderCursor, limit int) ([]*repository.Order, error) {
+	ret := _m.Called(ctx, userID, after, limit)
+
+	var r0 []*repository.Order
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, repository.OrderCursor, int) []*repository.Order); ok {
+		r0 = rf(ctx, userID, after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*repository.Order)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, repository.OrderCursor, int) error); ok {
+		r1 = rf(ctx, userID, after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // PrepareInsertOrder provides a mock function with given fields: ctx, order, txId
 func (_m *OrderRepoWith2PC) PrepareInsertOrder(ctx context.Context, order *repository.Order, txId uuid.UUID) error {
 	ret := _m.Called(ctx, order, txId)
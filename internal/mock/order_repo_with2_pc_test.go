@@ -18,9 +18,11 @@ func TestOrderRepoWith2PC_Methods(t *testing.T) {
 	m.On("CommitInsertTransaction", ctx, id).Return(nil)
 	m.On("RollbackInsertTransaction", ctx, id).Return(nil)
 	m.On("GetOrder", ctx, id).Return(&repository.Order{}, nil)
+	m.On("GetOrders", ctx, []uuid.UUID{id}).Return([]*repository.Order{}, nil)
 
 	_ = m.PrepareInsertOrder(ctx, order, id)
 	_ = m.CommitInsertTransaction(ctx, id)
 	_ = m.RollbackInsertTransaction(ctx, id)
 	_, _ = m.GetOrder(ctx, id)
+	_, _ = m.GetOrders(ctx, []uuid.UUID{id})
 }
@@ -0,0 +1,348 @@
+// Package partition maintains monthly partitions of the orders table.
+package partition
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/jackc/pgconn"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+const (
+	// DefaultCheckInterval is used when config.Partitions.CheckInterval is not set.
+	DefaultCheckInterval = time.Hour
+	// DefaultLockTimeout is used when config.Partitions.LockTimeout is not set.
+	DefaultLockTimeout = time.Second
+
+	table            = "orders"
+	defaultPartition = "orders_default"
+	namePrefix       = "orders_p"
+	nameLayout       = "200601"
+
+	lockNotAvailable = "55P03"
+)
+
+// ErrPartitionLocked is returned when a partition can not be detached because of locks held on orders.
+var ErrPartitionLocked = errors.New("partition is locked")
+
+// Month is the first moment of a month in UTC, orders of the month go to partition Month.Name.
+type Month time.Time
+
+// MonthOf returns month t belongs to.
+func MonthOf(t time.Time) Month {
+	t = t.UTC()
+
+	return Month(time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC))
+}
+
+// Add returns month n months later.
+func (m Month) Add(n int) Month {
+	return Month(time.Time(m).AddDate(0, n, 0))
+}
+
+// Name returns name of the partition of m.
+func (m Month) Name() string {
+	return namePrefix + time.Time(m).Format(nameLayout)
+}
+
+func (m Month) bound() string {
+	return time.Time(m).Format(time.DateOnly)
+}
+
+// parseName returns month of partition name, false for partitions not made by Manager.
+func parseName(name string) (Month, bool) {
+	suffix, ok := strings.CutPrefix(name, namePrefix)
+	if !ok {
+		return Month{}, false
+	}
+	t, err := time.Parse(nameLayout, suffix)
+	if err != nil {
+		return Month{}, false
+	}
+
+	return Month(t), true
+}
+
+// actions is what a maintenance run does.
+type actions struct {
+	Create []Month // missing partitions from the current month up to premake months ahead
+	Expire []Month // partitions older than retention
+}
+
+// plan compares existing partitions with settings.
+func plan(existing []Month, now time.Time, conf *config.Partitions) actions {
+	exists := make(map[Month]bool, len(existing))
+	for _, month := range existing {
+		exists[month] = true
+	}
+	current := MonthOf(now)
+
+	var p actions
+	for i := 0; i <= conf.Premake; i++ {
+		if month := current.Add(i); !exists[month] {
+			p.Create = append(p.Create, month)
+		}
+	}
+	if conf.Retention > 0 {
+		oldest := current.Add(-conf.Retention)
+		for _, month := range existing {
+			if time.Time(month).Before(time.Time(oldest)) {
+				p.Expire = append(p.Expire, month)
+			}
+		}
+		sort.Slice(p.Expire, func(i, j int) bool { return time.Time(p.Expire[i]).Before(time.Time(p.Expire[j])) })
+	}
+
+	return p
+}
+
+// Manager periodically creates partitions ahead of time and expires old ones.
+// Replicas sharing a DB take turns, a run is skipped while another replica does one.
+type Manager struct {
+	db       *sqlx.DB
+	now      func() time.Time
+	settings atomic.Pointer[config.Partitions]
+}
+
+// NewManager creates Manager of orders partitions in db. Nil or disabled conf makes runs no-op until Update.
+func NewManager(db *sqlx.DB, conf *config.Partitions) *Manager {
+	manager := &Manager{db: db, now: time.Now}
+	manager.Update(conf)
+
+	return manager
+}
+
+// Update replaces settings, they take effect from the next run.
+func (m *Manager) Update(conf *config.Partitions) {
+	if conf == nil {
+		conf = &config.Partitions{}
+	}
+	m.settings.Store(conf)
+}
+
+func (m *Manager) checkInterval() time.Duration {
+	if interval := m.settings.Load().CheckInterval; interval > 0 {
+		return interval
+	}
+
+	return DefaultCheckInterval
+}
+
+// Run maintains partitions at once and then every check interval until ctx is done.
+func (m *Manager) Run(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			if err := m.RunOnce(ctx); err != nil {
+				logger.WithError(err).Error("partition maintenance failed")
+			}
+			timer.Reset(m.checkInterval())
+		}
+	}
+}
+
+// lockKey is the advisory lock held during a run, it is shared by all replicas.
+func lockKey() int64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte("orders-manager/partitions/" + table))
+
+	return int64(hash.Sum64()) //nolint:gosec // any 64 bits make a key
+}
+
+// RunOnce creates and expires partitions according to settings.
+// A partition which can not be changed, e.g. because of locks held by prepared transactions,
+// is skipped till the next run, the error is returned after the others are done.
+func (m *Manager) RunOnce(ctx context.Context) error {
+	conf := m.settings.Load()
+	if !conf.Enabled {
+		return nil
+	}
+	logger := logging.FromContext(ctx)
+
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection failed: %w", err)
+	}
+	defer func() {
+		if closeErr := conn.Close(); closeErr != nil {
+			logger.WithError(closeErr).Error("closing partition maintenance connection failed")
+		}
+	}()
+
+	var locked bool
+	if err = conn.QueryRowxContext(ctx, "SELECT pg_try_advisory_lock($1)", lockKey()).Scan(&locked); err != nil {
+		return fmt.Errorf("try partition lock failed: %w", err)
+	}
+	if !locked {
+		logger.Debug("partitions are maintained by another replica")
+
+		return nil
+	}
+	defer func() {
+		if _, unlockErr := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockKey()); unlockErr != nil {
+			logger.WithError(unlockErr).Error("release partition lock failed")
+		}
+	}()
+
+	existing, err := listPartitions(ctx, conn)
+	if err != nil {
+		return err
+	}
+	p := plan(existing, m.now(), conf)
+
+	var errs []error
+	for _, month := range p.Create {
+		monthLogger := logger.WithField("partition", month.Name())
+		if err = inTx(ctx, conn, conf, func(tx *sqlx.Tx) error { return createPartition(ctx, tx, month) }); err != nil {
+			monthLogger.WithError(err).Warn("create partition failed, retrying on the next run")
+			errs = append(errs, fmt.Errorf("create %s: %w", month.Name(), err))
+
+			continue
+		}
+		monthLogger.Info("partition created")
+	}
+	for _, month := range p.Expire {
+		monthLogger := logger.WithField("partition", month.Name()).WithField("policy", conf.RetentionPolicy)
+		if err = inTx(ctx, conn, conf, func(tx *sqlx.Tx) error { return expirePartition(ctx, tx, month, conf) }); err != nil {
+			if errors.Is(err, ErrPartitionLocked) {
+				monthLogger.WithError(err).Warn("partition is locked, confirm or roll back its prepared transactions")
+			} else {
+				monthLogger.WithError(err).Warn("expire partition failed, retrying on the next run")
+			}
+			errs = append(errs, fmt.Errorf("expire %s: %w", month.Name(), err))
+
+			continue
+		}
+		monthLogger.Info("partition expired")
+	}
+
+	return errors.Join(errs...)
+}
+
+// listPartitions returns months of attached partitions made by Manager or by the migration.
+func listPartitions(ctx context.Context, conn *sqlx.Conn) ([]Month, error) {
+	var names []string
+	err := conn.SelectContext(ctx, &names, `SELECT c.relname FROM pg_inherits i
+JOIN pg_class c ON c.oid = i.inhrelid
+WHERE i.inhparent = $1::regclass`, table)
+	if err != nil {
+		return nil, fmt.Errorf("list partitions failed: %w", err)
+	}
+	months := make([]Month, 0, len(names))
+	for _, name := range names {
+		if month, ok := parseName(name); ok {
+			months = append(months, month)
+		}
+	}
+
+	return months, nil
+}
+
+// inTx runs fn in a transaction which gives up waiting for locks after the lock timeout,
+// so DDL does not queue every call behind locks of prepared transactions.
+func inTx(ctx context.Context, conn *sqlx.Conn, conf *config.Partitions, fn func(tx *sqlx.Tx) error) error {
+	timeout := conf.LockTimeout
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL lock_timeout = %d", timeout.Milliseconds())); err == nil {
+		err = fn(tx)
+	}
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			err = errors.Join(err, rollbackErr)
+		}
+
+		return err
+	}
+
+	return tx.Commit() //nolint:wrapcheck
+}
+
+// createPartition creates partition of month. Orders of the month already stored in the default
+// partition are moved to the new one, otherwise it can not be attached.
+func createPartition(ctx context.Context, tx *sqlx.Tx, month Month) error {
+	name := month.Name()
+	statements := []string{
+		fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS)", name, table),
+		fmt.Sprintf(`WITH moved AS (
+    DELETE FROM %s WHERE created_at >= '%s' AND created_at < '%s' RETURNING *
+) INSERT INTO %s SELECT * FROM moved`, defaultPartition, month.bound(), month.Add(1).bound(), name),
+		fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')",
+			table, name, month.bound(), month.Add(1).bound()),
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	return nil
+}
+
+// expirePartition detaches partition of month and archives it when the policy says so.
+// DETACH PARTITION CONCURRENTLY is refused for tables with a default partition, so the plain
+// DETACH takes ACCESS EXCLUSIVE lock on orders and every call waits while it queues for the lock.
+// It is not even tried while prepared transactions hold locks on orders or the partition,
+// and waiting longer than the lock timeout is reported as ErrPartitionLocked.
+func expirePartition(ctx context.Context, tx *sqlx.Tx, month Month, conf *config.Partitions) error {
+	name := month.Name()
+	var holders []string
+	err := tx.SelectContext(ctx, &holders, `SELECT DISTINCT px.gid FROM pg_locks l
+JOIN pg_prepared_xacts px ON l.virtualtransaction = '-1/' || px.transaction::text
+WHERE l.locktype = 'relation' AND l.relation IN ($1::regclass, $2::regclass)
+ORDER BY 1`, table, name)
+	if err != nil {
+		return fmt.Errorf("list lock holders failed: %w", err)
+	}
+	if len(holders) > 0 {
+		return fmt.Errorf("%w: prepared transactions %s hold locks on %s",
+			ErrPartitionLocked, strings.Join(holders, ", "), table)
+	}
+
+	statements := []string{fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s", table, name)}
+	if conf.RetentionPolicy == config.RetentionArchive {
+		schema := quoteIdent(conf.ArchiveSchema)
+		statements = append(statements,
+			"CREATE SCHEMA IF NOT EXISTS "+schema,
+			fmt.Sprintf("ALTER TABLE %s SET SCHEMA %s", name, schema),
+		)
+	}
+	for _, statement := range statements {
+		if _, err = tx.ExecContext(ctx, statement); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == lockNotAvailable {
+				return fmt.Errorf("%w: %w", ErrPartitionLocked, err)
+			}
+
+			return err //nolint:wrapcheck
+		}
+	}
+
+	return nil
+}
+
+// quoteIdent quotes identifier from config.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,175 @@
+package partition
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+func month(year int, m time.Month) Month {
+	return Month(time.Date(year, m, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func TestMonth(t *testing.T) {
+	assert.Equal(t, month(2026, time.October), MonthOf(time.Date(2026, 10, 31, 23, 59, 0, 0, time.UTC)))
+	assert.Equal(t, month(2026, time.November), MonthOf(time.Date(2026, 10, 31, 23, 0, 0, 0, time.FixedZone("X", -2*3600))))
+	assert.Equal(t, month(2027, time.January), month(2026, time.December).Add(1))
+	assert.Equal(t, "orders_p202610", month(2026, time.October).Name())
+
+	parsed, ok := parseName("orders_p202610")
+	assert.True(t, ok)
+	assert.Equal(t, month(2026, time.October), parsed)
+	for _, name := range []string{"orders_default", "orders_p2026", "archive_p202610"} {
+		_, ok = parseName(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	existing := []Month{month(2026, time.October), month(2026, time.July), month(2026, time.August), month(2026, time.November)}
+
+	p := plan(existing, now, &config.Partitions{Premake: 2})
+	assert.Equal(t, []Month{month(2026, time.December)}, p.Create)
+	assert.Empty(t, p.Expire, "retention 0 keeps all")
+
+	p = plan(existing, now, &config.Partitions{Premake: 3, Retention: 2, RetentionPolicy: config.RetentionDetach})
+	assert.Equal(t, []Month{month(2026, time.December), month(2027, time.January)}, p.Create)
+	assert.Equal(t, []Month{month(2026, time.July)}, p.Expire, "August is kept with retention of 2 months")
+}
+
+func newManager(t *testing.T, conf *config.Partitions) (*Manager, sqlmock.Sqlmock) {
+	t.Helper()
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	manager := NewManager(sqlx.NewDb(db, "sqlmock"), conf)
+	manager.now = func() time.Time { return time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC) }
+
+	return manager, dbMock
+}
+
+func expectLock(dbMock sqlmock.Sqlmock, locked bool) {
+	dbMock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(lockKey()).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(locked))
+}
+
+func expectHolders(dbMock sqlmock.Sqlmock, partition string, gids ...string) {
+	rows := sqlmock.NewRows([]string{"gid"})
+	for _, gid := range gids {
+		rows.AddRow(gid)
+	}
+	dbMock.ExpectQuery("SELECT DISTINCT px.gid FROM pg_locks").WithArgs("orders", partition).WillReturnRows(rows)
+}
+
+func TestManager_RunOnce(t *testing.T) {
+	manager, dbMock := newManager(t, &config.Partitions{
+		Enabled: true, Premake: 1, Retention: 1, RetentionPolicy: config.RetentionArchive, ArchiveSchema: "archive",
+	})
+
+	expectLock(dbMock, true)
+	dbMock.ExpectQuery("SELECT c.relname FROM pg_inherits").WithArgs("orders").
+		WillReturnRows(sqlmock.NewRows([]string{"relname"}).
+			AddRow("orders_default").AddRow("orders_p202608").AddRow("orders_p202609").AddRow("orders_p202610"))
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec("SET LOCAL lock_timeout = 1000").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec("CREATE TABLE orders_p202611 \\(LIKE orders").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec("DELETE FROM orders_default WHERE created_at >= '2026-11-01' AND created_at < '2026-12-01'").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	dbMock.ExpectExec("ALTER TABLE orders ATTACH PARTITION orders_p202611 FOR VALUES FROM \\('2026-11-01'\\) TO \\('2026-12-01'\\)").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectCommit()
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec("SET LOCAL lock_timeout").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectHolders(dbMock, "orders_p202608")
+	dbMock.ExpectExec("ALTER TABLE orders DETACH PARTITION orders_p202608").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec(`CREATE SCHEMA IF NOT EXISTS "archive"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec(`ALTER TABLE orders_p202608 SET SCHEMA "archive"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectCommit()
+
+	dbMock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(lockKey()).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, manager.RunOnce(context.Background()))
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestManager_RunOnce_LockTimeout(t *testing.T) {
+	manager, dbMock := newManager(t, &config.Partitions{Enabled: true, Premake: 1, LockTimeout: 200 * time.Millisecond})
+	lockErr := errors.New("canceling statement due to lock timeout")
+
+	expectLock(dbMock, true)
+	dbMock.ExpectQuery("SELECT c.relname FROM pg_inherits").
+		WillReturnRows(sqlmock.NewRows([]string{"relname"}).AddRow("orders_default"))
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec("SET LOCAL lock_timeout = 200").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec("CREATE TABLE orders_p202610").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec("DELETE FROM orders_default").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec("ATTACH PARTITION orders_p202610").WillReturnError(lockErr)
+	dbMock.ExpectRollback()
+	// the next month is still created
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec("SET LOCAL lock_timeout").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec("CREATE TABLE orders_p202611").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec("DELETE FROM orders_default").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec("ATTACH PARTITION orders_p202611").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectCommit()
+	dbMock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := manager.RunOnce(context.Background())
+	assert.ErrorIs(t, err, lockErr)
+	assert.ErrorContains(t, err, "create orders_p202610")
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestManager_RunOnce_PartitionLocked(t *testing.T) {
+	manager, dbMock := newManager(t, &config.Partitions{
+		Enabled: true, Retention: 1, RetentionPolicy: config.RetentionDetach,
+	})
+
+	expectLock(dbMock, true)
+	dbMock.ExpectQuery("SELECT c.relname FROM pg_inherits").
+		WillReturnRows(sqlmock.NewRows([]string{"relname"}).
+			AddRow("orders_default").AddRow("orders_p202607").AddRow("orders_p202608").AddRow("orders_p202609").
+			AddRow("orders_p202610"))
+	// locks of prepared transactions are reported without queueing for ACCESS EXCLUSIVE
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec("SET LOCAL lock_timeout").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectHolders(dbMock, "orders_p202607", "tx-1@acme", "tx-2@acme")
+	dbMock.ExpectRollback()
+	// waiting for other locks is reported once the lock timeout passes
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec("SET LOCAL lock_timeout").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectHolders(dbMock, "orders_p202608")
+	dbMock.ExpectExec("ALTER TABLE orders DETACH PARTITION orders_p202608").
+		WillReturnError(&pgconn.PgError{Severity: "ERROR", Code: "55P03", Message: "canceling statement due to lock timeout"})
+	dbMock.ExpectRollback()
+	dbMock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := manager.RunOnce(context.Background())
+	assert.ErrorIs(t, err, ErrPartitionLocked)
+	assert.ErrorContains(t, err, "expire orders_p202607: partition is locked: prepared transactions tx-1@acme, tx-2@acme hold locks on orders")
+	assert.ErrorContains(t, err, "expire orders_p202608: partition is locked: ERROR: canceling statement due to lock timeout")
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestManager_RunOnce_Skipped(t *testing.T) {
+	manager, dbMock := newManager(t, &config.Partitions{Enabled: true})
+	expectLock(dbMock, false)
+	assert.NoError(t, manager.RunOnce(context.Background()), "another replica holds the lock")
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+
+	manager.Update(nil)
+	assert.NoError(t, manager.RunOnce(context.Background()), "disabled")
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
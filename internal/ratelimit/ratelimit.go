@@ -0,0 +1,190 @@
+// Package ratelimit limits calls with token buckets per method, per client and per user.
+package ratelimit
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+// DefaultIdleTTL is used when config.RateLimit.IdleTTL is not set.
+const DefaultIdleTTL = 10 * time.Minute
+
+// Dimension names a limit a call was rejected by.
+type Dimension string
+
+const (
+	DimensionMethod Dimension = "method"
+	DimensionClient Dimension = "client"
+	DimensionUser   Dimension = "user"
+)
+
+// Decision is a result of Limiter.Allow.
+type Decision struct {
+	Allowed    bool
+	RetryAfter time.Duration // time after which the call fits all limits
+	Dimension  Dimension     // the most restrictive exceeded limit
+}
+
+// Key identifies a call.
+type Key struct {
+	Method string
+	Client string // empty for unknown client
+	User   string // empty for calls without user_id
+}
+
+// Limiter keeps token buckets. It is safe for concurrent use.
+type Limiter struct {
+	now   func() time.Time
+	state atomic.Pointer[limiterState]
+}
+
+type limiterState struct {
+	enabled bool
+	methods map[string]*rate.Limiter
+	clients *keyedLimiter
+	users   *keyedLimiter
+}
+
+// NewLimiter creates Limiter from conf. Nil or disabled conf allows every call.
+func NewLimiter(conf *config.RateLimit) *Limiter {
+	limiter := &Limiter{now: time.Now}
+	limiter.Update(conf)
+
+	return limiter
+}
+
+// Update replaces limits with ones from conf. Buckets are refilled.
+func (l *Limiter) Update(conf *config.RateLimit) {
+	if conf == nil || !conf.Enabled {
+		l.state.Store(&limiterState{})
+
+		return
+	}
+	idleTTL := conf.IdleTTL
+	if idleTTL <= 0 {
+		idleTTL = DefaultIdleTTL
+	}
+	methods := make(map[string]*rate.Limiter, len(conf.Methods))
+	for _, rule := range conf.Methods {
+		methods[rule.Method] = newBucket(rule)
+	}
+
+	l.state.Store(&limiterState{
+		enabled: true,
+		methods: methods,
+		clients: newKeyedLimiter(conf.PerClient, idleTTL),
+		users:   newKeyedLimiter(conf.PerUser, idleTTL),
+	})
+}
+
+func newBucket(rule config.RateLimitRule) *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(rule.RPS), rule.Burst)
+}
+
+// Allow takes a token from every bucket the call belongs to.
+// Tokens are taken only when all buckets have them, a rejected call does not drain any bucket.
+func (l *Limiter) Allow(key Key) Decision {
+	state := l.state.Load()
+	if !state.enabled {
+		return Decision{Allowed: true}
+	}
+	now := l.now()
+	type candidate struct {
+		bucket    *rate.Limiter
+		dimension Dimension
+	}
+	candidates := make([]candidate, 0, 3) //nolint:mnd // number of dimensions
+	if bucket, ok := state.methods[key.Method]; ok {
+		candidates = append(candidates, candidate{bucket, DimensionMethod})
+	}
+	if bucket := state.clients.get(key.Client, now); bucket != nil {
+		candidates = append(candidates, candidate{bucket, DimensionClient})
+	}
+	if bucket := state.users.get(key.User, now); bucket != nil {
+		candidates = append(candidates, candidate{bucket, DimensionUser})
+	}
+
+	decision := Decision{Allowed: true}
+	reservations := make([]*rate.Reservation, 0, len(candidates))
+	for _, c := range candidates {
+		reservation := c.bucket.ReserveN(now, 1)
+		reservations = append(reservations, reservation)
+		if !reservation.OK() {
+			// burst is zero, the call can never pass
+			decision = Decision{Dimension: c.dimension, RetryAfter: rate.InfDuration}
+
+			continue
+		}
+		if delay := reservation.DelayFrom(now); delay > 0 && (decision.Allowed || delay > decision.RetryAfter) {
+			decision = Decision{Dimension: c.dimension, RetryAfter: delay}
+		}
+	}
+	if !decision.Allowed {
+		for _, reservation := range reservations {
+			reservation.CancelAt(now)
+		}
+	}
+
+	return decision
+}
+
+// keyedLimiter keeps a bucket per key and drops buckets idle for longer than ttl.
+type keyedLimiter struct {
+	rule *config.RateLimitRule
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*keyedBucket
+	sweptAt time.Time
+}
+
+type keyedBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newKeyedLimiter(rule *config.RateLimitRule, ttl time.Duration) *keyedLimiter {
+	return &keyedLimiter{
+		rule:    rule,
+		ttl:     ttl,
+		buckets: make(map[string]*keyedBucket),
+	}
+}
+
+func (k *keyedLimiter) get(key string, now time.Time) *rate.Limiter {
+	if k.rule == nil || key == "" {
+		return nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if now.Sub(k.sweptAt) >= k.ttl {
+		for bucketKey, bucket := range k.buckets {
+			if now.Sub(bucket.lastSeen) >= k.ttl {
+				delete(k.buckets, bucketKey)
+			}
+		}
+		k.sweptAt = now
+	}
+
+	bucket, ok := k.buckets[key]
+	if !ok {
+		bucket = &keyedBucket{limiter: newBucket(*k.rule)}
+		k.buckets[key] = bucket
+	}
+	bucket.lastSeen = now
+
+	return bucket.limiter
+}
+
+func (k *keyedLimiter) size() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return len(k.buckets)
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+const insertMethod = "/pb.OrdersManagerService/InsertOrder"
+
+func newTestLimiter(conf *config.RateLimit) (*Limiter, *time.Time) {
+	limiter := NewLimiter(conf)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	return limiter, &now
+}
+
+func TestLimiter_Method(t *testing.T) {
+	limiter, now := newTestLimiter(&config.RateLimit{
+		Enabled: true,
+		Methods: []config.RateLimitRule{{Method: insertMethod, RPS: 1, Burst: 2}},
+	})
+	key := Key{Method: insertMethod}
+
+	assert.True(t, limiter.Allow(key).Allowed)
+	assert.True(t, limiter.Allow(key).Allowed)
+	decision := limiter.Allow(key)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, DimensionMethod, decision.Dimension)
+	assert.Equal(t, time.Second, decision.RetryAfter)
+
+	assert.True(t, limiter.Allow(Key{Method: "/pb.OrdersManagerService/GetOrder"}).Allowed, "other methods are not limited")
+
+	*now = now.Add(time.Second)
+	assert.True(t, limiter.Allow(key).Allowed)
+}
+
+func TestLimiter_PerClientAndUser(t *testing.T) {
+	limiter, _ := newTestLimiter(&config.RateLimit{
+		Enabled:   true,
+		PerClient: &config.RateLimitRule{RPS: 1, Burst: 3},
+		PerUser:   &config.RateLimitRule{RPS: 1, Burst: 1},
+	})
+
+	assert.True(t, limiter.Allow(Key{Client: "a", User: "u1"}).Allowed)
+	decision := limiter.Allow(Key{Client: "a", User: "u1"})
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, DimensionUser, decision.Dimension)
+
+	assert.True(t, limiter.Allow(Key{Client: "a", User: "u2"}).Allowed)
+	assert.True(t, limiter.Allow(Key{Client: "b", User: "u3"}).Allowed, "clients have separate buckets")
+
+	// client "a" has one token left, it must not be spent by calls rejected by user limit
+	assert.False(t, limiter.Allow(Key{Client: "a", User: "u2"}).Allowed)
+	assert.True(t, limiter.Allow(Key{Client: "a", User: "u4"}).Allowed)
+	decision = limiter.Allow(Key{Client: "a", User: "u5"})
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, DimensionClient, decision.Dimension)
+
+	assert.True(t, limiter.Allow(Key{}).Allowed, "calls without client and user are not limited")
+}
+
+func TestLimiter_ZeroBurst(t *testing.T) {
+	limiter, _ := newTestLimiter(&config.RateLimit{
+		Enabled: true,
+		Methods: []config.RateLimitRule{{Method: insertMethod, RPS: 1, Burst: 0}},
+	})
+
+	decision := limiter.Allow(Key{Method: insertMethod})
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, DimensionMethod, decision.Dimension)
+}
+
+func TestLimiter_IdleBucketsDropped(t *testing.T) {
+	limiter, now := newTestLimiter(&config.RateLimit{
+		Enabled:   true,
+		PerClient: &config.RateLimitRule{RPS: 1, Burst: 1},
+		IdleTTL:   time.Minute,
+	})
+
+	limiter.Allow(Key{Client: "a"})
+	limiter.Allow(Key{Client: "b"})
+	assert.Equal(t, 2, limiter.state.Load().clients.size())
+
+	*now = now.Add(2 * time.Minute)
+	limiter.Allow(Key{Client: "c"})
+	assert.Equal(t, 1, limiter.state.Load().clients.size())
+}
+
+func TestLimiter_Update(t *testing.T) {
+	limiter, _ := newTestLimiter(nil)
+	key := Key{Method: insertMethod}
+	for i := 0; i < 10; i++ {
+		assert.True(t, limiter.Allow(key).Allowed, "disabled limiter allows everything")
+	}
+
+	limiter.Update(&config.RateLimit{
+		Enabled: true,
+		Methods: []config.RateLimitRule{{Method: insertMethod, RPS: 1, Burst: 1}},
+	})
+	assert.True(t, limiter.Allow(key).Allowed)
+	assert.False(t, limiter.Allow(key).Allowed)
+
+	limiter.Update(&config.RateLimit{Enabled: false})
+	assert.True(t, limiter.Allow(key).Allowed)
+}
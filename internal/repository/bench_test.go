@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	"github.com/Sugar-pack/orders-manager/internal/db"
+	"github.com/Sugar-pack/orders-manager/internal/migration"
+)
+
+// benchDBEnv points benchmarks to an existing Postgres with max_prepared_transactions > 0,
+// otherwise they start one in docker.
+const benchDBEnv = "ORDERS_MANAGER_BENCH_DB"
+
+type benchRepo struct {
+	name string
+	repo OrderRepoWith2PC
+}
+
+// benchRepos returns both repository implementations over the same migrated DB.
+func benchRepos(b *testing.B) []benchRepo {
+	b.Helper()
+	ctx := context.Background()
+	conf := &config.DB{
+		ConnString:     os.Getenv(benchDBEnv),
+		MigrationTable: "migrations",
+		MaxOpenConns:   20,
+	}
+	if conf.ConnString == "" {
+		conf.ConnString = startPostgres(b)
+	}
+	if err := migration.Apply(ctx, conf); err != nil {
+		b.Fatalf("apply migrations: %v", err)
+	}
+
+	sqlxDB, err := db.Connect(ctx, conf)
+	if err != nil {
+		b.Fatalf("connect: %v", err)
+	}
+	b.Cleanup(func() { _ = sqlxDB.Close() })
+	pool, err := db.OpenPool(ctx, conf)
+	if err != nil {
+		b.Fatalf("open pool: %v", err)
+	}
+	b.Cleanup(pool.Close)
+	if err = db.PingPool(ctx, pool, conf); err != nil {
+		b.Fatalf("ping pool: %v", err)
+	}
+
+	return []benchRepo{
+		{"sqlx", NewPsqlRepository(sqlxDB)},
+		{"pgxpool", NewPgxRepository(pool)},
+	}
+}
+
+func startPostgres(b *testing.B) string {
+	b.Helper()
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		b.Skipf("Could not connect to docker: %s", err)
+	}
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "postgres",
+		Tag:        "15.13",
+		Env:        []string{"POSTGRES_USER=bench", "POSTGRES_DB=orders", "POSTGRES_HOST_AUTH_METHOD=trust"},
+		Cmd:        []string{"postgres", "--max_prepared_transactions=100"},
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		b.Skipf("Could not start resource: %s", err)
+	}
+	b.Cleanup(func() { _ = pool.Purge(resource) })
+
+	host, port, err := net.SplitHostPort(resource.GetHostPort("5432/tcp"))
+	if err != nil {
+		b.Fatalf("split host-port: %v", err)
+	}
+
+	return fmt.Sprintf("host=%s port=%s user=bench dbname=orders sslmode=disable", host, port)
+}
+
+func newBenchOrder() *Order {
+	return &Order{ID: uuid.New(), UserID: uuid.New(), Label: "bench", CreatedAt: time.Now().UTC()}
+}
+
+// BenchmarkPrepareInsertOrder measures the prepare phase of 2PC, commits are not timed.
+func BenchmarkPrepareInsertOrder(b *testing.B) {
+	ctx := context.Background()
+	for _, bench := range benchRepos(b) {
+		repo := bench.repo
+		b.Run(bench.name, func(b *testing.B) {
+			for range b.N {
+				txID := uuid.New()
+				if err := repo.PrepareInsertOrder(ctx, newBenchOrder(), txID); err != nil {
+					b.Fatalf("prepare: %v", err)
+				}
+				b.StopTimer()
+				if err := repo.CommitInsertTransaction(ctx, txID); err != nil {
+					b.Fatalf("commit: %v", err)
+				}
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+func BenchmarkGetOrder(b *testing.B) {
+	ctx := context.Background()
+	for _, bench := range benchRepos(b) {
+		repo := bench.repo
+		order := newBenchOrder()
+		txID := uuid.New()
+		if err := repo.PrepareInsertOrder(ctx, order, txID); err != nil {
+			b.Fatalf("prepare: %v", err)
+		}
+		if err := repo.CommitInsertTransaction(ctx, txID); err != nil {
+			b.Fatalf("commit: %v", err)
+		}
+		b.Run(bench.name, func(b *testing.B) {
+			for range b.N {
+				if _, err := repo.GetOrder(ctx, order.ID); err != nil {
+					b.Fatalf("get: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// OrderFilter selects orders streamed by StreamOrders.
+type OrderFilter struct {
+	UserID uuid.UUID // uuid.Nil matches orders of every user
+	From   time.Time // created_at lower bound, inclusive, zero leaves it open
+	To     time.Time // created_at upper bound, exclusive, zero leaves it open
+}
+
+// where returns condition of the filter and its arguments.
+func (f OrderFilter) where() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if f.UserID != uuid.Nil {
+		args = append(args, f.UserID.String())
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if !f.From.IsZero() {
+		args = append(args, f.From.UTC())
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !f.To.IsZero() {
+		args = append(args, f.To.UTC())
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// StreamOrders calls fn for every order of the tenant from ctx matching filter, oldest first.
+// Orders are fetched through a server-side cursor batchSize at a time, so memory stays flat on large tables.
+func StreamOrders(ctx context.Context, dbConn *sqlx.DB, filter OrderFilter, batchSize int, fn func(order *Order) error,
+) error {
+	where, args := filter.where()
+
+	return inTenantTx(ctx, dbConn, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, "DECLARE orders_export NO SCROLL CURSOR FOR "+
+			"SELECT id, user_id, label, created_at, tenant_id FROM orders"+where+" ORDER BY created_at, id", args...)
+		if err != nil {
+			return err
+		}
+		fetch := fmt.Sprintf("FETCH FORWARD %d FROM orders_export", batchSize)
+		for {
+			fetched, fetchErr := fetchOrders(ctx, tx, fetch, fn)
+			if fetchErr != nil {
+				return fetchErr
+			}
+			if fetched < batchSize {
+				break
+			}
+		}
+		_, err = tx.ExecContext(ctx, "CLOSE orders_export")
+
+		return err
+	})
+}
+
+// fetchOrders runs fetch and passes fetched orders to fn.
+func fetchOrders(ctx context.Context, tx *sqlx.Tx, fetch string, fn func(order *Order) error) (int, error) {
+	rows, err := tx.QueryxContext(ctx, fetch)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	fetched := 0
+	for rows.Next() {
+		var order Order
+		if err = rows.StructScan(&order); err != nil {
+			return fetched, err
+		}
+		if err = fn(&order); err != nil {
+			return fetched, err
+		}
+		fetched++
+	}
+
+	return fetched, rows.Err()
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamOrders(t *testing.T) {
+	dbConn, dbMock := newMock(t)
+	first := &Order{ID: uuid.New(), UserID: uuid.New(), Label: "first", CreatedAt: time.Now().UTC()}
+	second := &Order{ID: uuid.New(), UserID: first.UserID, Label: "second", CreatedAt: time.Now().UTC()}
+	from := time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	dbMock.ExpectBegin()
+	expectTenant(dbMock, "acme")
+	dbMock.ExpectExec("DECLARE orders_export NO SCROLL CURSOR FOR SELECT (.+) FROM orders "+
+		"WHERE user_id = \\$1 AND created_at >= \\$2 AND created_at < \\$3 ORDER BY created_at, id").
+		WithArgs(first.UserID.String(), from, to).WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectQuery("FETCH FORWARD 1 FROM orders_export").WillReturnRows(orderRows(first))
+	dbMock.ExpectQuery("FETCH FORWARD 1 FROM orders_export").WillReturnRows(orderRows(second))
+	dbMock.ExpectQuery("FETCH FORWARD 1 FROM orders_export").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	dbMock.ExpectExec("CLOSE orders_export").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectCommit()
+
+	var labels []string
+	err := StreamOrders(WithTenant(context.Background(), "acme"), dbConn,
+		OrderFilter{UserID: first.UserID, From: from, To: to}, 1, func(order *Order) error {
+			labels = append(labels, order.Label)
+
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, labels)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestStreamOrders_CallbackError(t *testing.T) {
+	dbConn, dbMock := newMock(t)
+	order := &Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+
+	dbMock.ExpectBegin()
+	expectTenant(dbMock, "")
+	dbMock.ExpectExec("DECLARE orders_export NO SCROLL CURSOR FOR SELECT (.+) FROM orders ORDER BY").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectQuery("FETCH FORWARD 100 FROM orders_export").WillReturnRows(orderRows(order))
+	dbMock.ExpectRollback()
+
+	writeErr := errors.New("disk full")
+	err := StreamOrders(context.Background(), dbConn, OrderFilter{}, 100, func(*Order) error { return writeErr })
+	assert.ErrorIs(t, err, writeErr)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
@@ -0,0 +1,357 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Sugar-pack/orders-manager/internal/tracing"
+)
+
+const (
+	insertOrderStatement = "orders_manager_insert_order"
+	// insertOrderSQL records the transaction with the order, see CommittedOrder
+	insertOrderSQL = "WITH tx AS (INSERT INTO order_transactions (tx_id, order_id) VALUES ($5, $1)) " +
+		"INSERT INTO orders (id, user_id, label, created_at) VALUES ($1, $2, $3, $4)"
+	// preparedResult is the result of PREPARE TRANSACTION in the batch of PrepareInsertOrder
+	preparedResult = 3
+
+	committedOrderSQL = "SELECT order_id FROM order_transactions WHERE tx_id = $1"
+
+	importCopySQL   = "COPY orders_import (id, user_id, label, created_at) FROM STDIN (FORMAT csv)"
+	importInsertSQL = "INSERT INTO orders (id, user_id, label, created_at) " +
+		"SELECT id, user_id, label, created_at FROM orders_import ON CONFLICT DO NOTHING"
+)
+
+// PgxRepository implements OrderRepoWith2PC on native pgx pool.
+// PrepareInsertOrder sends BEGIN, tenant setting, INSERT and PREPARE TRANSACTION in a single round trip.
+type PgxRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPgxRepository(pool *pgxpool.Pool) *PgxRepository {
+	return &PgxRepository{pool: pool}
+}
+
+func (p *PgxRepository) PrepareInsertOrder(ctx context.Context, order *Order, txID uuid.UUID) (err error) {
+	prepareSQL := fmt.Sprintf("PREPARE TRANSACTION '%s'", preparedGID(ctx, txID))
+	traceparent, tracestate, traced := preparedTrace(ctx)
+	ctx, span := startSpan(ctx, "PrepareInsertOrder", insertOrderSQL+"; "+prepareSQL)
+	defer func() { endSpan(span, err) }()
+
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	defer conn.Release()
+
+	// statement is prepared once per connection, Prepare returns the cached one later
+	stmt, err := conn.Conn().Prepare(ctx, insertOrderStatement, insertOrderSQL)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	params, err := encodeOrder(conn.Conn().ConnInfo(), order)
+	if err != nil {
+		return err
+	}
+	params = append(params, []byte(txID.String()))
+
+	batch := &pgconn.Batch{}
+	batch.ExecParams("BEGIN", nil, nil, nil, nil)
+	batch.ExecParams(setTenantSQL, [][]byte{[]byte(TenantFromContext(ctx))}, nil, nil, nil)
+	batch.ExecPrepared(stmt.Name, params, nil, nil)
+	batch.ExecParams(prepareSQL, nil, nil, nil, nil)
+	if traced {
+		batch.ExecParams(insertTraceSQL,
+			[][]byte{[]byte(preparedGID(ctx, txID)), []byte(traceparent), []byte(tracestate)}, nil, nil, nil)
+	}
+	pgConn := conn.Conn().PgConn()
+	results, err := pgConn.ExecBatch(ctx, batch).ReadAll()
+	if err != nil && len(results) > preparedResult && results[preparedResult].Err == nil {
+		// the transaction is prepared already, it is not failed for the sake of tracing
+		logging.FromContext(ctx).WithError(err).Warn("store trace context of prepared transaction failed")
+
+		return nil
+	}
+	if err != nil && pgConn.TxStatus() != 'I' && !pgConn.IsClosed() {
+		// failed statement leaves the explicit transaction aborted, it is not prepared
+		if _, rollbackErr := pgConn.Exec(ctx, "ROLLBACK").ReadAll(); rollbackErr != nil {
+			err = errors.Join(err, rollbackErr)
+		}
+	}
+
+	return hideOtherTenantOrder(err)
+}
+
+// encodeOrder encodes insertOrderSQL params in text format the same way pgx encodes them.
+func encodeOrder(connInfo *pgtype.ConnInfo, order *Order) ([][]byte, error) {
+	values := []struct {
+		value   pgtype.TextEncoder
+		setFrom any
+	}{
+		{&pgtype.UUID{}, [16]byte(order.ID)},
+		{&pgtype.UUID{}, [16]byte(order.UserID)},
+		{&pgtype.Text{}, order.Label},
+		{&pgtype.Timestamp{}, order.CreatedAt},
+	}
+	params := make([][]byte, 0, len(values))
+	for _, v := range values {
+		if err := v.value.(pgtype.Value).Set(v.setFrom); err != nil {
+			return nil, fmt.Errorf("encode order: %w", err)
+		}
+		param, err := v.value.EncodeText(connInfo, nil)
+		if err != nil {
+			return nil, fmt.Errorf("encode order: %w", err)
+		}
+		params = append(params, param)
+	}
+
+	return params, nil
+}
+
+// ImportOrders stores committed orders bypassing two-phase commit and returns how many were inserted.
+// Orders are copied into a temporary table with COPY and moved to orders in the same transaction,
+// orders already stored are skipped, so importing them again is harmless.
+func (p *PgxRepository) ImportOrders(ctx context.Context, orders []*Order) (inserted int64, err error) {
+	ctx, span := startSpan(ctx, "ImportOrders", importCopySQL)
+	defer func() { endSpan(span, err) }()
+
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+	defer conn.Release()
+
+	var data bytes.Buffer
+	writer := csv.NewWriter(&data)
+	for _, order := range orders {
+		params, encodeErr := encodeOrder(conn.Conn().ConnInfo(), order)
+		if encodeErr != nil {
+			return 0, encodeErr
+		}
+		_ = writer.Write([]string{string(params[0]), string(params[1]), string(params[2]), string(params[3])})
+	}
+	writer.Flush()
+
+	pgConn := conn.Conn().PgConn()
+	defer func() {
+		if err != nil && pgConn.TxStatus() != 'I' && !pgConn.IsClosed() {
+			if _, rollbackErr := pgConn.Exec(ctx, "ROLLBACK").ReadAll(); rollbackErr != nil {
+				err = errors.Join(err, rollbackErr)
+			}
+		}
+	}()
+	batch := &pgconn.Batch{}
+	batch.ExecParams("BEGIN", nil, nil, nil, nil)
+	batch.ExecParams(setTenantSQL, [][]byte{[]byte(TenantFromContext(ctx))}, nil, nil, nil)
+	// defaults fill tenant_id from the tenant setting
+	batch.ExecParams("CREATE TEMP TABLE orders_import (LIKE orders INCLUDING DEFAULTS) ON COMMIT DROP", nil, nil, nil, nil)
+	if _, err = pgConn.ExecBatch(ctx, batch).ReadAll(); err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+	if _, err = pgConn.CopyFrom(ctx, &data, importCopySQL); err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+	results, err := pgConn.Exec(ctx, importInsertSQL+"; COMMIT").ReadAll()
+	if err != nil {
+		return 0, hideOtherTenantOrder(err)
+	}
+
+	return results[0].CommandTag.RowsAffected(), nil
+}
+
+func (p *PgxRepository) CommitInsertTransaction(ctx context.Context, txID uuid.UUID) (err error) {
+	query := fmt.Sprintf("COMMIT PREPARED '%s'", preparedGID(ctx, txID))
+	// the caller's span is linked to the trace which prepared the transaction
+	defer func() { finishPreparedTrace(ctx, txID, err == nil, p.queryRow(ctx)) }()
+	spanCtx, span := startSpan(ctx, "CommitInsertTransaction", query)
+	defer func() { endSpan(span, err) }()
+	_, err = p.pool.Exec(spanCtx, query)
+
+	return err //nolint:wrapcheck
+}
+
+func (p *PgxRepository) RollbackInsertTransaction(ctx context.Context, txID uuid.UUID) (err error) {
+	query := fmt.Sprintf("ROLLBACK PREPARED '%s'", preparedGID(ctx, txID))
+	// the caller's span is linked to the trace which prepared the transaction
+	defer func() { finishPreparedTrace(ctx, txID, err == nil, p.queryRow(ctx)) }()
+	spanCtx, span := startSpan(ctx, "RollbackInsertTransaction", query)
+	defer func() { endSpan(span, err) }()
+	_, err = p.pool.Exec(spanCtx, query)
+
+	return err //nolint:wrapcheck
+}
+
+func (p *PgxRepository) CommittedOrder(ctx context.Context, txID uuid.UUID) (_ uuid.UUID, err error) {
+	ctx, span := startSpan(ctx, "CommittedOrder", committedOrderSQL)
+	defer func() { endSpan(span, err) }()
+
+	var orderID uuid.UUID
+	err = p.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, setErr := tx.Exec(ctx, setTenantSQL, TenantFromContext(ctx)); setErr != nil {
+			return setErr //nolint:wrapcheck
+		}
+
+		return tx.QueryRow(ctx, committedOrderSQL, txID).Scan(&orderID) //nolint:wrapcheck
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = sql.ErrNoRows
+	}
+
+	return orderID, err
+}
+
+func (p *PgxRepository) queryRow(ctx context.Context) func(query string, args ...any) rowScanner {
+	return func(query string, args ...any) rowScanner {
+		return p.pool.QueryRow(ctx, query, args...)
+	}
+}
+
+func (p *PgxRepository) GetOrder(ctx context.Context, id uuid.UUID) (_ *Order, err error) {
+	const query = "SELECT id, user_id, label, created_at, tenant_id FROM orders WHERE id = $1"
+	ctx, span := startSpan(ctx, "GetOrder", query)
+	defer func() { endSpan(span, err) }()
+
+	var order Order
+	err = p.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, setErr := tx.Exec(ctx, setTenantSQL, TenantFromContext(ctx)); setErr != nil {
+			return setErr //nolint:wrapcheck
+		}
+
+		return tx.QueryRow(ctx, query, id).Scan(&order.ID, &order.UserID, &order.Label, &order.CreatedAt, &order.TenantID) //nolint:wrapcheck
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// keep the error callers of PsqlRepository expect
+		err = sql.ErrNoRows
+	}
+
+	return &order, err
+}
+
+func (p *PgxRepository) GetOrders(ctx context.Context, ids []uuid.UUID) (_ []*Order, err error) {
+	const query = "SELECT id, user_id, label, created_at, tenant_id FROM orders WHERE id = ANY($1)"
+	ctx, span := startSpan(ctx, "GetOrders", query)
+	defer func() { endSpan(span, err) }()
+
+	var orders []*Order
+	err = p.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, setErr := tx.Exec(ctx, setTenantSQL, TenantFromContext(ctx)); setErr != nil {
+			return setErr //nolint:wrapcheck
+		}
+		rows, queryErr := tx.Query(ctx, query, uuidStrings(ids))
+		if queryErr != nil {
+			return queryErr //nolint:wrapcheck
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var order Order
+			if scanErr := rows.Scan(&order.ID, &order.UserID, &order.Label, &order.CreatedAt, &order.TenantID); scanErr != nil {
+				return scanErr //nolint:wrapcheck
+			}
+			orders = append(orders, &order)
+		}
+
+		return rows.Err() //nolint:wrapcheck
+	})
+	if err != nil {
+		return nil, err
+	}
+	SortByIDs(orders, ids)
+
+	return orders, nil
+}
+
+func (p *PgxRepository) ListOrders(ctx context.Context, userID uuid.UUID, after OrderCursor, limit int,
+) (_ []*Order, err error) {
+	const query = `SELECT id, user_id, label, created_at, tenant_id FROM orders
+WHERE user_id = $1 AND (created_at, id) > ($2, $3) ORDER BY created_at, id LIMIT $4`
+	ctx, span := startSpan(ctx, "ListOrders", query)
+	defer func() { endSpan(span, err) }()
+
+	var orders []*Order
+	err = p.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, setErr := tx.Exec(ctx, setTenantSQL, TenantFromContext(ctx)); setErr != nil {
+			return setErr //nolint:wrapcheck
+		}
+		rows, queryErr := tx.Query(ctx, query, userID, after.CreatedAt, after.ID, limit)
+		if queryErr != nil {
+			return queryErr //nolint:wrapcheck
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var order Order
+			if scanErr := rows.Scan(&order.ID, &order.UserID, &order.Label, &order.CreatedAt, &order.TenantID); scanErr != nil {
+				return scanErr //nolint:wrapcheck
+			}
+			orders = append(orders, &order)
+		}
+
+		return rows.Err() //nolint:wrapcheck
+	})
+
+	return orders, err
+}
+
+// ListPreparedTransactions returns transactions of the current database prepared before preparedBefore.
+// Prepared transactions with gid not made by preparedGID are not created by this service and are skipped.
+func (p *PgxRepository) ListPreparedTransactions(ctx context.Context, preparedBefore time.Time,
+) (_ []PreparedTransaction, err error) {
+	const query = "SELECT gid, prepared FROM pg_prepared_xacts WHERE database = current_database() AND prepared < $1 ORDER BY prepared"
+	ctx, span := startSpan(ctx, "ListPreparedTransactions", query)
+	defer func() { endSpan(span, err) }()
+
+	rows, err := p.pool.Query(ctx, query, preparedBefore)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	defer rows.Close()
+
+	var transactions []PreparedTransaction
+	for rows.Next() {
+		var (
+			gid      string
+			prepared time.Time
+		)
+		if err = rows.Scan(&gid, &prepared); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		txID, tenant, parseErr := parsePreparedGID(gid)
+		if parseErr != nil {
+			continue
+		}
+		transactions = append(transactions, PreparedTransaction{TxID: txID, Tenant: tenant, Prepared: prepared})
+	}
+
+	return transactions, rows.Err() //nolint:wrapcheck
+}
+
+// startSpan starts client span of a DB call, like otelsql does for PsqlRepository.
+func startSpan(ctx context.Context, name, statement string) (context.Context, trace.Span) {
+	return otel.Tracer(tracing.TracerName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBStatementKey.String(statement)),
+	)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
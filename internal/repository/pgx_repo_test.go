@@ -0,0 +1,343 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgmock"
+	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// expectParse checks that the next message parses query.
+type expectParse string
+
+func (e expectParse) Step(backend *pgproto3.Backend) error {
+	msg, err := backend.Receive()
+	if err != nil {
+		return err
+	}
+	if parse, ok := msg.(*pgproto3.Parse); !ok || parse.Query != string(e) {
+		return fmt.Errorf("want parse of %q, got %#v", string(e), msg)
+	}
+
+	return nil
+}
+
+// expectBind checks that the next message binds statement.
+type expectBind string
+
+func (e expectBind) Step(backend *pgproto3.Backend) error {
+	msg, err := backend.Receive()
+	if err != nil {
+		return err
+	}
+	if bind, ok := msg.(*pgproto3.Bind); !ok || bind.PreparedStatement != string(e) {
+		return fmt.Errorf("want bind of %q, got %#v", string(e), msg)
+	}
+
+	return nil
+}
+
+// expectBindParams checks that the next message binds unnamed statement with params.
+type expectBindParams [][]byte
+
+func (e expectBindParams) Step(backend *pgproto3.Backend) error {
+	msg, err := backend.Receive()
+	if err != nil {
+		return err
+	}
+	if bind, ok := msg.(*pgproto3.Bind); !ok || bind.PreparedStatement != "" || !reflect.DeepEqual(bind.Parameters, [][]byte(e)) {
+		return fmt.Errorf("want bind of %q, got %#v", [][]byte(e), msg)
+	}
+
+	return nil
+}
+
+func expectExec(query string) []pgmock.Step {
+	return []pgmock.Step{
+		expectParse(query),
+		expectBind(""),
+		pgmock.ExpectAnyMessage(&pgproto3.Describe{}),
+		pgmock.ExpectAnyMessage(&pgproto3.Execute{}),
+	}
+}
+
+func prepareInsertSteps() []pgmock.Step {
+	return []pgmock.Step{
+		pgmock.ExpectMessage(&pgproto3.Parse{Name: insertOrderStatement, Query: insertOrderSQL}),
+		pgmock.ExpectMessage(&pgproto3.Describe{ObjectType: 'S', Name: insertOrderStatement}),
+		pgmock.ExpectAnyMessage(&pgproto3.Sync{}),
+		pgmock.SendMessage(&pgproto3.ParseComplete{}),
+		pgmock.SendMessage(&pgproto3.ParameterDescription{
+			ParameterOIDs: []uint32{pgtype.UUIDOID, pgtype.UUIDOID, pgtype.TextOID, pgtype.TimestampOID, pgtype.UUIDOID},
+		}),
+		pgmock.SendMessage(&pgproto3.NoData{}),
+		pgmock.SendMessage(&pgproto3.ReadyForQuery{TxStatus: 'I'}),
+	}
+}
+
+// batchSteps expects the whole batch before Sync, i.e. a single round trip.
+func batchSteps(gid, tenant string) []pgmock.Step {
+	steps := expectExec("BEGIN")
+	steps = append(steps,
+		expectParse(setTenantSQL),
+		expectBindParams{[]byte(tenant)},
+		pgmock.ExpectAnyMessage(&pgproto3.Describe{}),
+		pgmock.ExpectAnyMessage(&pgproto3.Execute{}),
+		expectBind(insertOrderStatement),
+		pgmock.ExpectAnyMessage(&pgproto3.Describe{}),
+		pgmock.ExpectAnyMessage(&pgproto3.Execute{}),
+	)
+	steps = append(steps, expectExec(fmt.Sprintf("PREPARE TRANSACTION '%s'", gid))...)
+
+	return append(steps, pgmock.ExpectAnyMessage(&pgproto3.Sync{}))
+}
+
+// servePgMock serves script to a single connection and returns pool connected to it.
+func servePgMock(t *testing.T, steps []pgmock.Step) (*pgxpool.Pool, <-chan error) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	script := &pgmock.Script{Steps: append(pgmock.AcceptUnauthenticatedConnRequestSteps(), steps...)}
+	script.Steps = append(script.Steps, pgmock.WaitForClose())
+	done := make(chan error, 1)
+	go func() {
+		conn, acceptErr := listener.Accept()
+		if acceptErr != nil {
+			done <- acceptErr
+
+			return
+		}
+		defer conn.Close()
+		done <- script.Run(pgproto3.NewBackend(pgproto3.NewChunkReader(conn), conn))
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	pool, err := pgxpool.Connect(context.Background(),
+		fmt.Sprintf("host=%s port=%s user=test sslmode=disable pool_max_conns=1", host, port))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	return pool, done
+}
+
+func waitScript(t *testing.T, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("script did not finish")
+	}
+}
+
+func TestPgxRepository_PrepareInsertOrder(t *testing.T) {
+	txID := uuid.New()
+	steps := prepareInsertSteps()
+	steps = append(steps, batchSteps(txID.String()+"@acme", "acme")...)
+	steps = append(steps,
+		pgmock.SendMessage(&pgproto3.ParseComplete{}),
+		pgmock.SendMessage(&pgproto3.BindComplete{}),
+		pgmock.SendMessage(&pgproto3.NoData{}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("BEGIN")}),
+		pgmock.SendMessage(&pgproto3.ParseComplete{}),
+		pgmock.SendMessage(&pgproto3.BindComplete{}),
+		pgmock.SendMessage(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+			{Name: []byte("set_config"), DataTypeOID: pgtype.TextOID, DataTypeSize: -1, TypeModifier: -1},
+		}}),
+		pgmock.SendMessage(&pgproto3.DataRow{Values: [][]byte{[]byte("acme")}}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")}),
+		pgmock.SendMessage(&pgproto3.BindComplete{}),
+		pgmock.SendMessage(&pgproto3.NoData{}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("INSERT 0 1")}),
+		pgmock.SendMessage(&pgproto3.ParseComplete{}),
+		pgmock.SendMessage(&pgproto3.BindComplete{}),
+		pgmock.SendMessage(&pgproto3.NoData{}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("PREPARE TRANSACTION")}),
+		pgmock.SendMessage(&pgproto3.ReadyForQuery{TxStatus: 'I'}),
+	)
+	pool, done := servePgMock(t, steps)
+	repo := NewPgxRepository(pool)
+	order := &Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now()}
+
+	assert.NoError(t, repo.PrepareInsertOrder(WithTenant(context.Background(), "acme"), order, txID))
+	pool.Close()
+	waitScript(t, done)
+}
+
+func TestPgxRepository_PrepareInsertOrder_TraceErr(t *testing.T) {
+	ctx, _, span := startRecordedSpan(t, sdktrace.AlwaysSample())
+	defer span.End()
+	txID := uuid.New()
+	steps := prepareInsertSteps()
+	batch := batchSteps(txID.String()+"@acme", "acme")
+	steps = append(steps, batch[:len(batch)-1]...)
+	steps = append(steps, expectExec(insertTraceSQL)...)
+	steps = append(steps,
+		pgmock.ExpectAnyMessage(&pgproto3.Sync{}),
+		pgmock.SendMessage(&pgproto3.ParseComplete{}),
+		pgmock.SendMessage(&pgproto3.BindComplete{}),
+		pgmock.SendMessage(&pgproto3.NoData{}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("BEGIN")}),
+		pgmock.SendMessage(&pgproto3.ParseComplete{}),
+		pgmock.SendMessage(&pgproto3.BindComplete{}),
+		pgmock.SendMessage(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+			{Name: []byte("set_config"), DataTypeOID: pgtype.TextOID, DataTypeSize: -1, TypeModifier: -1},
+		}}),
+		pgmock.SendMessage(&pgproto3.DataRow{Values: [][]byte{[]byte("acme")}}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")}),
+		pgmock.SendMessage(&pgproto3.BindComplete{}),
+		pgmock.SendMessage(&pgproto3.NoData{}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("INSERT 0 1")}),
+		pgmock.SendMessage(&pgproto3.ParseComplete{}),
+		pgmock.SendMessage(&pgproto3.BindComplete{}),
+		pgmock.SendMessage(&pgproto3.NoData{}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("PREPARE TRANSACTION")}),
+		pgmock.SendMessage(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "42P01", Message: "relation does not exist"}),
+		pgmock.SendMessage(&pgproto3.ReadyForQuery{TxStatus: 'I'}),
+	)
+	pool, done := servePgMock(t, steps)
+	repo := NewPgxRepository(pool)
+	order := &Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now()}
+
+	assert.NoError(t, repo.PrepareInsertOrder(ctx, order, txID), "the transaction is prepared anyway")
+	pool.Close()
+	waitScript(t, done)
+}
+
+func TestPgxRepository_PrepareInsertOrder_InsertErr(t *testing.T) {
+	txID := uuid.New()
+	steps := prepareInsertSteps()
+	steps = append(steps, batchSteps(txID.String(), "")...)
+	steps = append(steps,
+		pgmock.SendMessage(&pgproto3.ParseComplete{}),
+		pgmock.SendMessage(&pgproto3.BindComplete{}),
+		pgmock.SendMessage(&pgproto3.NoData{}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("BEGIN")}),
+		pgmock.SendMessage(&pgproto3.ParseComplete{}),
+		pgmock.SendMessage(&pgproto3.BindComplete{}),
+		pgmock.SendMessage(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+			{Name: []byte("set_config"), DataTypeOID: pgtype.TextOID, DataTypeSize: -1, TypeModifier: -1},
+		}}),
+		pgmock.SendMessage(&pgproto3.DataRow{Values: [][]byte{[]byte("acme")}}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")}),
+		pgmock.SendMessage(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "23505", Message: "duplicate key"}),
+		// the rest of the batch is skipped, explicit transaction stays aborted
+		pgmock.SendMessage(&pgproto3.ReadyForQuery{TxStatus: 'E'}),
+		pgmock.ExpectMessage(&pgproto3.Query{String: "ROLLBACK"}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("ROLLBACK")}),
+		pgmock.SendMessage(&pgproto3.ReadyForQuery{TxStatus: 'I'}),
+	)
+	pool, done := servePgMock(t, steps)
+	repo := NewPgxRepository(pool)
+	order := &Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now()}
+
+	assert.ErrorContains(t, repo.PrepareInsertOrder(context.Background(), order, txID), "duplicate key")
+	pool.Close()
+	waitScript(t, done)
+}
+
+// expectCopyData checks that the next message copies rows containing every value.
+type expectCopyData []string
+
+func (e expectCopyData) Step(backend *pgproto3.Backend) error {
+	msg, err := backend.Receive()
+	if err != nil {
+		return err
+	}
+	data, ok := msg.(*pgproto3.CopyData)
+	if !ok {
+		return fmt.Errorf("want copy data, got %#v", msg)
+	}
+	for _, value := range e {
+		if !strings.Contains(string(data.Data), value) {
+			return fmt.Errorf("want %q in copy data %q", value, data.Data)
+		}
+	}
+
+	return nil
+}
+
+func TestPgxRepository_ImportOrders(t *testing.T) {
+	order := &Order{ID: uuid.New(), UserID: uuid.New(), Label: "with, comma", CreatedAt: time.Now()}
+	steps := expectExec("BEGIN")
+	steps = append(steps,
+		expectParse(setTenantSQL),
+		expectBindParams{[]byte("acme")},
+		pgmock.ExpectAnyMessage(&pgproto3.Describe{}),
+		pgmock.ExpectAnyMessage(&pgproto3.Execute{}),
+	)
+	steps = append(steps, expectExec("CREATE TEMP TABLE orders_import (LIKE orders INCLUDING DEFAULTS) ON COMMIT DROP")...)
+	steps = append(steps,
+		pgmock.ExpectAnyMessage(&pgproto3.Sync{}),
+		pgmock.SendMessage(&pgproto3.ParseComplete{}),
+		pgmock.SendMessage(&pgproto3.BindComplete{}),
+		pgmock.SendMessage(&pgproto3.NoData{}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("BEGIN")}),
+		pgmock.SendMessage(&pgproto3.ParseComplete{}),
+		pgmock.SendMessage(&pgproto3.BindComplete{}),
+		pgmock.SendMessage(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+			{Name: []byte("set_config"), DataTypeOID: pgtype.TextOID, DataTypeSize: -1, TypeModifier: -1},
+		}}),
+		pgmock.SendMessage(&pgproto3.DataRow{Values: [][]byte{[]byte("acme")}}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")}),
+		pgmock.SendMessage(&pgproto3.ParseComplete{}),
+		pgmock.SendMessage(&pgproto3.BindComplete{}),
+		pgmock.SendMessage(&pgproto3.NoData{}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("CREATE TABLE")}),
+		pgmock.SendMessage(&pgproto3.ReadyForQuery{TxStatus: 'T'}),
+
+		pgmock.ExpectMessage(&pgproto3.Query{String: importCopySQL}),
+		pgmock.SendMessage(&pgproto3.CopyInResponse{ColumnFormatCodes: []uint16{0, 0, 0, 0}}),
+		expectCopyData{order.ID.String(), order.UserID.String(), `"with, comma"`},
+		pgmock.ExpectAnyMessage(&pgproto3.CopyDone{}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("COPY 1")}),
+		pgmock.SendMessage(&pgproto3.ReadyForQuery{TxStatus: 'T'}),
+
+		pgmock.ExpectMessage(&pgproto3.Query{String: importInsertSQL + "; COMMIT"}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("INSERT 0 1")}),
+		pgmock.SendMessage(&pgproto3.CommandComplete{CommandTag: []byte("COMMIT")}),
+		pgmock.SendMessage(&pgproto3.ReadyForQuery{TxStatus: 'I'}),
+	)
+	pool, done := servePgMock(t, steps)
+	repo := NewPgxRepository(pool)
+
+	inserted, err := repo.ImportOrders(WithTenant(context.Background(), "acme"), []*Order{order})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), inserted)
+	pool.Close()
+	waitScript(t, done)
+}
+
+func TestEncodeOrder(t *testing.T) {
+	order := &Order{
+		ID:        uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+		UserID:    uuid.MustParse("6ba7b811-9dad-11d1-80b4-00c04fd430c8"),
+		Label:     "label",
+		CreatedAt: time.Date(2026, 10, 19, 12, 30, 0, 123456789, time.FixedZone("X", 3600)),
+	}
+	params, err := encodeOrder(pgtype.NewConnInfo(), order)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{
+		[]byte("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+		[]byte("6ba7b811-9dad-11d1-80b4-00c04fd430c8"),
+		[]byte("label"),
+		// wall clock is kept like pgx does for timestamp without time zone
+		[]byte("2026-10-19 12:30:00.123456"),
+	}, params)
+}
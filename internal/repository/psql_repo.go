@@ -5,17 +5,22 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/Sugar-pack/users-manager/pkg/logging"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
 type PsqlRepository struct {
-	db *sqlx.DB
+	db     *sqlx.DB
+	router *Router
 }
 
-func NewPsqlRepository(db *sqlx.DB) *PsqlRepository {
-	return &PsqlRepository{db: db}
+// NewPsqlRepository creates repository writing to db. Reads go to replicas if any, see Router.
+func NewPsqlRepository(db *sqlx.DB, replicas ...*sqlx.DB) *PsqlRepository {
+	return &PsqlRepository{db: db, router: NewRouter(db, replicas...)}
 }
 
 func (p *PsqlRepository) PrepareInsertOrder(ctx context.Context, order *Order, txID uuid.UUID) (err error) {
@@ -34,13 +39,18 @@ func (p *PsqlRepository) PrepareInsertOrder(ctx context.Context, order *Order, t
 		}
 	}(transaction)
 
-	_, err = transaction.NamedExecContext(ctx,
-		"INSERT INTO orders ( id,  user_id, label, created_at ) VALUES (:id, :user_id, :label, :created_at)", order)
+	_, err = transaction.ExecContext(ctx, setTenantSQL, TenantFromContext(ctx))
 	if err != nil {
 		return err
 	}
 
-	_, err = transaction.ExecContext(ctx, fmt.Sprintf("PREPARE TRANSACTION '%s'", txID.String()))
+	_, err = transaction.ExecContext(ctx, insertOrderSQL,
+		order.ID.String(), order.UserID.String(), order.Label, order.CreatedAt, txID.String())
+	if err != nil {
+		return hideOtherTenantOrder(err)
+	}
+
+	_, err = transaction.ExecContext(ctx, fmt.Sprintf("PREPARE TRANSACTION '%s'", preparedGID(ctx, txID)))
 	if err != nil {
 		defer func(ctx context.Context, dbConn sqlx.ExecerContext, txID uuid.UUID) {
 			errRollBack := p.RollbackInsertTransaction(ctx, txID)
@@ -48,26 +58,161 @@ func (p *PsqlRepository) PrepareInsertOrder(ctx context.Context, order *Order, t
 				err = errRollBack
 			}
 		}(ctx, transaction, txID)
+
+		return err
+	}
+	if traceparent, tracestate, ok := preparedTrace(ctx); ok {
+		// the transaction is prepared already, it is not failed for the sake of tracing
+		if _, traceErr := p.db.ExecContext(ctx, insertTraceSQL, preparedGID(ctx, txID), traceparent, tracestate); traceErr != nil {
+			logging.FromContext(ctx).WithError(traceErr).Warn("store trace context of prepared transaction failed")
+		}
 	}
 
-	return err
+	return nil
 }
 
 func (p *PsqlRepository) CommitInsertTransaction(ctx context.Context, txID uuid.UUID) error {
-	_, err := p.db.ExecContext(ctx, fmt.Sprintf("COMMIT PREPARED '%s'", txID))
+	_, err := p.db.ExecContext(ctx, fmt.Sprintf("COMMIT PREPARED '%s'", preparedGID(ctx, txID)))
+	finishPreparedTrace(ctx, txID, err == nil, p.queryRow(ctx))
 
 	return err
 }
 
 func (p *PsqlRepository) RollbackInsertTransaction(ctx context.Context, txID uuid.UUID) error {
-	_, err := p.db.ExecContext(ctx, fmt.Sprintf("ROLLBACK PREPARED '%s'", txID))
+	_, err := p.db.ExecContext(ctx, fmt.Sprintf("ROLLBACK PREPARED '%s'", preparedGID(ctx, txID)))
+	finishPreparedTrace(ctx, txID, err == nil, p.queryRow(ctx))
 
 	return err
 }
 
+// CommittedOrder reads the primary, replicas may not have applied the commit yet.
+func (p *PsqlRepository) CommittedOrder(ctx context.Context, txID uuid.UUID) (uuid.UUID, error) {
+	var orderID uuid.UUID
+	err := inTenantTx(ctx, p.db, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &orderID, committedOrderSQL, txID.String())
+	})
+
+	return orderID, err
+}
+
+func (p *PsqlRepository) queryRow(ctx context.Context) func(query string, args ...any) rowScanner {
+	return func(query string, args ...any) rowScanner {
+		return p.db.QueryRowxContext(ctx, query, args...)
+	}
+}
+
 func (p *PsqlRepository) GetOrder(ctx context.Context, id uuid.UUID) (*Order, error) {
 	var order Order
-	err := sqlx.GetContext(ctx, p.db, &order, "SELECT * FROM orders WHERE id = $1", id.String())
+	err := p.router.Read(ctx, func(dbConn *sqlx.DB) error {
+		return inTenantTx(ctx, dbConn, func(tx *sqlx.Tx) error {
+			return tx.GetContext(ctx, &order, "SELECT * FROM orders WHERE id = $1", id.String())
+		})
+	})
 
 	return &order, err
 }
+
+func (p *PsqlRepository) GetOrders(ctx context.Context, ids []uuid.UUID) ([]*Order, error) {
+	var orders []*Order
+	err := p.router.Read(ctx, func(dbConn *sqlx.DB) error {
+		orders = orders[:0]
+
+		return inTenantTx(ctx, dbConn, func(tx *sqlx.Tx) error {
+			return tx.SelectContext(ctx, &orders, "SELECT * FROM orders WHERE id = ANY($1)", uuidArray(ids))
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	SortByIDs(orders, ids)
+
+	return orders, nil
+}
+
+// listOrdersSQL selects orders of a user after a cursor, Postgres compares uuids bytewise like OrderCursor does.
+const listOrdersSQL = `SELECT * FROM orders WHERE user_id = $1 AND (created_at, id) > ($2, $3)
+ORDER BY created_at, id LIMIT $4`
+
+func (p *PsqlRepository) ListOrders(ctx context.Context, userID uuid.UUID, after OrderCursor, limit int,
+) ([]*Order, error) {
+	var orders []*Order
+	err := p.router.Read(ctx, func(dbConn *sqlx.DB) error {
+		orders = orders[:0]
+
+		return inTenantTx(ctx, dbConn, func(tx *sqlx.Tx) error {
+			return tx.SelectContext(ctx, &orders, listOrdersSQL, userID.String(), after.CreatedAt, after.ID.String(), limit)
+		})
+	})
+
+	return orders, err
+}
+
+// uuidStrings is ids as strings, both drivers encode them as uuid[].
+func uuidStrings(ids []uuid.UUID) []string {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = id.String()
+	}
+
+	return values
+}
+
+// uuidArray is ids as a Postgres uuid[] literal, database/sql does not encode slices.
+func uuidArray(ids []uuid.UUID) string {
+	return "{" + strings.Join(uuidStrings(ids), ",") + "}"
+}
+
+// inTenantTx runs fn in a transaction seeing orders of the tenant from ctx only.
+func inTenantTx(ctx context.Context, dbConn *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+	tx, err := dbConn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, setTenantSQL, TenantFromContext(ctx)); err == nil {
+		err = fn(tx)
+	}
+	if err != nil {
+		return errors.Join(err, ignoreTxDone(tx.Rollback()))
+	}
+
+	return tx.Commit()
+}
+
+func ignoreTxDone(err error) error {
+	if errors.Is(err, sql.ErrTxDone) {
+		return nil
+	}
+
+	return err
+}
+
+// ListPreparedTransactions returns transactions of the current database prepared before preparedBefore.
+// Prepared transactions with gid not made by preparedGID are not created by this service and are skipped.
+func (p *PsqlRepository) ListPreparedTransactions(ctx context.Context, preparedBefore time.Time,
+) ([]PreparedTransaction, error) {
+	rows, err := p.db.QueryxContext(ctx,
+		"SELECT gid, prepared FROM pg_prepared_xacts WHERE database = current_database() AND prepared < $1 ORDER BY prepared",
+		preparedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []PreparedTransaction
+	for rows.Next() {
+		var (
+			gid      string
+			prepared time.Time
+		)
+		if err = rows.Scan(&gid, &prepared); err != nil {
+			return nil, err
+		}
+		txID, tenant, parseErr := parsePreparedGID(gid)
+		if parseErr != nil {
+			continue
+		}
+		transactions = append(transactions, PreparedTransaction{TxID: txID, Tenant: tenant, Prepared: prepared})
+	}
+
+	return transactions, rows.Err()
+}
@@ -2,12 +2,14 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 )
@@ -21,6 +23,27 @@ func newMock(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
 	return sqlx.NewDb(db, "sqlmock"), mock
 }
 
+// expectTenant expects the tenant of the current transaction to be set.
+func expectTenant(dbMock sqlmock.Sqlmock, tenant string) {
+	dbMock.ExpectExec("SELECT set_config\\('orders_manager.tenant_id', \\$1, true\\)").
+		WithArgs(tenant).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// expectGetOrder expects GetOrder transaction of tenant returning rows or failing with err.
+func expectGetOrder(dbMock sqlmock.Sqlmock, tenant string, rows *sqlmock.Rows, err error) {
+	dbMock.ExpectBegin()
+	expectTenant(dbMock, tenant)
+	query := dbMock.ExpectQuery("SELECT \\* FROM orders")
+	if err != nil {
+		query.WillReturnError(err)
+		dbMock.ExpectRollback()
+
+		return
+	}
+	query.WillReturnRows(rows)
+	dbMock.ExpectCommit()
+}
+
 func TestPrepareInsertOrder_OK(t *testing.T) {
 	db, mock := newMock(t)
 	repo := NewPsqlRepository(db)
@@ -29,6 +52,7 @@ func TestPrepareInsertOrder_OK(t *testing.T) {
 	txID := uuid.New()
 
 	mock.ExpectBegin()
+	expectTenant(mock, "")
 	mock.ExpectExec("INSERT INTO orders").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectExec("PREPARE TRANSACTION").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectRollback()
@@ -46,6 +70,7 @@ func TestPrepareInsertOrder_InsertErr(t *testing.T) {
 	txID := uuid.New()
 
 	mock.ExpectBegin()
+	expectTenant(mock, "")
 	mock.ExpectExec("INSERT INTO orders").WillReturnError(fmt.Errorf("insert err"))
 	mock.ExpectRollback()
 
@@ -54,6 +79,22 @@ func TestPrepareInsertOrder_InsertErr(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestPrepareInsertOrder_OtherTenantID(t *testing.T) {
+	db, mock := newMock(t)
+	repo := NewPsqlRepository(db)
+	ctx := WithTenant(context.Background(), "acme")
+	order := &Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+
+	mock.ExpectBegin()
+	expectTenant(mock, "acme")
+	mock.ExpectExec("INSERT INTO orders").WillReturnError(&pgconn.PgError{Code: "P0002", Message: "order not found"})
+	mock.ExpectRollback()
+
+	err := repo.PrepareInsertOrder(ctx, order, uuid.New())
+	assert.ErrorIs(t, err, sql.ErrNoRows, "id of another tenant is reported as a missing order")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestPrepareInsertOrder_PrepareErr(t *testing.T) {
 	db, mock := newMock(t)
 	repo := NewPsqlRepository(db)
@@ -62,9 +103,11 @@ func TestPrepareInsertOrder_PrepareErr(t *testing.T) {
 	txID := uuid.New()
 
 	mock.ExpectBegin()
+	expectTenant(mock, "")
 	mock.ExpectExec("INSERT INTO orders").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectExec("PREPARE TRANSACTION").WillReturnError(fmt.Errorf("prep err"))
 	mock.ExpectExec("ROLLBACK PREPARED").WillReturnResult(sqlmock.NewResult(1, 1))
+	expectTakeTrace(mock)
 	mock.ExpectRollback()
 
 	err := repo.PrepareInsertOrder(ctx, order, txID)
@@ -79,6 +122,7 @@ func TestCommitInsertTransaction(t *testing.T) {
 	txID := uuid.New()
 
 	mock.ExpectExec("COMMIT PREPARED").WillReturnResult(sqlmock.NewResult(1, 1))
+	expectTakeTrace(mock)
 	err := repo.CommitInsertTransaction(ctx, txID)
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
@@ -103,6 +147,7 @@ func TestRollbackInsertTransaction(t *testing.T) {
 	txID := uuid.New()
 
 	mock.ExpectExec("ROLLBACK PREPARED").WillReturnResult(sqlmock.NewResult(1, 1))
+	expectTakeTrace(mock)
 	err := repo.RollbackInsertTransaction(ctx, txID)
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
@@ -129,7 +174,7 @@ func TestGetOrder_OK(t *testing.T) {
 
 	rows := sqlmock.NewRows([]string{"id", "user_id", "label", "created_at"}).
 		AddRow(order.ID, order.UserID, order.Label, order.CreatedAt)
-	mock.ExpectQuery("SELECT").WithArgs(orderID.String()).WillReturnRows(rows)
+	expectGetOrder(mock, "", rows, nil)
 
 	res, err := repo.GetOrder(ctx, orderID)
 	assert.NoError(t, err)
@@ -146,10 +191,106 @@ func TestGetOrder_Error(t *testing.T) {
 	ctx := context.Background()
 	orderID := uuid.New()
 
-	mock.ExpectQuery("SELECT").WithArgs(orderID.String()).WillReturnError(fmt.Errorf("get err"))
+	expectGetOrder(mock, "", nil, fmt.Errorf("get err"))
 
 	res, err := repo.GetOrder(ctx, orderID)
 	assert.Error(t, err)
 	assert.NotNil(t, res)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestListPreparedTransactions(t *testing.T) {
+	db, mock := newMock(t)
+	repo := NewPsqlRepository(db)
+	ctx := context.Background()
+	txID := uuid.New()
+	before := time.Now()
+	prepared := before.Add(-time.Hour)
+
+	rows := sqlmock.NewRows([]string{"gid", "prepared"}).
+		AddRow(txID.String(), prepared).
+		AddRow("foreign-transaction", prepared)
+	mock.ExpectQuery("FROM pg_prepared_xacts").WithArgs(before).WillReturnRows(rows)
+
+	res, err := repo.ListPreparedTransactions(ctx, before)
+	assert.NoError(t, err)
+	assert.Equal(t, []PreparedTransaction{{TxID: txID, Prepared: prepared}}, res)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListPreparedTransactions_Error(t *testing.T) {
+	db, mock := newMock(t)
+	repo := NewPsqlRepository(db)
+	before := time.Now()
+
+	mock.ExpectQuery("FROM pg_prepared_xacts").WithArgs(before).WillReturnError(fmt.Errorf("list err"))
+
+	_, err := repo.ListPreparedTransactions(context.Background(), before)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetOrders(t *testing.T) {
+	db, mock := newMock(t)
+	repo := NewPsqlRepository(db)
+	first := &Order{ID: uuid.New(), UserID: uuid.New(), Label: "first", CreatedAt: time.Now().UTC()}
+	second := &Order{ID: uuid.New(), UserID: uuid.New(), Label: "second", CreatedAt: time.Now().UTC()}
+	missing := uuid.New()
+	ids := []uuid.UUID{second.ID, missing, first.ID}
+
+	mock.ExpectBegin()
+	expectTenant(mock, "")
+	mock.ExpectQuery("SELECT \\* FROM orders WHERE id = ANY\\(\\$1\\)").
+		WithArgs("{" + second.ID.String() + "," + missing.String() + "," + first.ID.String() + "}").
+		WillReturnRows(orderRows(first).AddRow(second.ID, second.UserID, second.Label, second.CreatedAt))
+	mock.ExpectCommit()
+
+	orders, err := repo.GetOrders(context.Background(), ids)
+	assert.NoError(t, err)
+	if assert.Len(t, orders, 2) {
+		assert.Equal(t, second.ID, orders[0].ID, "orders follow ids")
+		assert.Equal(t, first.ID, orders[1].ID)
+	}
+
+	mock.ExpectBegin()
+	expectTenant(mock, "")
+	mock.ExpectQuery("SELECT \\* FROM orders WHERE id = ANY").WillReturnError(fmt.Errorf("get err"))
+	mock.ExpectRollback()
+	_, err = repo.GetOrders(context.Background(), ids)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListOrders(t *testing.T) {
+	db, mock := newMock(t)
+	repo := NewPsqlRepository(db)
+	userID := uuid.New()
+	order := &Order{ID: uuid.New(), UserID: userID, Label: "first", CreatedAt: time.Now().UTC()}
+	after := OrderCursor{CreatedAt: order.CreatedAt.Add(-time.Hour), ID: uuid.New()}
+
+	mock.ExpectBegin()
+	expectTenant(mock, "")
+	mock.ExpectQuery("SELECT \\* FROM orders WHERE user_id = \\$1 AND \\(created_at, id\\) > \\(\\$2, \\$3\\)").
+		WithArgs(userID.String(), after.CreatedAt, after.ID.String(), 10).
+		WillReturnRows(orderRows(order))
+	mock.ExpectCommit()
+
+	orders, err := repo.ListOrders(context.Background(), userID, after, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, orders, 1) {
+		assert.Equal(t, order.ID, orders[0].ID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOrderCursor_Before(t *testing.T) {
+	now := time.Now()
+	first := OrderCursor{CreatedAt: now, ID: uuid.MustParse("00000000-0000-4000-8000-000000000001")}
+	second := OrderCursor{CreatedAt: now, ID: uuid.MustParse("00000000-0000-4000-8000-000000000002")}
+	later := OrderCursor{CreatedAt: now.Add(time.Second)}
+
+	assert.True(t, first.Before(second), "ID breaks ties")
+	assert.False(t, second.Before(first))
+	assert.True(t, second.Before(later), "creation time goes first")
+	assert.True(t, OrderCursor{}.Before(first), "zero cursor precedes every order")
+}
@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"bytes"
 	"context"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,6 +14,14 @@ type Order struct {
 	UserID    uuid.UUID `db:"user_id"`
 	Label     string    `db:"label"`
 	CreatedAt time.Time `db:"created_at"`
+	TenantID  string    `db:"tenant_id"` // set by DB from TenantSetting
+}
+
+// PreparedTransaction is a transaction prepared by PrepareInsertOrder and waiting for confirmation.
+type PreparedTransaction struct {
+	TxID     uuid.UUID `db:"gid"`
+	Tenant   string    // rollback under WithTenant of it
+	Prepared time.Time `db:"prepared"`
 }
 
 type OrderRepoWith2PC interface {
@@ -22,4 +32,53 @@ type OrderRepoWith2PC interface {
 	RollbackInsertTransaction(ctx context.Context, txID uuid.UUID) error
 
 	GetOrder(ctx context.Context, id uuid.UUID) (*Order, error)
+
+	// GetOrders returns orders with ids in the order of ids, ids without an order are skipped.
+	GetOrders(ctx context.Context, ids []uuid.UUID) ([]*Order, error)
+
+	// ListOrders returns up to limit orders of userID following after, sorted by OrderCursor.
+	ListOrders(ctx context.Context, userID uuid.UUID, after OrderCursor, limit int) ([]*Order, error)
+}
+
+// PreparedTxLister is implemented by repositories which list transactions waiting for confirmation.
+type PreparedTxLister interface {
+	// ListPreparedTransactions returns transactions of every tenant prepared before preparedBefore, the oldest first.
+	ListPreparedTransactions(ctx context.Context, preparedBefore time.Time) ([]PreparedTransaction, error)
+}
+
+// CommittedOrderReader is implemented by repositories which tell orders of committed transactions.
+type CommittedOrderReader interface {
+	// CommittedOrder returns id of the order inserted by transaction txID of the tenant from ctx,
+	// sql.ErrNoRows unless the transaction is committed.
+	CommittedOrder(ctx context.Context, txID uuid.UUID) (uuid.UUID, error)
+}
+
+// OrderCursor is a position in orders sorted by creation time and then by ID.
+// Zero cursor precedes every order.
+type OrderCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// CursorOf returns position of order.
+func CursorOf(order *Order) OrderCursor {
+	return OrderCursor{CreatedAt: order.CreatedAt, ID: order.ID}
+}
+
+// Before reports whether c precedes other.
+func (c OrderCursor) Before(other OrderCursor) bool {
+	if !c.CreatedAt.Equal(other.CreatedAt) {
+		return c.CreatedAt.Before(other.CreatedAt)
+	}
+
+	return bytes.Compare(c.ID[:], other.ID[:]) < 0
+}
+
+// SortByIDs puts orders in the order of ids.
+func SortByIDs(orders []*Order, ids []uuid.UUID) {
+	position := make(map[uuid.UUID]int, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		position[ids[i]] = i
+	}
+	sort.SliceStable(orders, func(i, j int) bool { return position[orders[i].ID] < position[orders[j].ID] })
 }
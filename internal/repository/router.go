@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/Sugar-pack/orders-manager/internal/db"
+)
+
+// ReplicaCooldown is how long a replica which failed with connection error gets no reads.
+const ReplicaCooldown = 10 * time.Second
+
+type readYourWritesKey struct{}
+
+// WithReadYourWrites makes reads under ctx go to primary, so they see writes done just before.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, true)
+}
+
+// ReadYourWritesRequired reports whether ctx was made by WithReadYourWrites.
+func ReadYourWritesRequired(ctx context.Context) bool {
+	required, _ := ctx.Value(readYourWritesKey{}).(bool)
+
+	return required
+}
+
+// Router sends reads to replicas in turn and everything else to primary.
+// Reads fall back to primary when no replica is available. It is safe for concurrent use.
+type Router struct {
+	primary  *sqlx.DB
+	replicas []*replica
+	next     atomic.Uint64
+	now      func() time.Time
+}
+
+type replica struct {
+	db        *sqlx.DB
+	downUntil atomic.Int64 // unix nanoseconds
+}
+
+// NewRouter creates Router. Without replicas all reads go to primary.
+func NewRouter(primary *sqlx.DB, replicas ...*sqlx.DB) *Router {
+	router := &Router{primary: primary, now: time.Now}
+	for _, replicaDB := range replicas {
+		router.replicas = append(router.replicas, &replica{db: replicaDB})
+	}
+
+	return router
+}
+
+// Primary returns connection to primary.
+func (r *Router) Primary() *sqlx.DB {
+	return r.primary
+}
+
+// Read runs fn on a replica. Read runs fn on primary when ctx requires read-your-writes,
+// when all replicas are down, or again after fn failed on a replica with connection error.
+func (r *Router) Read(ctx context.Context, fn func(dbConn *sqlx.DB) error) error {
+	if ReadYourWritesRequired(ctx) {
+		return fn(r.primary)
+	}
+	if replica := r.pick(); replica != nil {
+		err := fn(replica.db)
+		if err == nil || !db.IsTransient(ctx, err) {
+			return err
+		}
+		replica.downUntil.Store(r.now().Add(ReplicaCooldown).UnixNano())
+		logging.FromContext(ctx).WithError(err).Warn("replica is down, reading from primary")
+	}
+
+	return fn(r.primary)
+}
+
+// pick returns the next replica which is not down, nil if there is none.
+func (r *Router) pick() *replica {
+	now := r.now().UnixNano()
+	for range r.replicas {
+		candidate := r.replicas[r.next.Add(1)%uint64(len(r.replicas))]
+		if candidate.downUntil.Load() <= now {
+			return candidate
+		}
+	}
+
+	return nil
+}
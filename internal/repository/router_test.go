@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func orderRows(order *Order) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "user_id", "label", "created_at"}).
+		AddRow(order.ID, order.UserID, order.Label, order.CreatedAt)
+}
+
+func TestRouter_ReadsFromReplica(t *testing.T) {
+	primary, primaryMock := newMock(t)
+	replicaDB, replicaMock := newMock(t)
+	repo := NewPsqlRepository(primary, replicaDB)
+	order := &Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+
+	expectGetOrder(replicaMock, "", orderRows(order), nil)
+	got, err := repo.GetOrder(context.Background(), order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, order.Label, got.Label)
+
+	expectGetOrder(primaryMock, "", orderRows(order), nil)
+	_, err = repo.GetOrder(WithReadYourWrites(context.Background()), order.ID)
+	assert.NoError(t, err, "read-your-writes goes to primary")
+
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestRouter_FallbackToPrimary(t *testing.T) {
+	primary, primaryMock := newMock(t)
+	replicaDB, replicaMock := newMock(t)
+	repo := NewPsqlRepository(primary, replicaDB)
+	now := time.Now()
+	repo.router.now = func() time.Time { return now }
+	order := &Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+
+	expectGetOrder(replicaMock, "", nil, &net.OpError{Op: "read", Err: syscall.ECONNRESET})
+	expectGetOrder(primaryMock, "", orderRows(order), nil)
+	_, err := repo.GetOrder(context.Background(), order.ID)
+	assert.NoError(t, err)
+
+	// replica is skipped during cooldown
+	expectGetOrder(primaryMock, "", orderRows(order), nil)
+	_, err = repo.GetOrder(context.Background(), order.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+
+	// and used again after it
+	now = now.Add(ReplicaCooldown)
+	expectGetOrder(replicaMock, "", orderRows(order), nil)
+	_, err = repo.GetOrder(context.Background(), order.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestRouter_QueryErrorIsNotRetried(t *testing.T) {
+	primary, primaryMock := newMock(t)
+	replicaDB, replicaMock := newMock(t)
+	repo := NewPsqlRepository(primary, replicaDB)
+
+	expectGetOrder(replicaMock, "", nil, assert.AnError)
+	_, err := repo.GetOrder(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestRouter_RoundRobin(t *testing.T) {
+	primary, _ := newMock(t)
+	first, _ := newMock(t)
+	second, _ := newMock(t)
+	router := NewRouter(primary, first, second)
+
+	picked := map[*replica]int{}
+	for range 4 {
+		picked[router.pick()]++
+	}
+	assert.Equal(t, 2, picked[router.replicas[0]])
+	assert.Equal(t, 2, picked[router.replicas[1]])
+	assert.Nil(t, NewRouter(primary).pick())
+}
@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+// shardedIDVersion marks IDs carrying shard number in their first two bytes.
+// Version 8 is reserved for custom layouts, so random IDs made before sharding never have it.
+const shardedIDVersion = 8
+
+// ErrUnknownShard is returned for IDs carrying shard number which is not configured.
+var ErrUnknownShard = errors.New("id refers to unknown shard")
+
+// IDAssigner is implemented by repositories which encode placement of an order in its IDs.
+type IDAssigner interface {
+	NewIDs(userID uuid.UUID) (orderID, txID uuid.UUID)
+}
+
+// Shard is a repository of one shard.
+type Shard interface {
+	OrderRepoWith2PC
+	PreparedTxLister
+	CommittedOrderReader
+}
+
+// ShardedRepository spreads orders across shards by hash of user_id.
+// Order and transaction IDs made by NewIDs carry the shard, so reads and confirmations go straight to it.
+// IDs without shard, e.g. made before sharding, are looked up on every shard.
+type ShardedRepository struct {
+	shards []Shard
+}
+
+// NewShardedRepository creates repository over shards, shard number is the index in shards.
+func NewShardedRepository(shards ...Shard) *ShardedRepository {
+	if len(shards) == 0 || len(shards) > config.MaxShards {
+		panic(fmt.Sprintf("number of shards must be between 1 and %d", config.MaxShards))
+	}
+
+	return &ShardedRepository{shards: shards}
+}
+
+// ShardOf returns shard number carried by id.
+func ShardOf(id uuid.UUID) (int, bool) {
+	if id.Version() != shardedIDVersion || id.Variant() != uuid.RFC4122 {
+		return 0, false
+	}
+
+	return int(id[0])<<8 | int(id[1]), true
+}
+
+// newShardedID returns random id carrying shard number.
+func newShardedID(shard int) uuid.UUID {
+	return WithShard(uuid.New(), shard)
+}
+
+// WithShard returns id carrying shard number, the rest of id is kept.
+func WithShard(id uuid.UUID, shard int) uuid.UUID {
+	id[0], id[1] = byte(shard>>8), byte(shard) //nolint:gosec // shard is less than config.MaxShards
+	id[6] = id[6]&0x0f | shardedIDVersion<<4
+	id[8] = id[8]&0x3f | 0x80 // RFC 4122 variant
+
+	return id
+}
+
+// UserShard returns shard number orders of userID belong to when there are shards of them.
+func UserShard(userID uuid.UUID, shards int) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write(userID[:])
+
+	return int(hash.Sum32() % uint32(shards)) //nolint:gosec // number of shards fits uint32
+}
+
+// ShardOfUser returns shard number orders of userID belong to.
+func (r *ShardedRepository) ShardOfUser(userID uuid.UUID) int {
+	return UserShard(userID, len(r.shards))
+}
+
+// NewIDs returns order and transaction IDs carrying shard of userID.
+func (r *ShardedRepository) NewIDs(userID uuid.UUID) (orderID, txID uuid.UUID) {
+	shard := r.ShardOfUser(userID)
+
+	return newShardedID(shard), newShardedID(shard)
+}
+
+// PrepareInsertOrder prepares order on the shard carried by txID, or on the shard of the user
+// when txID carries none.
+func (r *ShardedRepository) PrepareInsertOrder(ctx context.Context, order *Order, txID uuid.UUID) error {
+	shard, ok := ShardOf(txID)
+	if !ok {
+		shard = r.ShardOfUser(order.UserID)
+	}
+	if shard >= len(r.shards) {
+		return fmt.Errorf("%w: %d", ErrUnknownShard, shard)
+	}
+
+	return r.shards[shard].PrepareInsertOrder(ctx, order, txID)
+}
+
+func (r *ShardedRepository) CommitInsertTransaction(ctx context.Context, txID uuid.UUID) error {
+	return r.finishTransaction(ctx, txID, Shard.CommitInsertTransaction)
+}
+
+func (r *ShardedRepository) RollbackInsertTransaction(ctx context.Context, txID uuid.UUID) error {
+	return r.finishTransaction(ctx, txID, Shard.RollbackInsertTransaction)
+}
+
+// finishTransaction runs finish on the shard of txID. Transactions without shard are tried on
+// every shard until one knows them.
+func (r *ShardedRepository) finishTransaction(ctx context.Context, txID uuid.UUID,
+	finish func(shard Shard, ctx context.Context, txID uuid.UUID) error,
+) error {
+	if shard, ok := ShardOf(txID); ok {
+		if shard >= len(r.shards) {
+			return fmt.Errorf("%w: %d", ErrUnknownShard, shard)
+		}
+
+		return finish(r.shards[shard], ctx, txID)
+	}
+
+	var err error
+	for _, shard := range r.shards {
+		err = finish(shard, ctx, txID)
+		if !isUndefinedObject(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// CommittedOrder asks the shard carried by txID, or every shard in turn when txID carries none.
+func (r *ShardedRepository) CommittedOrder(ctx context.Context, txID uuid.UUID) (uuid.UUID, error) {
+	if shard, ok := ShardOf(txID); ok {
+		if shard >= len(r.shards) {
+			return uuid.Nil, fmt.Errorf("%w: %d", ErrUnknownShard, shard)
+		}
+
+		return r.shards[shard].CommittedOrder(ctx, txID) //nolint:wrapcheck
+	}
+
+	for _, shard := range r.shards {
+		orderID, err := shard.CommittedOrder(ctx, txID)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return orderID, err //nolint:wrapcheck
+		}
+	}
+
+	return uuid.Nil, sql.ErrNoRows
+}
+
+// GetOrder reads order from the shard carried by id, or from every shard at once when id carries none.
+func (r *ShardedRepository) GetOrder(ctx context.Context, id uuid.UUID) (*Order, error) {
+	if shard, ok := ShardOf(id); ok {
+		if shard >= len(r.shards) {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownShard, shard)
+		}
+
+		return r.shards[shard].GetOrder(ctx, id)
+	}
+
+	orders := make([]*Order, len(r.shards))
+	errs := make([]error, len(r.shards))
+	var wg sync.WaitGroup
+	for i, shard := range r.shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			orders[i], errs[i] = shard.GetOrder(ctx, id)
+		}()
+	}
+	wg.Wait()
+
+	var failed []error
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			return orders[i], nil
+		case !errors.Is(err, sql.ErrNoRows):
+			failed = append(failed, fmt.Errorf("shard %d: %w", i, err))
+		}
+	}
+	if len(failed) > 0 {
+		// the order may be on a failed shard, so it is not reported as missing
+		return nil, errors.Join(failed...)
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+// GetOrders reads orders from shards carried by ids in parallel, ids carrying no shard are looked up on every shard.
+func (r *ShardedRepository) GetOrders(ctx context.Context, ids []uuid.UUID) ([]*Order, error) {
+	shardIDs := make([][]uuid.UUID, len(r.shards))
+	var unsharded []uuid.UUID
+	for _, id := range ids {
+		shard, ok := ShardOf(id)
+		switch {
+		case !ok:
+			unsharded = append(unsharded, id)
+		case shard >= len(r.shards):
+			return nil, fmt.Errorf("%w: %d", ErrUnknownShard, shard)
+		default:
+			shardIDs[shard] = append(shardIDs[shard], id)
+		}
+	}
+
+	results := make([][]*Order, len(r.shards))
+	errs := make([]error, len(r.shards))
+	var wg sync.WaitGroup
+	for i, shard := range r.shards {
+		lookup := append(shardIDs[i], unsharded...)
+		if len(lookup) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = shard.GetOrders(ctx, lookup)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("shard %d: %w", i, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	var orders []*Order
+	for _, shardOrders := range results {
+		orders = append(orders, shardOrders...)
+	}
+	SortByIDs(orders, ids)
+
+	return orders, nil
+}
+
+// ListOrders reads orders of userID from every shard in parallel and merges them. Orders of a user live on
+// the shard of the user, but the ones made before sharding may be on any shard.
+func (r *ShardedRepository) ListOrders(ctx context.Context, userID uuid.UUID, after OrderCursor, limit int,
+) ([]*Order, error) {
+	results := make([][]*Order, len(r.shards))
+	errs := make([]error, len(r.shards))
+	var wg sync.WaitGroup
+	for i, shard := range r.shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = shard.ListOrders(ctx, userID, after, limit)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("shard %d: %w", i, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	var orders []*Order
+	for _, shardOrders := range results {
+		orders = append(orders, shardOrders...)
+	}
+	sort.Slice(orders, func(i, j int) bool { return CursorOf(orders[i]).Before(CursorOf(orders[j])) })
+	if len(orders) > limit {
+		orders = orders[:limit]
+	}
+
+	return orders, nil
+}
+
+// ListPreparedTransactions returns transactions prepared before preparedBefore on all shards.
+func (r *ShardedRepository) ListPreparedTransactions(ctx context.Context, preparedBefore time.Time,
+) ([]PreparedTransaction, error) {
+	var transactions []PreparedTransaction
+	for i, shard := range r.shards {
+		shardTransactions, err := shard.ListPreparedTransactions(ctx, preparedBefore)
+		if err != nil {
+			return nil, fmt.Errorf("shard %d: %w", i, err)
+		}
+		transactions = append(transactions, shardTransactions...)
+	}
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].Prepared.Before(transactions[j].Prepared)
+	})
+
+	return transactions, nil
+}
+
+// isUndefinedObject reports whether err says the prepared transaction does not exist.
+func isUndefinedObject(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == "42704" // undefined_object
+}
@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func newShards(t *testing.T, count int) (*ShardedRepository, []sqlmock.Sqlmock) {
+	t.Helper()
+	shards := make([]Shard, 0, count)
+	mocks := make([]sqlmock.Sqlmock, 0, count)
+	for range count {
+		dbConn, dbMock := newMock(t)
+		shards = append(shards, NewPsqlRepository(dbConn))
+		mocks = append(mocks, dbMock)
+	}
+
+	return NewShardedRepository(shards...), mocks
+}
+
+func TestShardOf(t *testing.T) {
+	for _, shard := range []int{0, 1, 255, 256, 65535} {
+		id := newShardedID(shard)
+		got, ok := ShardOf(id)
+		assert.True(t, ok)
+		assert.Equal(t, shard, got)
+		assert.Equal(t, uuid.RFC4122, id.Variant())
+	}
+
+	_, ok := ShardOf(uuid.New())
+	assert.False(t, ok, "random uuid carries no shard")
+}
+
+func TestWithShard(t *testing.T) {
+	id := uuid.NewSHA1(uuid.NameSpaceURL, []byte("orders"))
+	sharded := WithShard(id, 3)
+	shard, ok := ShardOf(sharded)
+	assert.True(t, ok)
+	assert.Equal(t, 3, shard)
+	assert.Equal(t, uuid.RFC4122, sharded.Variant())
+	assert.Equal(t, id[9:], sharded[9:], "the rest of id is kept")
+	assert.Equal(t, sharded, WithShard(id, 3), "derived ids are deterministic")
+
+	userID := uuid.New()
+	repo, _ := newShards(t, 4)
+	assert.Equal(t, repo.ShardOfUser(userID), UserShard(userID, 4))
+}
+
+func TestShardedRepository_NewIDs(t *testing.T) {
+	repo, _ := newShards(t, 3)
+	userID := uuid.New()
+	shard := repo.ShardOfUser(userID)
+	assert.Equal(t, shard, repo.ShardOfUser(userID), "same user is always on the same shard")
+
+	orderID, txID := repo.NewIDs(userID)
+	orderShard, _ := ShardOf(orderID)
+	txShard, _ := ShardOf(txID)
+	assert.Equal(t, shard, orderShard)
+	assert.Equal(t, shard, txShard)
+	assert.NotEqual(t, orderID, txID)
+}
+
+func TestShardedRepository_RoutesByID(t *testing.T) {
+	repo, mocks := newShards(t, 3)
+	order := &Order{UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+	var txID uuid.UUID
+	order.ID, txID = repo.NewIDs(order.UserID)
+	shardMock := mocks[repo.ShardOfUser(order.UserID)]
+
+	shardMock.ExpectBegin()
+	expectTenant(shardMock, "")
+	shardMock.ExpectExec("INSERT INTO orders").WillReturnResult(sqlmock.NewResult(1, 1))
+	shardMock.ExpectExec("PREPARE TRANSACTION '" + txID.String() + "'").WillReturnResult(sqlmock.NewResult(0, 0))
+	shardMock.ExpectRollback()
+	assert.NoError(t, repo.PrepareInsertOrder(context.Background(), order, txID))
+
+	shardMock.ExpectExec("COMMIT PREPARED '" + txID.String() + "'").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectTakeTrace(shardMock)
+	assert.NoError(t, repo.CommitInsertTransaction(context.Background(), txID))
+
+	expectGetOrder(shardMock, "", orderRows(order), nil)
+	got, err := repo.GetOrder(context.Background(), order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, order.Label, got.Label)
+
+	for _, dbMock := range mocks {
+		assert.NoError(t, dbMock.ExpectationsWereMet(), "other shards are not touched")
+	}
+}
+
+func TestShardedRepository_UnknownShard(t *testing.T) {
+	repo, _ := newShards(t, 2)
+	id := newShardedID(5)
+
+	_, err := repo.GetOrder(context.Background(), id)
+	assert.ErrorIs(t, err, ErrUnknownShard)
+	assert.ErrorIs(t, repo.CommitInsertTransaction(context.Background(), id), ErrUnknownShard)
+}
+
+func TestShardedRepository_GetOrderFanOut(t *testing.T) {
+	repo, mocks := newShards(t, 3)
+	order := &Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+
+	expectGetOrder(mocks[0], "", nil, sql.ErrNoRows)
+	expectGetOrder(mocks[1], "", orderRows(order), nil)
+	expectGetOrder(mocks[2], "", nil, sql.ErrNoRows)
+	got, err := repo.GetOrder(context.Background(), order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, order.ID, got.ID)
+
+	expectGetOrder(mocks[0], "", nil, sql.ErrNoRows)
+	expectGetOrder(mocks[1], "", nil, sql.ErrNoRows)
+	expectGetOrder(mocks[2], "", nil, errors.New("shard is down"))
+	_, err = repo.GetOrder(context.Background(), order.ID)
+	assert.ErrorContains(t, err, "shard 2: shard is down")
+	assert.NotErrorIs(t, err, sql.ErrNoRows, "order may be on the failed shard")
+
+	for _, dbMock := range mocks {
+		expectGetOrder(dbMock, "", nil, sql.ErrNoRows)
+	}
+	_, err = repo.GetOrder(context.Background(), order.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	for _, dbMock := range mocks {
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	}
+}
+
+func TestShardedRepository_GetOrders(t *testing.T) {
+	repo, mocks := newShards(t, 3)
+	sharded := &Order{UserID: uuid.New(), Label: "sharded", CreatedAt: time.Now().UTC()}
+	sharded.ID, _ = repo.NewIDs(sharded.UserID)
+	shard := repo.ShardOfUser(sharded.UserID)
+	legacy := &Order{ID: uuid.New(), UserID: uuid.New(), Label: "legacy", CreatedAt: time.Now().UTC()}
+
+	for i, dbMock := range mocks {
+		rows := sqlmock.NewRows([]string{"id", "user_id", "label", "created_at"})
+		if i == shard {
+			rows = orderRows(sharded)
+		}
+		if i == (shard+1)%len(mocks) {
+			rows = orderRows(legacy)
+		}
+		dbMock.ExpectBegin()
+		expectTenant(dbMock, "")
+		dbMock.ExpectQuery("WHERE id = ANY").WillReturnRows(rows)
+		dbMock.ExpectCommit()
+	}
+	orders, err := repo.GetOrders(context.Background(), []uuid.UUID{legacy.ID, sharded.ID})
+	assert.NoError(t, err)
+	if assert.Len(t, orders, 2) {
+		assert.Equal(t, legacy.ID, orders[0].ID)
+		assert.Equal(t, sharded.ID, orders[1].ID)
+	}
+
+	// only the shard carried by the id is asked
+	mocks[shard].ExpectBegin()
+	expectTenant(mocks[shard], "")
+	mocks[shard].ExpectQuery("WHERE id = ANY").WillReturnError(errors.New("shard is down"))
+	mocks[shard].ExpectRollback()
+	_, err = repo.GetOrders(context.Background(), []uuid.UUID{sharded.ID})
+	assert.ErrorContains(t, err, "shard is down")
+
+	_, err = repo.GetOrders(context.Background(), []uuid.UUID{newShardedID(7)})
+	assert.ErrorIs(t, err, ErrUnknownShard)
+
+	for _, dbMock := range mocks {
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	}
+}
+
+func TestShardedRepository_ListOrders(t *testing.T) {
+	repo, mocks := newShards(t, 2)
+	userID := uuid.New()
+	now := time.Now().UTC()
+	legacy := &Order{ID: uuid.New(), UserID: userID, Label: "legacy", CreatedAt: now.Add(-time.Hour)}
+	first := &Order{ID: newShardedID(1), UserID: userID, Label: "first", CreatedAt: now}
+	second := &Order{ID: newShardedID(1), UserID: userID, Label: "second", CreatedAt: now.Add(time.Minute)}
+
+	expectList := func(dbMock sqlmock.Sqlmock, rows *sqlmock.Rows) {
+		dbMock.ExpectBegin()
+		expectTenant(dbMock, "")
+		dbMock.ExpectQuery("WHERE user_id = ").WithArgs(userID.String(), sqlmock.AnyArg(), sqlmock.AnyArg(), 2).
+			WillReturnRows(rows)
+		dbMock.ExpectCommit()
+	}
+	expectList(mocks[0], orderRows(legacy))
+	expectList(mocks[1], orderRows(first).AddRow(second.ID, second.UserID, second.Label, second.CreatedAt))
+
+	orders, err := repo.ListOrders(context.Background(), userID, OrderCursor{}, 2)
+	assert.NoError(t, err)
+	if assert.Len(t, orders, 2, "orders of all shards are merged up to limit") {
+		assert.Equal(t, legacy.ID, orders[0].ID)
+		assert.Equal(t, first.ID, orders[1].ID)
+	}
+
+	for _, dbMock := range mocks {
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	}
+}
+
+func TestShardedRepository_ConfirmWithoutShard(t *testing.T) {
+	repo, mocks := newShards(t, 3)
+	txID := uuid.New()
+	notFound := &pgconn.PgError{Code: "42704", Message: "prepared transaction does not exist"}
+
+	mocks[0].ExpectExec("ROLLBACK PREPARED").WillReturnError(notFound)
+	mocks[1].ExpectExec("ROLLBACK PREPARED").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectTakeTrace(mocks[1])
+	assert.NoError(t, repo.RollbackInsertTransaction(context.Background(), txID))
+
+	for _, dbMock := range mocks {
+		dbMock.ExpectExec("COMMIT PREPARED").WillReturnError(notFound)
+	}
+	assert.ErrorAs(t, repo.CommitInsertTransaction(context.Background(), txID), &notFound)
+
+	for _, dbMock := range mocks {
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	}
+}
+
+func TestShardedRepository_CommittedOrder(t *testing.T) {
+	repo, mocks := newShards(t, 3)
+	ctx := WithTenant(context.Background(), "acme")
+	txID, orderID := uuid.New(), uuid.New()
+
+	// transactions without shard are looked up on every shard until one has the order
+	for i, dbMock := range mocks[:2] {
+		dbMock.ExpectBegin()
+		expectTenant(dbMock, "acme")
+		query := dbMock.ExpectQuery(regexp.QuoteMeta(committedOrderSQL)).WithArgs(txID.String())
+		if i == 0 {
+			query.WillReturnRows(sqlmock.NewRows([]string{"order_id"}))
+			dbMock.ExpectRollback()
+
+			continue
+		}
+		query.WillReturnRows(sqlmock.NewRows([]string{"order_id"}).AddRow(orderID.String()))
+		dbMock.ExpectCommit()
+	}
+	got, err := repo.CommittedOrder(ctx, txID)
+	assert.NoError(t, err)
+	assert.Equal(t, orderID, got)
+
+	sharded := newShardedID(2)
+	mocks[2].ExpectBegin()
+	expectTenant(mocks[2], "acme")
+	mocks[2].ExpectQuery(regexp.QuoteMeta(committedOrderSQL)).WithArgs(sharded.String()).
+		WillReturnRows(sqlmock.NewRows([]string{"order_id"}))
+	mocks[2].ExpectRollback()
+	_, err = repo.CommittedOrder(ctx, sharded)
+	assert.ErrorIs(t, err, sql.ErrNoRows, "transaction is not committed")
+
+	for _, dbMock := range mocks {
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	}
+}
+
+func TestShardedRepository_ListPreparedTransactions(t *testing.T) {
+	repo, mocks := newShards(t, 2)
+	now := time.Now().UTC()
+	first, second := newShardedID(1), newShardedID(0)
+
+	mocks[0].ExpectQuery("SELECT gid, prepared FROM pg_prepared_xacts").
+		WillReturnRows(sqlmock.NewRows([]string{"gid", "prepared"}).AddRow(second.String(), now))
+	mocks[1].ExpectQuery("SELECT gid, prepared FROM pg_prepared_xacts").
+		WillReturnRows(sqlmock.NewRows([]string{"gid", "prepared"}).AddRow(first.String(), now.Add(-time.Minute)))
+
+	transactions, err := repo.ListPreparedTransactions(context.Background(), now.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, []PreparedTransaction{
+		{TxID: first, Prepared: now.Add(-time.Minute)},
+		{TxID: second, Prepared: now},
+	}, transactions)
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+)
+
+// TenantSetting is the session variable row level security policies of orders compare tenant_id with.
+// Every transaction sets it, see setTenantSQL.
+const TenantSetting = "orders_manager.tenant_id"
+
+// setTenantSQL sets TenantSetting until the end of the current transaction.
+const setTenantSQL = "SELECT set_config('" + TenantSetting + "', $1, true)"
+
+// ErrInvalidTenant is returned by ParseTenant for tenants which are not usable as a part of transaction gid.
+var ErrInvalidTenant = errors.New("tenant must be 1 to 63 letters, digits, '_' or '-'")
+
+// otherTenantOrderCode is raised by the trigger registering order ids for ids of another tenant's orders.
+const otherTenantOrderCode = "P0002" // no_data_found
+
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,63}$`)
+
+type tenantKey struct{}
+
+// WithTenant makes repository calls under ctx see and create orders of tenant only.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns tenant set by WithTenant, empty tenant owns orders made without tenancy.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+
+	return tenant
+}
+
+// ParseTenant validates tenant taken from a request.
+func ParseTenant(tenant string) (string, error) {
+	if !tenantPattern.MatchString(tenant) {
+		return "", ErrInvalidTenant
+	}
+
+	return tenant, nil
+}
+
+// preparedGID returns gid of the prepared transaction txID of tenant. The gid includes the tenant,
+// so COMMIT PREPARED and ROLLBACK PREPARED of another tenant do not find the transaction.
+func preparedGID(ctx context.Context, txID uuid.UUID) string {
+	if tenant := TenantFromContext(ctx); tenant != "" {
+		return txID.String() + "@" + tenant
+	}
+
+	return txID.String()
+}
+
+// parsePreparedGID is the reverse of preparedGID. It fails for transactions not created by this service.
+func parsePreparedGID(gid string) (txID uuid.UUID, tenant string, err error) {
+	id, tenant, withTenant := strings.Cut(gid, "@")
+	if withTenant {
+		if _, err = ParseTenant(tenant); err != nil {
+			return uuid.Nil, "", err
+		}
+	}
+	txID, err = uuid.Parse(id)
+
+	return txID, tenant, err //nolint:wrapcheck
+}
+
+// hideOtherTenantOrder reports inserts of orders with an id of another tenant as sql.ErrNoRows,
+// the error of a missing order, so a tenant can not tell the id is used.
+func hideOtherTenantOrder(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == otherTenantOrderCode {
+		return sql.ErrNoRows
+	}
+
+	return err
+}
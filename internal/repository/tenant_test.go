@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTenant(t *testing.T) {
+	for _, tenant := range []string{"acme", "brand_2", "A-1"} {
+		got, err := ParseTenant(tenant)
+		assert.NoError(t, err)
+		assert.Equal(t, tenant, got)
+	}
+	for _, tenant := range []string{"", "a@b", "a'b", "with space", string(make([]byte, 64))} {
+		_, err := ParseTenant(tenant)
+		assert.ErrorIs(t, err, ErrInvalidTenant, tenant)
+	}
+}
+
+func TestPreparedGID(t *testing.T) {
+	txID := uuid.New()
+	assert.Equal(t, txID.String(), preparedGID(context.Background(), txID))
+
+	gid := preparedGID(WithTenant(context.Background(), "acme"), txID)
+	assert.Equal(t, txID.String()+"@acme", gid)
+
+	parsedID, tenant, err := parsePreparedGID(gid)
+	assert.NoError(t, err)
+	assert.Equal(t, txID, parsedID)
+	assert.Equal(t, "acme", tenant)
+
+	_, _, err = parsePreparedGID(txID.String() + "@a'b")
+	assert.Error(t, err)
+}
+
+func TestPsqlRepository_Tenant(t *testing.T) {
+	dbConn, dbMock := newMock(t)
+	repo := NewPsqlRepository(dbConn)
+	ctx := WithTenant(context.Background(), "acme")
+	order := &Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+	txID := uuid.New()
+	gid := txID.String() + "@acme"
+
+	dbMock.ExpectBegin()
+	expectTenant(dbMock, "acme")
+	dbMock.ExpectExec("INSERT INTO orders").WillReturnResult(sqlmock.NewResult(1, 1))
+	dbMock.ExpectExec("PREPARE TRANSACTION '" + gid + "'").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectRollback()
+	assert.NoError(t, repo.PrepareInsertOrder(ctx, order, txID))
+
+	// confirmation of another tenant does not find the transaction
+	dbMock.ExpectExec("COMMIT PREPARED '" + txID.String() + "@other'").WillReturnError(assert.AnError)
+	assert.Error(t, repo.CommitInsertTransaction(WithTenant(context.Background(), "other"), txID))
+	dbMock.ExpectExec("COMMIT PREPARED '" + gid + "'").WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.NoError(t, repo.CommitInsertTransaction(ctx, txID))
+
+	expectGetOrder(dbMock, "acme", orderRows(order), nil)
+	_, err := repo.GetOrder(ctx, order.ID)
+	assert.NoError(t, err)
+
+	before := time.Now()
+	dbMock.ExpectQuery("FROM pg_prepared_xacts").WithArgs(before).
+		WillReturnRows(sqlmock.NewRows([]string{"gid", "prepared"}).AddRow(gid, before))
+	transactions, err := repo.ListPreparedTransactions(context.Background(), before)
+	assert.NoError(t, err)
+	assert.Equal(t, []PreparedTransaction{{TxID: txID, Tenant: "acme", Prepared: before}}, transactions)
+
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
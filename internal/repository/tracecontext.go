@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Sugar-pack/orders-manager/internal/tracing"
+)
+
+// Trace context of the call preparing a transaction is kept until the transaction is committed or rolled back,
+// so spans confirming or expiring it link back to the trace which prepared it.
+const (
+	insertTraceSQL = "INSERT INTO prepared_tx_traces (gid, traceparent, tracestate) VALUES ($1, $2, $3) ON CONFLICT (gid) DO NOTHING"
+	takeTraceSQL   = "DELETE FROM prepared_tx_traces WHERE gid = $1 RETURNING traceparent, tracestate"
+	getTraceSQL    = "SELECT traceparent, tracestate FROM prepared_tx_traces WHERE gid = $1"
+)
+
+var traceContext = propagation.TraceContext{}
+
+// preparedTrace returns W3C trace context of the span of ctx, ok is false when the span is not sampled
+// and there is no trace to link to.
+func preparedTrace(ctx context.Context) (traceparent, tracestate string, ok bool) {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsSampled() {
+		return "", "", false
+	}
+	carrier := propagation.MapCarrier{}
+	traceContext.Inject(trace.ContextWithSpanContext(context.Background(), spanContext), carrier)
+
+	return carrier.Get("traceparent"), carrier.Get("tracestate"), true
+}
+
+// rowScanner is a row of sqlx or pgx.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// finishPreparedTrace links the span of ctx to the trace which prepared transaction txID once it is committed
+// or rolled back. The stored trace context is deleted when finished is set, whether the call is traced or not,
+// and kept for a retry otherwise. Failures are only logged, the trace context is not worth failing the call.
+func finishPreparedTrace(ctx context.Context, txID uuid.UUID, finished bool,
+	queryRow func(query string, args ...any) rowScanner,
+) {
+	traced := trace.SpanContextFromContext(ctx).IsValid()
+	query := takeTraceSQL
+	if !finished {
+		if !traced {
+			return
+		}
+		query = getTraceSQL
+	}
+	var traceparent, tracestate string
+	err := queryRow(query, preparedGID(ctx, txID)).Scan(&traceparent, &tracestate)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Warn("take trace context of prepared transaction failed")
+
+		return
+	}
+	if !traced {
+		return
+	}
+	carrier := propagation.MapCarrier{"traceparent": traceparent, "tracestate": tracestate}
+	prepared := trace.SpanContextFromContext(traceContext.Extract(context.Background(), carrier))
+	if prepared.IsValid() {
+		trace.SpanFromContext(ctx).AddLink(trace.Link{
+			SpanContext: prepared,
+			Attributes:  []attribute.KeyValue{tracing.TxIDKey.String(txID.String())},
+		})
+	}
+}
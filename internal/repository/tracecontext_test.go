@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Sugar-pack/orders-manager/internal/tracing"
+)
+
+func startRecordedSpan(t *testing.T, sampler sdktrace.Sampler) (context.Context, *tracetest.SpanRecorder, trace.Span) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder), sdktrace.WithSampler(sampler))
+	ctx, span := provider.Tracer(tracing.TracerName).Start(WithTenant(context.Background(), "acme"), "SendConfirmation")
+
+	return ctx, recorder, span
+}
+
+// expectTakeTrace expects trace context of a finished transaction to be deleted, there is none.
+func expectTakeTrace(dbMock sqlmock.Sqlmock) {
+	dbMock.ExpectQuery("DELETE FROM prepared_tx_traces").WillReturnRows(sqlmock.NewRows([]string{"traceparent", "tracestate"}))
+}
+
+func TestPrepareInsertOrder_StoresTrace(t *testing.T) {
+	db, dbMock := newMock(t)
+	repo := NewPsqlRepository(db)
+	ctx, _, span := startRecordedSpan(t, sdktrace.AlwaysSample())
+	defer span.End()
+	order := &Order{ID: uuid.New(), UserID: uuid.New(), Label: "label", CreatedAt: time.Now().UTC()}
+	txID := uuid.New()
+	traceparent := "00-" + span.SpanContext().TraceID().String() + "-" + span.SpanContext().SpanID().String() + "-01"
+
+	dbMock.ExpectBegin()
+	expectTenant(dbMock, "acme")
+	dbMock.ExpectExec("INSERT INTO orders").WillReturnResult(sqlmock.NewResult(1, 1))
+	dbMock.ExpectExec("PREPARE TRANSACTION").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectExec("INSERT INTO prepared_tx_traces").WithArgs(txID.String()+"@acme", traceparent, "").
+		WillReturnError(errors.New("relation does not exist"))
+	dbMock.ExpectRollback()
+
+	assert.NoError(t, repo.PrepareInsertOrder(ctx, order, txID), "the transaction is prepared anyway")
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestPrepareInsertOrder_NotSampled(t *testing.T) {
+	db, dbMock := newMock(t)
+	repo := NewPsqlRepository(db)
+	ctx, _, span := startRecordedSpan(t, sdktrace.NeverSample())
+	defer span.End()
+
+	dbMock.ExpectBegin()
+	expectTenant(dbMock, "acme")
+	dbMock.ExpectExec("INSERT INTO orders").WillReturnResult(sqlmock.NewResult(1, 1))
+	dbMock.ExpectExec("PREPARE TRANSACTION").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectRollback()
+
+	assert.NoError(t, repo.PrepareInsertOrder(ctx, &Order{ID: uuid.New(), UserID: uuid.New()}, uuid.New()))
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestCommitInsertTransaction_LinksPreparedTrace(t *testing.T) {
+	db, dbMock := newMock(t)
+	repo := NewPsqlRepository(db)
+	ctx, recorder, span := startRecordedSpan(t, sdktrace.AlwaysSample())
+	txID := uuid.New()
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	// a failed commit links the trace and keeps it for the retry
+	dbMock.ExpectExec("COMMIT PREPARED").WillReturnError(errors.New("connection reset"))
+	dbMock.ExpectQuery("SELECT traceparent, tracestate FROM prepared_tx_traces WHERE gid = \\$1").
+		WithArgs(txID.String() + "@acme").
+		WillReturnRows(sqlmock.NewRows([]string{"traceparent", "tracestate"}).AddRow(traceparent, "vendor=1"))
+	assert.Error(t, repo.CommitInsertTransaction(ctx, txID))
+
+	dbMock.ExpectExec("COMMIT PREPARED").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectQuery("DELETE FROM prepared_tx_traces WHERE gid = \\$1 RETURNING traceparent, tracestate").
+		WithArgs(txID.String() + "@acme").
+		WillReturnRows(sqlmock.NewRows([]string{"traceparent", "tracestate"}).AddRow(traceparent, "vendor=1"))
+	assert.NoError(t, repo.CommitInsertTransaction(ctx, txID))
+	span.End()
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+
+	links := recorder.Ended()[0].Links()
+	if assert.Len(t, links, 2) {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", links[0].SpanContext.TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", links[0].SpanContext.SpanID().String())
+		assert.Equal(t, "vendor=1", links[0].SpanContext.TraceState().String())
+		assert.Contains(t, links[0].Attributes, tracing.TxIDKey.String(txID.String()))
+	}
+}
+
+func TestRollbackInsertTransaction_WithoutPreparedTrace(t *testing.T) {
+	db, dbMock := newMock(t)
+	repo := NewPsqlRepository(db)
+	ctx, recorder, span := startRecordedSpan(t, sdktrace.AlwaysSample())
+	txID := uuid.New()
+
+	dbMock.ExpectExec("ROLLBACK PREPARED").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectTakeTrace(dbMock)
+	assert.NoError(t, repo.RollbackInsertTransaction(ctx, txID))
+
+	dbMock.ExpectExec("ROLLBACK PREPARED").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectQuery("DELETE FROM prepared_tx_traces").WillReturnError(errors.New("relation does not exist"))
+	assert.NoError(t, repo.RollbackInsertTransaction(ctx, txID), "trace context is not worth failing the call")
+	span.End()
+
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+	assert.Empty(t, recorder.Ended()[0].Links())
+}
+
+func TestCommitInsertTransaction_Untraced(t *testing.T) {
+	db, dbMock := newMock(t)
+	repo := NewPsqlRepository(db)
+	ctx := WithTenant(context.Background(), "acme")
+	txID := uuid.New()
+
+	// nothing is linked, but the stored trace context is deleted once the transaction is committed
+	dbMock.ExpectExec("COMMIT PREPARED").WillReturnError(errors.New("connection reset"))
+	assert.Error(t, repo.CommitInsertTransaction(ctx, txID))
+	dbMock.ExpectExec("COMMIT PREPARED").WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectQuery("DELETE FROM prepared_tx_traces").WithArgs(txID.String() + "@acme").
+		WillReturnRows(sqlmock.NewRows([]string{"traceparent", "tracestate"}).
+			AddRow("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ""))
+	assert.NoError(t, repo.CommitInsertTransaction(ctx, txID))
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
@@ -0,0 +1,135 @@
+// Package retry runs operations with exponential backoff and jitter.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+// Defaults are used for settings missing in config.Retry.
+const (
+	DefaultInitialInterval = 500 * time.Millisecond
+	DefaultMaxInterval     = 10 * time.Second
+	DefaultMultiplier      = 2.0
+	DefaultMaxElapsedTime  = time.Minute
+
+	// jitter spreads every delay uniformly over ±50% so replicas do not retry in lockstep.
+	jitter = 0.5
+)
+
+// Policy describes delays between attempts.
+type Policy struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	MaxElapsedTime  time.Duration // no attempts are started after it passes
+}
+
+// NewPolicy creates Policy from conf, nil conf gives default policy.
+func NewPolicy(conf *config.Retry) Policy {
+	policy := Policy{
+		InitialInterval: DefaultInitialInterval,
+		MaxInterval:     DefaultMaxInterval,
+		Multiplier:      DefaultMultiplier,
+		MaxElapsedTime:  DefaultMaxElapsedTime,
+	}
+	if conf == nil {
+		return policy
+	}
+	if conf.InitialInterval > 0 {
+		policy.InitialInterval = conf.InitialInterval
+	}
+	if conf.MaxInterval > 0 {
+		policy.MaxInterval = conf.MaxInterval
+	}
+	if conf.Multiplier >= 1 {
+		policy.Multiplier = conf.Multiplier
+	}
+	if conf.MaxElapsedTime > 0 {
+		policy.MaxElapsedTime = conf.MaxElapsedTime
+	}
+
+	return policy
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying. Do returns err unwrapped.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+// Do calls fn until it succeeds, returns permanent error, ctx is done or policy.MaxElapsedTime passes.
+// Every failed attempt is logged with operation name.
+func Do(ctx context.Context, policy Policy, operation string, fn func(ctx context.Context) error) error {
+	logger := logging.FromContext(ctx).WithField("operation", operation)
+	started := time.Now()
+	interval := policy.InitialInterval
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			if attempt > 1 {
+				logger.WithField("attempt", attempt).Info("attempt succeeded")
+			}
+
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			logger.WithError(permanent.err).WithField("attempt", attempt).Error("attempt failed, error is permanent")
+
+			return permanent.err
+		}
+
+		delay := randomize(interval)
+		if time.Since(started)+delay > policy.MaxElapsedTime {
+			logger.WithError(err).WithField("attempt", attempt).
+				WithField("elapsed", time.Since(started).String()).Error("attempt failed, giving up")
+
+			return err
+		}
+		logger.WithError(err).WithField("attempt", attempt).WithField("retry_in", delay.String()).
+			Warn("attempt failed, retrying")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+		interval = min(time.Duration(float64(interval)*policy.Multiplier), policy.MaxInterval)
+	}
+}
+
+// Delay returns randomized delay after failed attempt number attempt, counting from 1. It is for callers
+// scheduling attempts themselves, e.g. across restarts.
+func (p Policy) Delay(attempt int) time.Duration {
+	interval := float64(p.InitialInterval) * math.Pow(p.Multiplier, float64(max(attempt, 1)-1))
+
+	return randomize(time.Duration(min(interval, float64(p.MaxInterval))))
+}
+
+func randomize(interval time.Duration) time.Duration {
+	delta := jitter * float64(interval)
+
+	return time.Duration(float64(interval) - delta + rand.Float64()*2*delta) //nolint:gosec // jitter needs no crypto
+}
@@ -0,0 +1,106 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+var errTransient = errors.New("transient")
+
+func fastPolicy() Policy {
+	return Policy{
+		InitialInterval: time.Millisecond,
+		MaxInterval:     2 * time.Millisecond,
+		Multiplier:      2,
+		MaxElapsedTime:  time.Second,
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	assert.Equal(t, Policy{
+		InitialInterval: DefaultInitialInterval,
+		MaxInterval:     DefaultMaxInterval,
+		Multiplier:      DefaultMultiplier,
+		MaxElapsedTime:  DefaultMaxElapsedTime,
+	}, NewPolicy(nil))
+
+	policy := NewPolicy(&config.Retry{InitialInterval: time.Second, MaxElapsedTime: 5 * time.Minute})
+	assert.Equal(t, time.Second, policy.InitialInterval)
+	assert.Equal(t, DefaultMaxInterval, policy.MaxInterval)
+	assert.Equal(t, 5*time.Minute, policy.MaxElapsedTime)
+}
+
+func TestDo_SucceedsAfterRetries(t *testing.T) {
+	attempts := 0
+	err := Do(context.Background(), fastPolicy(), "test", func(context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errTransient
+		}
+
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestDo_Permanent(t *testing.T) {
+	attempts := 0
+	err := Do(context.Background(), fastPolicy(), "test", func(context.Context) error {
+		attempts++
+
+		return Permanent(errTransient)
+	})
+	assert.Equal(t, errTransient, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestDo_MaxElapsedTime(t *testing.T) {
+	policy := fastPolicy()
+	policy.MaxElapsedTime = 20 * time.Millisecond
+	attempts := 0
+	err := Do(context.Background(), policy, "test", func(context.Context) error {
+		attempts++
+
+		return errTransient
+	})
+	assert.ErrorIs(t, err, errTransient)
+	assert.Greater(t, attempts, 1)
+}
+
+func TestDo_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := fastPolicy()
+	policy.InitialInterval = time.Minute
+	policy.MaxElapsedTime = time.Hour
+	err := Do(ctx, policy, "test", func(context.Context) error {
+		cancel()
+
+		return errTransient
+	})
+	assert.ErrorIs(t, err, errTransient)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPolicy_Delay(t *testing.T) {
+	policy := Policy{InitialInterval: time.Second, MaxInterval: 10 * time.Second, Multiplier: 2}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 3: 4 * time.Second, 10: 10 * time.Second} {
+		delay := policy.Delay(attempt)
+		assert.GreaterOrEqual(t, delay, want/2, attempt)
+		assert.LessOrEqual(t, delay, want*3/2, attempt)
+	}
+}
+
+func TestRandomize(t *testing.T) {
+	for range 100 {
+		delay := randomize(time.Second)
+		assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+		assert.LessOrEqual(t, delay, 1500*time.Millisecond)
+	}
+}
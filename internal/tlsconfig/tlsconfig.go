@@ -0,0 +1,187 @@
+// Package tlsconfig builds tls.Config for the api listeners from config.TLS
+// and reloads certificates when they are rotated on disk.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+// DefaultReloadInterval is used when config.TLS.ReloadInterval is not set.
+const DefaultReloadInterval = 30 * time.Second
+
+var ErrNoClientCA = errors.New("require_client_cert is set but client_ca_file is empty")
+
+// Reloader keeps server certificate and client CA bundle up to date with files on disk.
+// Files are checked for modification lazily, on handshakes, at most once per reload interval.
+type Reloader struct {
+	conf     *config.TLS
+	logger   logging.Logger
+	interval time.Duration
+	now      func() time.Time
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+	checkedAt time.Time
+}
+
+// NewReloader loads certificates described by conf.
+func NewReloader(logger logging.Logger, conf *config.TLS) (*Reloader, error) {
+	if conf.RequireClientCert && conf.ClientCAFile == "" {
+		return nil, ErrNoClientCA
+	}
+	interval := conf.ReloadInterval
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	reloader := &Reloader{
+		conf:     conf,
+		logger:   logger,
+		interval: interval,
+		now:      time.Now,
+	}
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// ServerConfig returns tls.Config for a server, each handshake gets the latest certificates.
+func (r *Reloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.maybeReload()
+			cert, clientCAs := r.current()
+
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				ClientCAs:    clientCAs,
+				ClientAuth:   r.clientAuth(),
+				NextProtos:   []string{"h2", "http/1.1"},
+			}, nil
+		},
+	}
+}
+
+func (r *Reloader) clientAuth() tls.ClientAuthType {
+	switch {
+	case r.conf.RequireClientCert:
+		return tls.RequireAndVerifyClientCert
+	case r.conf.ClientCAFile != "":
+		return tls.VerifyClientCertIfGiven
+	default:
+		return tls.NoClientCert
+	}
+}
+
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, r.clientCAs
+}
+
+func (r *Reloader) maybeReload() {
+	now := r.now()
+	r.mu.RLock()
+	due := now.Sub(r.checkedAt) >= r.interval
+	r.mu.RUnlock()
+	if !due {
+		return
+	}
+
+	changed, err := r.changed()
+	if err != nil {
+		r.logger.WithError(err).Error("checking tls files failed")
+	}
+	if !changed {
+		r.mu.Lock()
+		r.checkedAt = now
+		r.mu.Unlock()
+
+		return
+	}
+	if err = r.load(); err != nil {
+		// keep serving with previous certificates
+		r.logger.WithError(err).Error("reloading tls certificates failed")
+
+		return
+	}
+	r.logger.Info("tls certificates reloaded")
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.conf.CertFile, r.conf.KeyFile}
+	if r.conf.ClientCAFile != "" {
+		files = append(files, r.conf.ClientCAFile)
+	}
+
+	return files
+}
+
+func (r *Reloader) changed() (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, err //nolint:wrapcheck // path is already in the error
+		}
+		if !info.ModTime().Equal(r.modTimes[file]) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *Reloader) load() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err //nolint:wrapcheck // path is already in the error
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.conf.CertFile, r.conf.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load key pair failed: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.conf.ClientCAFile != "" {
+		pem, readErr := os.ReadFile(r.conf.ClientCAFile)
+		if readErr != nil {
+			return readErr //nolint:wrapcheck // path is already in the error
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", r.conf.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	r.checkedAt = r.now()
+
+	return nil
+}
@@ -0,0 +1,248 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create ca: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse ca: %v", err)
+	}
+
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) writeCA(t *testing.T, path string) {
+	t.Helper()
+	writePEM(t, path, "CERTIFICATE", ca.cert.Raw)
+}
+
+// issue writes key pair signed by ca, usable both as server and client certificate.
+func (ca *testCA) issue(t *testing.T, commonName string, serial int64, certPath, keyPath string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("create cert: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	writePEM(t, certPath, "CERTIFICATE", der)
+	writePEM(t, keyPath, "EC PRIVATE KEY", keyDER)
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func testConf(t *testing.T, ca *testCA) *config.TLS {
+	t.Helper()
+	dir := t.TempDir()
+	conf := &config.TLS{
+		CertFile:          filepath.Join(dir, "server.crt"),
+		KeyFile:           filepath.Join(dir, "server.key"),
+		ClientCAFile:      filepath.Join(dir, "ca.crt"),
+		RequireClientCert: true,
+		ReloadInterval:    time.Minute,
+	}
+	ca.issue(t, "server-1", 2, conf.CertFile, conf.KeyFile)
+	ca.writeCA(t, conf.ClientCAFile)
+
+	return conf
+}
+
+func handshake(t *testing.T, serverConf, clientConf *tls.Config) (*tls.ConnectionState, error) {
+	t.Helper()
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", serverConf)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer lis.Close()
+
+	type result struct {
+		state tls.ConnectionState
+		err   error
+	}
+	serverRes := make(chan result, 1)
+	go func() {
+		conn, acceptErr := lis.Accept()
+		if acceptErr != nil {
+			serverRes <- result{err: acceptErr}
+
+			return
+		}
+		defer conn.Close()
+		tlsConn, _ := conn.(*tls.Conn)
+		hsErr := tlsConn.Handshake()
+		serverRes <- result{state: tlsConn.ConnectionState(), err: hsErr}
+	}()
+
+	clientConn, err := tls.Dial("tcp", lis.Addr().String(), clientConf)
+	if err == nil {
+		// TLS 1.3 client learns about rejected certificate only on the first read,
+		// accepted connection is just closed by the server.
+		_ = clientConn.SetReadDeadline(time.Now().Add(time.Second))
+		_, readErr := clientConn.Read(make([]byte, 1))
+		if !errors.Is(readErr, io.EOF) {
+			err = readErr
+		}
+		_ = clientConn.Close()
+	}
+	res := <-serverRes
+	if err != nil {
+		return nil, err
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	return &res.state, nil
+}
+
+func TestNewReloader_RequireClientCertWithoutCA(t *testing.T) {
+	_, err := NewReloader(logging.GetLogger(), &config.TLS{RequireClientCert: true})
+	assert.ErrorIs(t, err, ErrNoClientCA)
+}
+
+func TestNewReloader_MissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewReloader(logging.GetLogger(), &config.TLS{
+		CertFile: filepath.Join(dir, "none.crt"),
+		KeyFile:  filepath.Join(dir, "none.key"),
+	})
+	assert.Error(t, err)
+}
+
+func TestReloader_MutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	conf := testConf(t, ca)
+	reloader, err := NewReloader(logging.GetLogger(), conf)
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientDir := t.TempDir()
+	ca.issue(t, "coordinator", 3, filepath.Join(clientDir, "client.crt"), filepath.Join(clientDir, "client.key"))
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(clientDir, "client.crt"), filepath.Join(clientDir, "client.key"))
+	if err != nil {
+		t.Fatalf("load client cert: %v", err)
+	}
+
+	state, err := handshake(t, reloader.ServerConfig(), &tls.Config{
+		RootCAs:      roots,
+		ServerName:   "localhost",
+		Certificates: []tls.Certificate{clientCert},
+		MinVersion:   tls.VersionTLS12,
+	})
+	assert.NoError(t, err)
+	if assert.NotNil(t, state) && assert.NotEmpty(t, state.VerifiedChains) {
+		assert.Equal(t, "coordinator", state.VerifiedChains[0][0].Subject.CommonName)
+	}
+
+	_, err = handshake(t, reloader.ServerConfig(), &tls.Config{
+		RootCAs:    roots,
+		ServerName: "localhost",
+		MinVersion: tls.VersionTLS12,
+	})
+	assert.Error(t, err, "client without certificate must be rejected")
+}
+
+func TestReloader_Rotation(t *testing.T) {
+	ca := newTestCA(t)
+	conf := testConf(t, ca)
+	reloader, err := NewReloader(logging.GetLogger(), conf)
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+	now := time.Now()
+	reloader.now = func() time.Time { return now }
+
+	ca.issue(t, "server-2", 4, conf.CertFile, conf.KeyFile)
+	future := time.Now().Add(time.Minute)
+	for _, file := range []string{conf.CertFile, conf.KeyFile} {
+		if err = os.Chtimes(file, future, future); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+
+	reloader.maybeReload()
+	cert, _ := reloader.current()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parse leaf: %v", err)
+	}
+	assert.Equal(t, "server-1", leaf.Subject.CommonName, "files must not be checked before interval passes")
+
+	now = now.Add(2 * time.Minute)
+	reloader.maybeReload()
+	cert, _ = reloader.current()
+	leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parse leaf: %v", err)
+	}
+	assert.Equal(t, "server-2", leaf.Subject.CommonName)
+}
@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/sdk/metric"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+// newMetricExporter returns exporter selected by conf, nil for config.ExporterNone.
+func newMetricExporter(ctx context.Context, conf *config.Metrics) (metric.Exporter, error) {
+	switch conf.Exporter {
+	case "", config.ExporterOTLPGRPC:
+		var opts []otlpmetricgrpc.Option
+		if conf.Endpoint != "" {
+			opts = append(opts, otlpmetricgrpc.WithEndpoint(conf.Endpoint))
+		}
+		if conf.Insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+
+		return otlpmetricgrpc.New(ctx, opts...) //nolint:wrapcheck // too simple to wrap
+	case config.ExporterOTLPHTTP:
+		var opts []otlpmetrichttp.Option
+		if conf.Endpoint != "" {
+			opts = append(opts, otlpmetrichttp.WithEndpoint(conf.Endpoint))
+		}
+		if conf.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+
+		return otlpmetrichttp.New(ctx, opts...) //nolint:wrapcheck // too simple to wrap
+	case config.ExporterStdout:
+		return stdoutmetric.New(stdoutmetric.WithPrettyPrint()) //nolint:wrapcheck // too simple to wrap
+	case config.ExporterFile:
+		file, err := os.OpenFile(conf.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // too simple to wrap
+		}
+		exporter, err := stdoutmetric.New(stdoutmetric.WithWriter(file))
+		if err != nil {
+			_ = file.Close()
+
+			return nil, err //nolint:wrapcheck // too simple to wrap
+		}
+
+		return &fileMetricExporter{Exporter: exporter, file: file}, nil
+	case config.ExporterNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown metrics exporter %q", conf.Exporter)
+	}
+}
+
+// fileMetricExporter closes the file metrics are written to on shutdown.
+type fileMetricExporter struct {
+	metric.Exporter
+	file *os.File
+}
+
+func (e *fileMetricExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.Exporter.Shutdown(ctx), e.file.Close())
+}
+
+// InitMetrics sets global meter provider configured by conf, nil conf exports metrics with OTLP gRPC.
+// Instruments should be created after it, they are bound to the provider registered at their creation.
+func InitMetrics(ctx context.Context, logger logging.Logger, conf *config.Metrics) (*metric.MeterProvider, error) {
+	if conf == nil {
+		conf = &config.Metrics{}
+	}
+	exporter, err := newMetricExporter(ctx, conf)
+	if err != nil {
+		logger.WithError(err).Error("create metrics exporter failed")
+
+		return nil, err
+	}
+	metricsResource, err := newResource()
+	if err != nil {
+		logger.WithError(err).Error("create metrics resource failed")
+
+		return nil, err
+	}
+
+	opts := []metric.Option{metric.WithResource(metricsResource)}
+	if exporter != nil {
+		var readerOpts []metric.PeriodicReaderOption
+		if conf.Interval > 0 {
+			readerOpts = append(readerOpts, metric.WithInterval(conf.Interval))
+		}
+		opts = append(opts, metric.WithReader(metric.NewPeriodicReader(exporter, readerOpts...)))
+	}
+	meterProvider := metric.NewMeterProvider(opts...)
+
+	otel.SetMeterProvider(meterProvider)
+
+	return meterProvider, nil
+}
@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+)
+
+func TestInitMetrics_Exporters(t *testing.T) {
+	logger := logging.GetLogger()
+	ctx := logging.WithContext(context.Background(), logger)
+	prev := otel.GetMeterProvider()
+	t.Cleanup(func() { otel.SetMeterProvider(prev) })
+	for _, conf := range []*config.Metrics{
+		nil,
+		{Exporter: config.ExporterOTLPGRPC, Endpoint: "localhost:4317", Insecure: true},
+		{Exporter: config.ExporterOTLPHTTP, Endpoint: "localhost:4318", Insecure: true},
+		{Exporter: config.ExporterStdout},
+		{Exporter: config.ExporterNone},
+	} {
+		provider, err := InitMetrics(ctx, logger, conf)
+		if assert.NoError(t, err) {
+			assert.Same(t, provider, otel.GetMeterProvider(), "provider is registered")
+			// OTLP exporters fail to flush without collector
+			shutdownCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			_ = provider.Shutdown(shutdownCtx)
+			cancel()
+		}
+	}
+
+	_, err := InitMetrics(ctx, logger, &config.Metrics{Exporter: "prometheus"})
+	assert.Error(t, err)
+}
+
+func TestInitMetrics_File(t *testing.T) {
+	logger := logging.GetLogger()
+	ctx := logging.WithContext(context.Background(), logger)
+	prev := otel.GetMeterProvider()
+	t.Cleanup(func() { otel.SetMeterProvider(prev) })
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	provider, err := InitMetrics(ctx, logger, &config.Metrics{Exporter: config.ExporterFile, File: path})
+	if err != nil {
+		t.Fatalf("InitMetrics error: %v", err)
+	}
+	counter, err := otel.Meter(TracerName).Int64Counter("grpc.server.panics")
+	assert.NoError(t, err)
+	counter.Add(ctx, 1)
+	assert.NoError(t, provider.Shutdown(ctx), "shutdown exports collected metrics")
+
+	metrics, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(metrics), `"Name":"grpc.server.panics"`)
+}
@@ -2,19 +2,111 @@ package tracing
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
 
 	"github.com/Sugar-pack/users-manager/pkg/logging"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
 )
 
 const TracerName = "order-manager"
 
-func newExporter(ctx context.Context) (trace.SpanExporter, error) {
-	return otlptracegrpc.New(ctx) //nolint:wrapcheck // too simple to wrap
+// Attributes of spans handling orders.
+const (
+	OrderIDKey = attribute.Key("order.id")
+	UserIDKey  = attribute.Key("user.id")
+	TxIDKey    = attribute.Key("tx.id")
+)
+
+// RecordError adds err to span as an exception event and marks span failed.
+func RecordError(span oteltrace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// newExporter returns exporter selected by conf, nil for config.ExporterNone.
+func newExporter(ctx context.Context, conf *config.Tracing) (trace.SpanExporter, error) {
+	switch conf.Exporter {
+	case "", config.ExporterOTLPGRPC:
+		var opts []otlptracegrpc.Option
+		if conf.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(conf.Endpoint))
+		}
+		if conf.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+
+		return otlptracegrpc.New(ctx, opts...) //nolint:wrapcheck // too simple to wrap
+	case config.ExporterOTLPHTTP:
+		var opts []otlptracehttp.Option
+		if conf.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(conf.Endpoint))
+		}
+		if conf.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+
+		return otlptracehttp.New(ctx, opts...) //nolint:wrapcheck // too simple to wrap
+	case config.ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint()) //nolint:wrapcheck // too simple to wrap
+	case config.ExporterFile:
+		file, err := os.OpenFile(conf.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // too simple to wrap
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			_ = file.Close()
+
+			return nil, err //nolint:wrapcheck // too simple to wrap
+		}
+
+		return &fileExporter{SpanExporter: exporter, file: file}, nil
+	case config.ExporterNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", conf.Exporter)
+	}
+}
+
+// fileExporter closes the file spans are written to on shutdown.
+type fileExporter struct {
+	trace.SpanExporter
+	file *os.File
+}
+
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.SpanExporter.Shutdown(ctx), e.file.Close())
+}
+
+// newSampler returns sampler selected by conf.
+func newSampler(conf *config.Tracing) trace.Sampler {
+	var sampler trace.Sampler
+	switch conf.Sampler {
+	case config.SamplerNever:
+		sampler = trace.NeverSample()
+	case config.SamplerRatio:
+		sampler = trace.TraceIDRatioBased(conf.Ratio)
+	default:
+		sampler = trace.AlwaysSample()
+	}
+	if conf.ParentBased {
+		sampler = trace.ParentBased(sampler)
+	}
+
+	return sampler
 }
 
 func newResource() (*resource.Resource, error) {
@@ -26,10 +118,15 @@ func newResource() (*resource.Resource, error) {
 	return tracingResource, err //nolint:wrapcheck // err can be nil
 }
 
-func InitTracing(ctx context.Context, logger logging.Logger) (*trace.TracerProvider, error) {
-	otlpExporter, err := newExporter(ctx)
+// InitTracing sets global tracer provider configured by conf, nil conf exports spans
+// of every trace with OTLP gRPC.
+func InitTracing(ctx context.Context, logger logging.Logger, conf *config.Tracing) (*trace.TracerProvider, error) {
+	if conf == nil {
+		conf = &config.Tracing{}
+	}
+	exporter, err := newExporter(ctx, conf)
 	if err != nil {
-		logger.WithError(err).Error("create otlp exporter failed")
+		logger.WithError(err).Error("create tracing exporter failed")
 
 		return nil, err
 	}
@@ -40,11 +137,14 @@ func InitTracing(ctx context.Context, logger logging.Logger) (*trace.TracerProvi
 		return nil, err
 	}
 
-	tracingProvider := trace.NewTracerProvider(
-		trace.WithBatcher(otlpExporter),
+	opts := []trace.TracerProviderOption{
 		trace.WithResource(tracingResource),
-		trace.WithSampler(trace.AlwaysSample()),
-	)
+		trace.WithSampler(newSampler(conf)),
+	}
+	if exporter != nil {
+		opts = append(opts, trace.WithBatcher(exporter))
+	}
+	tracingProvider := trace.NewTracerProvider(opts...)
 
 	otel.SetTracerProvider(tracingProvider)
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
@@ -2,15 +2,26 @@ package tracing
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
 )
 
 func TestInitTracing(t *testing.T) {
 	logger := logging.GetLogger()
 	ctx := logging.WithContext(context.Background(), logger)
-	provider, err := InitTracing(ctx, logger)
+	provider, err := InitTracing(ctx, logger, nil)
 	if err != nil {
 		t.Fatalf("InitTracing error: %v", err)
 	}
@@ -21,3 +32,75 @@ func TestInitTracing(t *testing.T) {
 		t.Fatalf("shutdown error: %v", err)
 	}
 }
+
+func TestInitTracing_Exporters(t *testing.T) {
+	logger := logging.GetLogger()
+	ctx := logging.WithContext(context.Background(), logger)
+	for _, conf := range []*config.Tracing{
+		{Exporter: config.ExporterOTLPGRPC, Endpoint: "localhost:4317", Insecure: true},
+		{Exporter: config.ExporterOTLPHTTP, Endpoint: "localhost:4318", Insecure: true},
+		{Exporter: config.ExporterStdout},
+		{Exporter: config.ExporterNone},
+	} {
+		provider, err := InitTracing(ctx, logger, conf)
+		if assert.NoError(t, err, conf.Exporter) {
+			assert.NoError(t, provider.Shutdown(ctx), conf.Exporter)
+		}
+	}
+
+	_, err := InitTracing(ctx, logger, &config.Tracing{Exporter: "zipkin"})
+	assert.Error(t, err)
+}
+
+func TestInitTracing_File(t *testing.T) {
+	logger := logging.GetLogger()
+	ctx := logging.WithContext(context.Background(), logger)
+	path := filepath.Join(t.TempDir(), "spans.json")
+	provider, err := InitTracing(ctx, logger, &config.Tracing{Exporter: config.ExporterFile, File: path})
+	if err != nil {
+		t.Fatalf("InitTracing error: %v", err)
+	}
+	_, span := otel.Tracer(TracerName).Start(ctx, "InsertOrder")
+	span.End()
+	assert.NoError(t, provider.Shutdown(ctx))
+
+	spans, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(spans), `"Name":"InsertOrder"`)
+}
+
+func TestNewSampler(t *testing.T) {
+	traceID := oteltrace.TraceID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	sampled := func(sampler trace.Sampler, parent oteltrace.SpanContext) bool {
+		result := sampler.ShouldSample(trace.SamplingParameters{
+			ParentContext: oteltrace.ContextWithSpanContext(context.Background(), parent),
+			TraceID:       traceID,
+		})
+
+		return result.Decision == trace.RecordAndSample
+	}
+	sampledParent := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID: traceID, SpanID: oteltrace.SpanID{1}, TraceFlags: oteltrace.FlagsSampled, Remote: true,
+	})
+
+	assert.True(t, sampled(newSampler(&config.Tracing{}), oteltrace.SpanContext{}))
+	assert.False(t, sampled(newSampler(&config.Tracing{Sampler: config.SamplerNever}), oteltrace.SpanContext{}))
+	assert.False(t, sampled(newSampler(&config.Tracing{Sampler: config.SamplerRatio, Ratio: 0.5}), oteltrace.SpanContext{}),
+		"trace ID is above the ratio bound")
+	assert.True(t, sampled(newSampler(&config.Tracing{Sampler: config.SamplerNever, ParentBased: true}), sampledParent),
+		"sampled parent is followed")
+}
+
+func TestRecordError(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	_, span := trace.NewTracerProvider(trace.WithSpanProcessor(recorder)).Tracer(TracerName).Start(context.Background(), "GetOrder")
+	RecordError(span, errors.New("no rows"))
+	span.End()
+
+	ended := recorder.Ended()[0]
+	assert.Equal(t, codes.Error, ended.Status().Code)
+	assert.Equal(t, "no rows", ended.Status().Description)
+	if assert.Len(t, ended.Events(), 1) {
+		assert.Equal(t, "exception", ended.Events()[0].Name)
+	}
+}
@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenDestination is returned for deliveries to addresses of the service's own networks.
+var ErrForbiddenDestination = errors.New("webhook destination is not a public address")
+
+const dialTimeout = 10 * time.Second
+
+// reservedPrefixes are not reachable on the internet, in addition to the ones told by netip.Addr methods.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved and broadcast
+}
+
+// PublicAddr reports whether webhooks may be delivered to addr. Loopback, private, link-local,
+// multicast and reserved addresses are refused, so subscriptions can not reach the service's own networks.
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkDestination is a net.Dialer control refusing connections to addresses which are not public.
+// It runs after the host is resolved, so names resolving to internal addresses are refused too.
+func checkDestination(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenDestination, address)
+	}
+	if !PublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenDestination, addrPort.Addr())
+	}
+
+	return nil
+}
+
+// newClient creates client of deliveries dialing with control. Redirects are not followed, the redirect
+// response fails the attempt, and proxies are not used, they would dial the destination instead of control.
+func newClient(control func(network, address string, c syscall.RawConn) error) *http.Client {
+	dialer := &net.Dialer{Timeout: dialTimeout, Control: control}
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert // always *http.Transport
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
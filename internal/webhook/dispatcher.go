@@ -0,0 +1,322 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/google/uuid"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+	"github.com/Sugar-pack/orders-manager/internal/retry"
+)
+
+// Defaults are used for settings missing in config.Webhooks.
+const (
+	DefaultMaxAttempts  = 10
+	DefaultTimeout      = 10 * time.Second
+	DefaultPollInterval = 5 * time.Second
+	DefaultBatchSize    = 50
+
+	userAgent = "orders-manager-webhooks"
+	// maxResponseSize is read from responses so connections can be reused, the rest is dropped.
+	maxResponseSize = 64 << 10
+	// heldGrace is left to confirmations to settle their held deliveries before reconciliation does.
+	heldGrace = time.Minute
+)
+
+// Queue keeps deliveries between attempts, it is implemented by Store.
+type Queue interface {
+	Hold(ctx context.Context, event, tenant, txID string, payload []byte) (int64, error)
+	Release(ctx context.Context, tenant, txID, event string, payload []byte) error
+	Discard(ctx context.Context, tenant, txID string) error
+	Held(ctx context.Context, before time.Time) ([]HeldTx, error)
+	Lease(ctx context.Context, limit int, lease time.Duration) ([]*Delivery, error)
+	Delivered(ctx context.Context, delivery *Delivery) error
+	Retry(ctx context.Context, delivery *Delivery, next time.Time, lastError string) error
+	DeadLetter(ctx context.Context, delivery *Delivery, lastError string) error
+}
+
+// Transactions tell outcomes of transactions with held deliveries, they are implemented by repositories.
+type Transactions interface {
+	repository.PreparedTxLister
+	repository.CommittedOrderReader
+}
+
+// Dispatcher queues events and periodically sends due deliveries.
+type Dispatcher struct {
+	queue Queue
+	// transactions tell which held deliveries belong to transactions committed or rolled back already
+	transactions Transactions
+	client       *http.Client
+	now          func() time.Time
+	settings     atomic.Pointer[config.Webhooks]
+}
+
+// NewDispatcher creates Dispatcher. Nil conf disables webhooks until Update.
+// transactions tell outcomes of transactions of every shard.
+func NewDispatcher(queue Queue, transactions Transactions, conf *config.Webhooks) *Dispatcher {
+	dispatcher := &Dispatcher{
+		queue:        queue,
+		transactions: transactions,
+		client:       newClient(checkDestination),
+		now:          time.Now,
+	}
+	dispatcher.Update(conf)
+
+	return dispatcher
+}
+
+// Update replaces webhook settings, it takes effect from the next poll.
+func (d *Dispatcher) Update(conf *config.Webhooks) {
+	if conf == nil {
+		conf = &config.Webhooks{}
+	}
+	d.settings.Store(conf)
+}
+
+func (d *Dispatcher) pollInterval() time.Duration {
+	if interval := d.settings.Load().PollInterval; interval > 0 {
+		return interval
+	}
+
+	return DefaultPollInterval
+}
+
+// Hold queues event about transaction txID for subscriptions of the tenant from ctx before the transaction
+// is confirmed, deliveries are sent once Settle releases them. The transaction must not be confirmed
+// when Hold fails, its outcome would not be delivered. It does nothing while webhooks are disabled.
+func (d *Dispatcher) Hold(ctx context.Context, event string, txID string) error {
+	if !d.settings.Load().Enabled {
+		return nil
+	}
+	tenant := repository.TenantFromContext(ctx)
+	payload, err := json.Marshal(Event{Type: event, TxID: txID, Tenant: tenant, OccurredAt: d.now().UTC()})
+	if err != nil {
+		return err //nolint:wrapcheck // logged by caller
+	}
+	queued, err := d.queue.Hold(ctx, event, tenant, txID, payload)
+	if err != nil {
+		return err //nolint:wrapcheck // logged by caller
+	}
+	if queued > 0 {
+		logging.FromContext(ctx).WithField("event", event).WithField("deliveries", queued).Debug("webhook deliveries held")
+	}
+
+	return nil
+}
+
+// Settle releases deliveries held for transaction txID when its confirmation is done, otherwise they are
+// discarded. Deliveries left held by a failed Settle are settled by Reconcile once the transaction is no longer prepared.
+func (d *Dispatcher) Settle(ctx context.Context, txID string, confirmed bool) error {
+	if !d.settings.Load().Enabled {
+		return nil
+	}
+	tenant := repository.TenantFromContext(ctx)
+	if !confirmed {
+		return d.queue.Discard(ctx, tenant, txID) //nolint:wrapcheck // logged by caller
+	}
+
+	return d.release(ctx, HeldTx{Tenant: tenant, TxID: txID})
+}
+
+// Reconcile settles deliveries held longer than a minute for transactions which are not prepared anymore,
+// their confirmation ended without settling them, and returns the number of settled transactions.
+func (d *Dispatcher) Reconcile(ctx context.Context) (int, error) {
+	if !d.settings.Load().Enabled {
+		return 0, nil
+	}
+	held, err := d.queue.Held(ctx, d.now().Add(-heldGrace))
+	if err != nil || len(held) == 0 {
+		return 0, err //nolint:wrapcheck // logged by caller
+	}
+	// listed after held deliveries, so a transaction confirmed in between is not prepared
+	transactions, err := d.transactions.ListPreparedTransactions(ctx, d.now())
+	if err != nil {
+		return 0, err //nolint:wrapcheck // logged by caller
+	}
+	prepared := make(map[HeldTx]bool, len(transactions))
+	for _, tx := range transactions {
+		prepared[HeldTx{Tenant: tx.Tenant, TxID: tx.TxID.String()}] = true
+	}
+
+	logger := logging.FromContext(ctx)
+	settled := 0
+	for _, tx := range held {
+		if prepared[tx] {
+			continue
+		}
+		if err = d.release(ctx, tx); err != nil {
+			return settled, err
+		}
+		logger.WithField("tenant", tx.Tenant).WithField("tx_id", tx.TxID).Warn("held webhook deliveries settled by reconciliation")
+		settled++
+	}
+
+	return settled, nil
+}
+
+// release settles deliveries held for transaction tx which is not prepared anymore. It was committed when
+// its order is stored, order.committed deliveries are released then with the order id in their payload,
+// otherwise it was rolled back and order.aborted ones are. Deliveries of the other event are discarded.
+func (d *Dispatcher) release(ctx context.Context, tx HeldTx) error {
+	txID, err := uuid.Parse(tx.TxID)
+	if err != nil {
+		// no outcome can be told for it
+		return d.queue.Discard(ctx, tx.Tenant, tx.TxID) //nolint:wrapcheck // logged by caller
+	}
+	event := Event{Type: EventOrderAborted, TxID: tx.TxID, Tenant: tx.Tenant, OccurredAt: d.now().UTC()}
+	orderID, err := d.transactions.CommittedOrder(repository.WithTenant(ctx, tx.Tenant), txID)
+	switch {
+	case err == nil:
+		event.Type = EventOrderCommitted
+		event.OrderID = orderID.String()
+	case !errors.Is(err, sql.ErrNoRows):
+		return err //nolint:wrapcheck // logged by caller
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err //nolint:wrapcheck // logged by caller
+	}
+
+	return d.queue.Release(ctx, tx.Tenant, tx.TxID, event.Type, payload) //nolint:wrapcheck // logged by caller
+}
+
+// Run sends due deliveries every poll interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+	timer := time.NewTimer(d.pollInterval())
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			if _, err := d.Reconcile(ctx); err != nil {
+				logger.WithError(err).Error("webhook reconciliation failed")
+			}
+			if _, err := d.DeliverOnce(ctx); err != nil {
+				logger.WithError(err).Error("webhook deliveries failed")
+			}
+			timer.Reset(d.pollInterval())
+		}
+	}
+}
+
+// DeliverOnce sends due deliveries at once and returns the number of accepted ones. Failed deliveries
+// are retried with exponential backoff and moved to dead letters after max attempts.
+func (d *Dispatcher) DeliverOnce(ctx context.Context) (int, error) {
+	conf := d.settings.Load()
+	if !conf.Enabled {
+		return 0, nil
+	}
+	timeout := orDefault(conf.Timeout, DefaultTimeout)
+	// deliveries are sent concurrently, twice the timeout is enough to finish them before the lease ends
+	deliveries, err := d.queue.Lease(ctx, orDefault(conf.BatchSize, DefaultBatchSize), 2*timeout)
+	if err != nil {
+		return 0, err //nolint:wrapcheck // logged by caller
+	}
+
+	var (
+		wg        sync.WaitGroup
+		delivered atomic.Int64
+	)
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if d.deliver(ctx, delivery, conf) {
+				delivered.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return int(delivered.Load()), nil
+}
+
+// deliver makes an attempt of delivery and records its outcome, it reports whether the receiver accepted it.
+func (d *Dispatcher) deliver(ctx context.Context, delivery *Delivery, conf *config.Webhooks) bool {
+	logger := logging.FromContext(ctx).WithField("delivery_id", delivery.ID.String()).
+		WithField("webhook_id", delivery.SubscriptionID.String()).WithField("event", delivery.Event)
+	err := d.send(ctx, delivery, orDefault(conf.Timeout, DefaultTimeout))
+	if err == nil {
+		// a delivery which is not removed is sent again after the lease
+		if doneErr := d.queue.Delivered(ctx, delivery); doneErr != nil {
+			logger.WithError(doneErr).Error("removing delivered webhook failed")
+		}
+
+		return true
+	}
+
+	delivery.Attempts++
+	logger = logger.WithField("attempt", delivery.Attempts)
+	if delivery.Attempts >= orDefault(conf.MaxAttempts, DefaultMaxAttempts) {
+		if deadErr := d.queue.DeadLetter(ctx, delivery, err.Error()); deadErr != nil {
+			logger.WithError(deadErr).Error("moving webhook delivery to dead letters failed")
+
+			return false
+		}
+		logger.WithError(err).Error("webhook delivery failed, moved to dead letters")
+
+		return false
+	}
+	delay := retry.NewPolicy(conf.Backoff).Delay(delivery.Attempts)
+	if retryErr := d.queue.Retry(ctx, delivery, d.now().Add(delay), err.Error()); retryErr != nil {
+		logger.WithError(retryErr).Error("scheduling webhook delivery retry failed")
+
+		return false
+	}
+	logger.WithError(err).WithField("retry_in", delay.String()).Warn("webhook delivery failed, retrying")
+
+	return false
+}
+
+// send posts signed payload of delivery, any status but 2xx is a failure.
+func (d *Dispatcher) send(ctx context.Context, delivery *Delivery, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err //nolint:wrapcheck // recorded as delivery error
+	}
+	timestamp := strconv.FormatInt(d.now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", userAgent)
+	request.Header.Set(EventHeader, delivery.Event)
+	request.Header.Set(DeliveryHeader, delivery.ID.String())
+	request.Header.Set(TimestampHeader, timestamp)
+	request.Header.Set(SignatureHeader, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return err //nolint:wrapcheck // recorded as delivery error
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, maxResponseSize))
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected response status %s", response.Status)
+	}
+
+	return nil
+}
+
+func orDefault[T int | time.Duration](value, defaultValue T) T {
+	if value > 0 {
+		return value
+	}
+
+	return defaultValue
+}
@@ -0,0 +1,374 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+)
+
+type heldDelivery struct {
+	delivery *Delivery
+	at       time.Time
+}
+
+// memoryQueue keeps deliveries of a single subscription in memory.
+type memoryQueue struct {
+	mu          sync.Mutex
+	url         string
+	secret      string
+	held        map[HeldTx]heldDelivery
+	pending     map[uuid.UUID]*Delivery
+	retries     map[uuid.UUID]time.Time
+	deadLetters []string
+}
+
+func newMemoryQueue(url string) *memoryQueue {
+	return &memoryQueue{
+		url: url, secret: "s3cret", held: make(map[HeldTx]heldDelivery),
+		pending: make(map[uuid.UUID]*Delivery), retries: make(map[uuid.UUID]time.Time),
+	}
+}
+
+func (q *memoryQueue) Hold(_ context.Context, event, tenant, txID string, payload []byte) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delivery := &Delivery{ID: uuid.New(), Tenant: tenant, URL: q.url, Secret: q.secret, Event: event, Payload: payload}
+	q.held[HeldTx{Tenant: tenant, TxID: txID}] = heldDelivery{delivery: delivery, at: time.Now()}
+
+	return 1, nil
+}
+
+func (q *memoryQueue) Release(_ context.Context, tenant, txID, event string, payload []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if held, ok := q.held[HeldTx{Tenant: tenant, TxID: txID}]; ok && held.delivery.Event == event {
+		held.delivery.Payload = payload
+		q.pending[held.delivery.ID] = held.delivery
+	}
+	delete(q.held, HeldTx{Tenant: tenant, TxID: txID})
+
+	return nil
+}
+
+func (q *memoryQueue) Discard(_ context.Context, tenant, txID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.held, HeldTx{Tenant: tenant, TxID: txID})
+
+	return nil
+}
+
+func (q *memoryQueue) Held(_ context.Context, before time.Time) ([]HeldTx, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var held []HeldTx
+	for tx, delivery := range q.held {
+		if !delivery.at.After(before) {
+			held = append(held, tx)
+		}
+	}
+
+	return held, nil
+}
+
+// notify holds event about txID and settles it as a successful confirmation does.
+func notify(t *testing.T, dispatcher *Dispatcher, event, txID string) {
+	t.Helper()
+	ctx := context.Background()
+	if event == EventOrderCommitted {
+		dispatcher.transactions.(*txOutcomes).commit("", txID)
+	}
+	assert.NoError(t, dispatcher.Hold(ctx, event, txID))
+	assert.NoError(t, dispatcher.Settle(ctx, txID, true))
+}
+
+// txOutcomes tells outcomes of transactions: prepared ones wait for confirmation, committed ones stored orders.
+type txOutcomes struct {
+	prepared  []repository.PreparedTransaction
+	committed map[HeldTx]uuid.UUID
+}
+
+// commit stores an order of transaction txID of tenant and returns its id.
+func (o *txOutcomes) commit(tenant, txID string) uuid.UUID {
+	if o.committed == nil {
+		o.committed = make(map[HeldTx]uuid.UUID)
+	}
+	orderID := uuid.New()
+	o.committed[HeldTx{Tenant: tenant, TxID: txID}] = orderID
+
+	return orderID
+}
+
+func (o *txOutcomes) ListPreparedTransactions(context.Context, time.Time) ([]repository.PreparedTransaction, error) {
+	return o.prepared, nil
+}
+
+func (o *txOutcomes) CommittedOrder(ctx context.Context, txID uuid.UUID) (uuid.UUID, error) {
+	orderID, ok := o.committed[HeldTx{Tenant: repository.TenantFromContext(ctx), TxID: txID.String()}]
+	if !ok {
+		return uuid.Nil, sql.ErrNoRows
+	}
+
+	return orderID, nil
+}
+
+func (q *memoryQueue) Lease(_ context.Context, limit int, _ time.Duration) ([]*Delivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var deliveries []*Delivery
+	for _, delivery := range q.pending {
+		if len(deliveries) < limit {
+			copied := *delivery
+			deliveries = append(deliveries, &copied)
+		}
+	}
+
+	return deliveries, nil
+}
+
+func (q *memoryQueue) Delivered(_ context.Context, delivery *Delivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.pending, delivery.ID)
+
+	return nil
+}
+
+func (q *memoryQueue) Retry(_ context.Context, delivery *Delivery, next time.Time, _ string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending[delivery.ID].Attempts = delivery.Attempts
+	q.retries[delivery.ID] = next
+
+	return nil
+}
+
+func (q *memoryQueue) DeadLetter(_ context.Context, delivery *Delivery, lastError string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.pending, delivery.ID)
+	q.deadLetters = append(q.deadLetters, lastError)
+
+	return nil
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []Event
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, Sign("s3cret", r.Header.Get(TimestampHeader), body), r.Header.Get(SignatureHeader))
+		assert.Equal(t, EventOrderCommitted, r.Header.Get(EventHeader))
+		assert.NotEmpty(t, r.Header.Get(DeliveryHeader))
+
+		var event Event
+		assert.NoError(t, json.Unmarshal(body, &event))
+		mu.Lock()
+		received = append(received, event)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	queue := newMemoryQueue(receiver.URL)
+	outcomes := &txOutcomes{}
+	dispatcher := NewDispatcher(queue, outcomes, &config.Webhooks{Enabled: true})
+	dispatcher.client = newClient(nil)
+	txID := uuid.NewString()
+	orderID := outcomes.commit("acme", txID)
+	ctx := repository.WithTenant(context.Background(), "acme")
+	assert.NoError(t, dispatcher.Hold(ctx, EventOrderCommitted, txID))
+	delivered, err := dispatcher.DeliverOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, delivered, "held deliveries are not sent")
+	assert.NoError(t, dispatcher.Settle(ctx, txID, true))
+
+	delivered, err = dispatcher.DeliverOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Empty(t, queue.pending)
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, txID, received[0].TxID)
+		assert.Equal(t, "acme", received[0].Tenant)
+		assert.Equal(t, orderID.String(), received[0].OrderID)
+	}
+}
+
+func TestDispatcher_RetryAndDeadLetter(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	queue := newMemoryQueue(receiver.URL)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	dispatcher := NewDispatcher(queue, &txOutcomes{}, &config.Webhooks{
+		Enabled:     true,
+		MaxAttempts: 3,
+		Backoff:     &config.Retry{InitialInterval: time.Second, MaxInterval: time.Minute, Multiplier: 2},
+	})
+	dispatcher.now = func() time.Time { return now }
+	dispatcher.client = newClient(nil)
+	notify(t, dispatcher, EventOrderAborted, uuid.NewString())
+
+	for attempt := 1; attempt < 3; attempt++ {
+		delivered, err := dispatcher.DeliverOnce(context.Background())
+		assert.NoError(t, err)
+		assert.Zero(t, delivered)
+		for id, next := range queue.retries {
+			assert.Equal(t, attempt, queue.pending[id].Attempts)
+			wantDelay := time.Second << (attempt - 1)
+			assert.GreaterOrEqual(t, next.Sub(now), wantDelay/2, "attempt %d", attempt)
+			assert.LessOrEqual(t, next.Sub(now), wantDelay*3/2, "attempt %d", attempt)
+		}
+	}
+
+	_, err := dispatcher.DeliverOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, queue.pending)
+	assert.Equal(t, []string{"unexpected response status 503 Service Unavailable"}, queue.deadLetters)
+}
+
+func TestDispatcher_Settle(t *testing.T) {
+	queue := newMemoryQueue("https://partner.example/hooks")
+	dispatcher := NewDispatcher(queue, &txOutcomes{}, &config.Webhooks{Enabled: true})
+	ctx := repository.WithTenant(context.Background(), "acme")
+	txID := uuid.NewString()
+
+	// a retried confirmation replaces deliveries held by the failed one
+	assert.NoError(t, dispatcher.Hold(ctx, EventOrderCommitted, txID))
+	assert.NoError(t, dispatcher.Settle(ctx, txID, false))
+	assert.Empty(t, queue.held, "deliveries of a failed confirmation are discarded")
+	assert.NoError(t, dispatcher.Hold(ctx, EventOrderAborted, txID))
+	assert.NoError(t, dispatcher.Settle(ctx, txID, true))
+	assert.Empty(t, queue.held)
+	for _, delivery := range queue.pending {
+		assert.Equal(t, EventOrderAborted, delivery.Event)
+	}
+	assert.Len(t, queue.pending, 1)
+
+	// commit is not taken for granted, its order must be stored
+	notCommitted := uuid.NewString()
+	assert.NoError(t, dispatcher.Hold(ctx, EventOrderCommitted, notCommitted))
+	assert.NoError(t, dispatcher.Settle(ctx, notCommitted, true))
+	assert.Empty(t, queue.held)
+	assert.Len(t, queue.pending, 1)
+}
+
+func TestDispatcher_Reconcile(t *testing.T) {
+	queue := newMemoryQueue("https://partner.example/hooks")
+	stillPrepared, committed, failed, rolledBack := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	outcomes := &txOutcomes{prepared: []repository.PreparedTransaction{{TxID: stillPrepared, Tenant: "acme"}}}
+	orderID := outcomes.commit("acme", committed.String())
+	dispatcher := NewDispatcher(queue, outcomes, &config.Webhooks{Enabled: true})
+	ctx := repository.WithTenant(context.Background(), "acme")
+	// confirmations which ended before settling their deliveries
+	assert.NoError(t, dispatcher.Hold(ctx, EventOrderCommitted, stillPrepared.String()))
+	assert.NoError(t, dispatcher.Hold(ctx, EventOrderCommitted, committed.String()))
+	// a failed commit followed by ROLLBACK PREPARED of an operator
+	assert.NoError(t, dispatcher.Hold(ctx, EventOrderCommitted, failed.String()))
+	assert.NoError(t, dispatcher.Hold(ctx, EventOrderAborted, rolledBack.String()))
+
+	settled, err := dispatcher.Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, settled, "confirmations are given time to settle")
+
+	dispatcher.now = func() time.Time { return time.Now().Add(2 * heldGrace) }
+	settled, err = dispatcher.Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, settled)
+	assert.Len(t, queue.held, 1)
+	assert.Contains(t, queue.held, HeldTx{Tenant: "acme", TxID: stillPrepared.String()}, "prepared transactions are kept")
+
+	events := make(map[string]Event)
+	for _, delivery := range queue.pending {
+		var event Event
+		assert.NoError(t, json.Unmarshal(delivery.Payload, &event))
+		assert.Equal(t, delivery.Event, event.Type)
+		events[event.TxID] = event
+	}
+	assert.Len(t, events, 2, "order.committed of the transaction without order is discarded")
+	assert.Equal(t, EventOrderCommitted, events[committed.String()].Type)
+	assert.Equal(t, orderID.String(), events[committed.String()].OrderID)
+	assert.Equal(t, EventOrderAborted, events[rolledBack.String()].Type)
+	assert.Empty(t, events[rolledBack.String()].OrderID)
+}
+
+func TestDispatcher_Destinations(t *testing.T) {
+	var reached atomic.Bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		reached.Store(true)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer redirect.Close()
+
+	// the receiver resolves to loopback
+	queue := newMemoryQueue(strings.Replace(target.URL, "127.0.0.1", "localhost", 1))
+	dispatcher := NewDispatcher(queue, &txOutcomes{}, &config.Webhooks{Enabled: true, MaxAttempts: 1})
+	notify(t, dispatcher, EventOrderCommitted, uuid.NewString())
+	delivered, err := dispatcher.DeliverOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, delivered)
+	if assert.Len(t, queue.deadLetters, 1) {
+		assert.Contains(t, queue.deadLetters[0], ErrForbiddenDestination.Error())
+	}
+
+	// redirects are not followed
+	queue = newMemoryQueue(redirect.URL)
+	dispatcher = NewDispatcher(queue, &txOutcomes{}, &config.Webhooks{Enabled: true, MaxAttempts: 1})
+	dispatcher.client = newClient(nil)
+	notify(t, dispatcher, EventOrderCommitted, uuid.NewString())
+	_, err = dispatcher.DeliverOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"unexpected response status 307 Temporary Redirect"}, queue.deadLetters)
+	assert.False(t, reached.Load())
+}
+
+func TestPublicAddr(t *testing.T) {
+	for _, addr := range []string{"8.8.8.8", "2001:4860:4860::8888", "::ffff:93.184.216.34"} {
+		assert.True(t, PublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+	for _, addr := range []string{
+		"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "fe80::1", "fd00::1",
+		"0.0.0.0", "::", "100.64.0.1", "224.0.0.1", "255.255.255.255", "::ffff:127.0.0.1", "::ffff:169.254.169.254",
+	} {
+		assert.False(t, PublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestDispatcher_Disabled(t *testing.T) {
+	queue := newMemoryQueue("http://127.0.0.1:0")
+	dispatcher := NewDispatcher(queue, &txOutcomes{}, nil)
+
+	notify(t, dispatcher, EventOrderCommitted, uuid.NewString())
+	assert.Empty(t, queue.held)
+	assert.Empty(t, queue.pending)
+	delivered, err := dispatcher.DeliverOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, delivered)
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1760875200.{}' | openssl dgst -sha256 -hmac s3cret
+	assert.Equal(t, "sha256=54e3a09477d980ca864c4683d1f3accc4701618c0dec2ba8646724e1eda90bb9", Sign("s3cret", "1760875200", []byte("{}")))
+}
@@ -0,0 +1,221 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+)
+
+// ErrNotFound is returned for subscriptions missing or owned by another tenant.
+var ErrNotFound = errors.New("webhook subscription not found")
+
+// events are stored as text[], they are passed as comma separated strings since database/sql does not encode slices.
+const (
+	insertSubscriptionSQL = `INSERT INTO webhook_subscriptions (id, tenant_id, url, secret, events)
+VALUES ($1, $2, $3, $4, string_to_array($5, ',')) RETURNING created_at`
+	listSubscriptionsSQL = `SELECT id, url, array_to_string(events, ',') AS events, created_at
+FROM webhook_subscriptions WHERE tenant_id = $1 ORDER BY created_at`
+	deleteSubscriptionSQL = "DELETE FROM webhook_subscriptions WHERE id = $1 AND tenant_id = $2"
+	// holdSQL makes a held delivery for every subscription of the tenant to the event, replacing held deliveries
+	// of an earlier confirmation of the transaction
+	holdSQL = `WITH replaced AS (
+    DELETE FROM webhook_deliveries AS d USING webhook_subscriptions AS s
+    WHERE s.id = d.subscription_id AND s.tenant_id = $3 AND d.tx_id = $4 AND d.held)
+INSERT INTO webhook_deliveries (id, subscription_id, event, payload, tx_id, held)
+SELECT gen_random_uuid(), id, $1, $2, $4, true FROM webhook_subscriptions WHERE tenant_id = $3 AND $1 = ANY(events)`
+	// releaseSQL makes held deliveries of the event due with the final payload and removes held ones of other events
+	releaseSQL = `WITH discarded AS (
+    DELETE FROM webhook_deliveries AS d USING webhook_subscriptions AS s
+    WHERE s.id = d.subscription_id AND s.tenant_id = $1 AND d.tx_id = $2 AND d.held AND d.event <> $3)
+UPDATE webhook_deliveries AS d SET held = false, payload = $4, next_attempt_at = now()
+FROM webhook_subscriptions AS s
+WHERE s.id = d.subscription_id AND s.tenant_id = $1 AND d.tx_id = $2 AND d.held AND d.event = $3`
+	discardSQL = `DELETE FROM webhook_deliveries AS d USING webhook_subscriptions AS s
+WHERE s.id = d.subscription_id AND s.tenant_id = $1 AND d.tx_id = $2 AND d.held`
+	heldSQL = `SELECT DISTINCT s.tenant_id, d.tx_id FROM webhook_deliveries AS d
+JOIN webhook_subscriptions AS s ON s.id = d.subscription_id
+WHERE d.held AND d.created_at <= $1`
+	// leaseSQL postpones due deliveries by the lease, so other replicas do not pick them while they are sent
+	leaseSQL = `UPDATE webhook_deliveries AS d SET next_attempt_at = now() + make_interval(secs => $1)
+FROM webhook_subscriptions AS s
+WHERE s.id = d.subscription_id AND d.id IN (
+    SELECT id FROM webhook_deliveries WHERE next_attempt_at <= now() AND NOT held
+    ORDER BY next_attempt_at LIMIT $2 FOR UPDATE SKIP LOCKED)
+RETURNING d.id, d.subscription_id, s.tenant_id, s.url, s.secret, d.event, d.payload, d.attempts`
+	deliveredSQL  = "DELETE FROM webhook_deliveries WHERE id = $1"
+	retrySQL      = "UPDATE webhook_deliveries SET attempts = $2, next_attempt_at = $3, last_error = $4 WHERE id = $1"
+	deadLetterSQL = `WITH moved AS (DELETE FROM webhook_deliveries WHERE id = $1 RETURNING id, subscription_id, event, payload)
+INSERT INTO webhook_dead_letters (id, subscription_id, tenant_id, url, event, payload, attempts, last_error)
+SELECT id, subscription_id, $2, $3, event, payload, $4, $5 FROM moved`
+	listDeadLettersSQL = `SELECT id, subscription_id, url, event, payload, attempts, last_error, failed_at
+FROM webhook_dead_letters WHERE tenant_id = $1 ORDER BY failed_at DESC LIMIT $2`
+)
+
+// Store keeps subscriptions and deliveries in the primary database. Subscriptions and dead letters
+// are of the tenant from repository.WithTenant.
+type Store struct {
+	db *sqlx.DB
+}
+
+// NewStore creates Store.
+func NewStore(db *sqlx.DB) *Store {
+	return &Store{db: db}
+}
+
+// CreateSubscription stores subscription of the tenant and sets its CreatedAt.
+func (s *Store) CreateSubscription(ctx context.Context, subscription *Subscription) error {
+	subscription.Tenant = repository.TenantFromContext(ctx)
+
+	return s.db.QueryRowxContext(ctx, insertSubscriptionSQL, subscription.ID.String(), subscription.Tenant,
+		subscription.URL, subscription.Secret, strings.Join(subscription.Events, ",")).Scan(&subscription.CreatedAt) //nolint:wrapcheck
+}
+
+// ListSubscriptions returns subscriptions of the tenant without secrets.
+func (s *Store) ListSubscriptions(ctx context.Context) ([]*Subscription, error) {
+	rows, err := s.db.QueryxContext(ctx, listSubscriptionsSQL, repository.TenantFromContext(ctx))
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	defer rows.Close()
+
+	var subscriptions []*Subscription
+	for rows.Next() {
+		var (
+			subscription Subscription
+			events       string
+		)
+		if err = rows.Scan(&subscription.ID, &subscription.URL, &events, &subscription.CreatedAt); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		subscription.Tenant = repository.TenantFromContext(ctx)
+		subscription.Events = strings.Split(events, ",")
+		subscriptions = append(subscriptions, &subscription)
+	}
+
+	return subscriptions, rows.Err() //nolint:wrapcheck
+}
+
+// DeleteSubscription deletes subscription of the tenant together with its pending deliveries.
+func (s *Store) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, deleteSubscriptionSQL, id.String(), repository.TenantFromContext(ctx))
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Hold makes deliveries of event with payload about transaction txID to subscriptions of tenant and returns
+// their count. They are not sent until Release, held deliveries of the transaction made before are replaced.
+func (s *Store) Hold(ctx context.Context, event, tenant, txID string, payload []byte) (int64, error) {
+	result, err := s.db.ExecContext(ctx, holdSQL, event, payload, tenant, txID)
+	if err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+
+	return result.RowsAffected() //nolint:wrapcheck
+}
+
+// Release makes held deliveries of event about transaction txID of tenant due with payload,
+// held deliveries of other events of the transaction are removed.
+func (s *Store) Release(ctx context.Context, tenant, txID, event string, payload []byte) error {
+	_, err := s.db.ExecContext(ctx, releaseSQL, tenant, txID, event, payload)
+
+	return err //nolint:wrapcheck
+}
+
+// Discard removes held deliveries of transaction txID of tenant.
+func (s *Store) Discard(ctx context.Context, tenant, txID string) error {
+	_, err := s.db.ExecContext(ctx, discardSQL, tenant, txID)
+
+	return err //nolint:wrapcheck
+}
+
+// Held returns transactions with deliveries held since before.
+func (s *Store) Held(ctx context.Context, before time.Time) ([]HeldTx, error) {
+	var held []HeldTx
+	if err := s.db.SelectContext(ctx, &held, heldSQL, before); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return held, nil
+}
+
+// Lease returns up to limit due deliveries and postpones their next attempt by lease.
+func (s *Store) Lease(ctx context.Context, limit int, lease time.Duration) ([]*Delivery, error) {
+	rows, err := s.db.QueryxContext(ctx, leaseSQL, lease.Seconds(), limit)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	defer rows.Close()
+
+	var deliveries []*Delivery
+	for rows.Next() {
+		var delivery Delivery
+		err = rows.Scan(&delivery.ID, &delivery.SubscriptionID, &delivery.Tenant, &delivery.URL, &delivery.Secret,
+			&delivery.Event, &delivery.Payload, &delivery.Attempts)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+
+	return deliveries, rows.Err() //nolint:wrapcheck
+}
+
+// Delivered removes delivery accepted by the receiver.
+func (s *Store) Delivered(ctx context.Context, delivery *Delivery) error {
+	_, err := s.db.ExecContext(ctx, deliveredSQL, delivery.ID.String())
+
+	return err //nolint:wrapcheck
+}
+
+// Retry records failed attempt of delivery and schedules the next one at next.
+func (s *Store) Retry(ctx context.Context, delivery *Delivery, next time.Time, lastError string) error {
+	_, err := s.db.ExecContext(ctx, retrySQL, delivery.ID.String(), delivery.Attempts, next, lastError)
+
+	return err //nolint:wrapcheck
+}
+
+// DeadLetter moves delivery to dead letters.
+func (s *Store) DeadLetter(ctx context.Context, delivery *Delivery, lastError string) error {
+	_, err := s.db.ExecContext(ctx, deadLetterSQL, delivery.ID.String(), delivery.Tenant, delivery.URL,
+		delivery.Attempts, lastError)
+
+	return err //nolint:wrapcheck
+}
+
+// ListDeadLetters returns up to limit dead letters of the tenant, the latest first.
+func (s *Store) ListDeadLetters(ctx context.Context, limit int) ([]*DeadLetter, error) {
+	rows, err := s.db.QueryxContext(ctx, listDeadLettersSQL, repository.TenantFromContext(ctx), limit)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	defer rows.Close()
+
+	var deadLetters []*DeadLetter
+	for rows.Next() {
+		var deadLetter DeadLetter
+		err = rows.Scan(&deadLetter.ID, &deadLetter.SubscriptionID, &deadLetter.URL, &deadLetter.Event,
+			&deadLetter.Payload, &deadLetter.Attempts, &deadLetter.LastError, &deadLetter.FailedAt)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		deadLetters = append(deadLetters, &deadLetter)
+	}
+
+	return deadLetters, rows.Err() //nolint:wrapcheck
+}
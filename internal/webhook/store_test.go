@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+)
+
+func newStore(t *testing.T) (*Store, sqlmock.Sqlmock) {
+	t.Helper()
+	db, dbMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { assert.NoError(t, dbMock.ExpectationsWereMet()) })
+
+	return NewStore(sqlx.NewDb(db, "sqlmock")), dbMock
+}
+
+func TestStore_Subscriptions(t *testing.T) {
+	store, dbMock := newStore(t)
+	ctx := repository.WithTenant(context.Background(), "acme")
+	createdAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	subscription := &Subscription{ID: uuid.New(), URL: "https://partner.example/hooks", Secret: "s3cret", Events: Events}
+
+	dbMock.ExpectQuery(regexp.QuoteMeta("INSERT INTO webhook_subscriptions")).
+		WithArgs(subscription.ID.String(), "acme", subscription.URL, "s3cret", "order.committed,order.aborted").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(createdAt))
+	assert.NoError(t, store.CreateSubscription(ctx, subscription))
+	assert.Equal(t, "acme", subscription.Tenant)
+	assert.Equal(t, createdAt, subscription.CreatedAt)
+
+	dbMock.ExpectQuery(regexp.QuoteMeta("FROM webhook_subscriptions WHERE tenant_id = $1")).WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "events", "created_at"}).
+			AddRow(subscription.ID.String(), subscription.URL, "order.aborted", createdAt))
+	subscriptions, err := store.ListSubscriptions(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Subscription{{
+		ID: subscription.ID, Tenant: "acme", URL: subscription.URL, Events: []string{EventOrderAborted}, CreatedAt: createdAt,
+	}}, subscriptions)
+
+	dbMock.ExpectExec(regexp.QuoteMeta(deleteSubscriptionSQL)).WithArgs(subscription.ID.String(), "acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, store.DeleteSubscription(ctx, subscription.ID))
+
+	dbMock.ExpectExec(regexp.QuoteMeta(deleteSubscriptionSQL)).WithArgs(subscription.ID.String(), "acme").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, store.DeleteSubscription(ctx, subscription.ID), ErrNotFound)
+}
+
+func TestStore_Hold(t *testing.T) {
+	store, dbMock := newStore(t)
+	ctx := context.Background()
+	payload := []byte(`{"event":"order.committed"}`)
+	txID := uuid.NewString()
+
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO webhook_deliveries")).
+		WithArgs(EventOrderCommitted, payload, "acme", txID).WillReturnResult(sqlmock.NewResult(0, 2))
+	queued, err := store.Hold(ctx, EventOrderCommitted, "acme", txID, payload)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), queued)
+
+	dbMock.ExpectExec(regexp.QuoteMeta(releaseSQL)).WithArgs("acme", txID, EventOrderCommitted, []byte("{}")).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	assert.NoError(t, store.Release(ctx, "acme", txID, EventOrderCommitted, []byte("{}")))
+	dbMock.ExpectExec(regexp.QuoteMeta(discardSQL)).WithArgs("acme", txID).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.NoError(t, store.Discard(ctx, "acme", txID))
+
+	before := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	dbMock.ExpectQuery(regexp.QuoteMeta(heldSQL)).WithArgs(before).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "tx_id"}).AddRow("acme", txID))
+	held, err := store.Held(ctx, before)
+	assert.NoError(t, err)
+	assert.Equal(t, []HeldTx{{Tenant: "acme", TxID: txID}}, held)
+}
+
+func TestStore_Deliveries(t *testing.T) {
+	store, dbMock := newStore(t)
+	ctx := context.Background()
+	delivery := &Delivery{
+		ID: uuid.New(), SubscriptionID: uuid.New(), Tenant: "acme", URL: "https://partner.example/hooks",
+		Secret: "s3cret", Event: EventOrderAborted, Payload: []byte(`{}`), Attempts: 2,
+	}
+
+	dbMock.ExpectQuery(regexp.QuoteMeta("UPDATE webhook_deliveries AS d SET next_attempt_at")).WithArgs(20.0, 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subscription_id", "tenant_id", "url", "secret", "event", "payload", "attempts"}).
+			AddRow(delivery.ID.String(), delivery.SubscriptionID.String(), "acme", delivery.URL, "s3cret", delivery.Event, delivery.Payload, 2))
+	deliveries, err := store.Lease(ctx, 50, 20*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Delivery{delivery}, deliveries)
+
+	dbMock.ExpectExec(regexp.QuoteMeta(deliveredSQL)).WithArgs(delivery.ID.String()).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, store.Delivered(ctx, delivery))
+
+	next := time.Date(2026, 10, 19, 12, 0, 40, 0, time.UTC)
+	dbMock.ExpectExec(regexp.QuoteMeta(retrySQL)).WithArgs(delivery.ID.String(), 2, next, "unexpected response status 500").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, store.Retry(ctx, delivery, next, "unexpected response status 500"))
+
+	dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO webhook_dead_letters")).
+		WithArgs(delivery.ID.String(), "acme", delivery.URL, 2, "connection refused").WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, store.DeadLetter(ctx, delivery, "connection refused"))
+}
+
+func TestStore_ListDeadLetters(t *testing.T) {
+	store, dbMock := newStore(t)
+	failedAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	deadLetter := &DeadLetter{
+		ID: uuid.New(), SubscriptionID: uuid.New(), URL: "https://partner.example/hooks", Event: EventOrderCommitted,
+		Payload: []byte(`{}`), Attempts: 10, LastError: "connection refused", FailedAt: failedAt,
+	}
+
+	dbMock.ExpectQuery(regexp.QuoteMeta("FROM webhook_dead_letters WHERE tenant_id = $1")).WithArgs("", 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subscription_id", "url", "event", "payload", "attempts", "last_error", "failed_at"}).
+			AddRow(deadLetter.ID.String(), deadLetter.SubscriptionID.String(), deadLetter.URL, deadLetter.Event,
+				deadLetter.Payload, 10, "connection refused", failedAt))
+	deadLetters, err := store.ListDeadLetters(context.Background(), 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []*DeadLetter{deadLetter}, deadLetters)
+}
@@ -0,0 +1,99 @@
+// Package webhook calls partners back over HTTP when transactions commit or abort.
+//
+// Events are queued held in webhook_deliveries of the primary database by Dispatcher.Hold before
+// their transaction is confirmed, released by Dispatcher.Settle once it is and sent by Dispatcher.Run,
+// so they survive restarts and are delivered at least once. Only the event matching the outcome stored
+// in the orders database is released, order.committed ones once the order of the transaction is stored.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Events subscriptions can be made to.
+const (
+	EventOrderCommitted = "order.committed"
+	EventOrderAborted   = "order.aborted"
+)
+
+// Events lists every event, a subscription without events gets all of them.
+var Events = []string{EventOrderCommitted, EventOrderAborted}
+
+// IsEvent reports whether event is known.
+func IsEvent(event string) bool {
+	return slices.Contains(Events, event)
+}
+
+// Headers of delivery requests.
+const (
+	EventHeader     = "X-Orders-Event"
+	DeliveryHeader  = "X-Orders-Delivery" // delivery ID, the same for every attempt, receivers may deduplicate by it
+	TimestampHeader = "X-Orders-Timestamp"
+	SignatureHeader = "X-Orders-Signature" // see Sign
+)
+
+// Event is the JSON body of a delivery.
+type Event struct {
+	Type       string    `json:"event"`
+	TxID       string    `json:"tx_id"`
+	OrderID    string    `json:"order_id,omitempty"` // order inserted by the transaction, of committed ones only
+	Tenant     string    `json:"tenant,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Subscription is an URL receiving events of a tenant.
+type Subscription struct {
+	ID        uuid.UUID
+	Tenant    string
+	URL       string
+	Secret    string // signs deliveries, see Sign
+	Events    []string
+	CreatedAt time.Time
+}
+
+// Delivery is an event on its way to a subscription.
+type Delivery struct {
+	ID             uuid.UUID
+	SubscriptionID uuid.UUID
+	Tenant         string
+	URL            string
+	Secret         string
+	Event          string
+	Payload        []byte
+	Attempts       int // failed attempts so far
+}
+
+// HeldTx is a transaction with held deliveries.
+type HeldTx struct {
+	Tenant string `db:"tenant_id"`
+	TxID   string `db:"tx_id"`
+}
+
+// DeadLetter is a delivery given up after too many failed attempts.
+type DeadLetter struct {
+	ID             uuid.UUID
+	SubscriptionID uuid.UUID
+	URL            string
+	Event          string
+	Payload        []byte
+	Attempts       int
+	LastError      string
+	FailedAt       time.Time
+}
+
+// Sign returns SignatureHeader value of body sent at timestamp: "sha256=" and hex of HMAC-SHA256 keyed by secret
+// over timestamp, "." and body. Receivers recompute it to verify the sender and reject replays by timestamp.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
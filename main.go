@@ -2,55 +2,281 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"time"
 
 	"github.com/Sugar-pack/users-manager/pkg/logging"
+	"github.com/jmoiron/sqlx"
 
+	"github.com/Sugar-pack/orders-manager/internal/auth"
 	"github.com/Sugar-pack/orders-manager/internal/config"
-	"github.com/Sugar-pack/orders-manager/internal/db"
 	"github.com/Sugar-pack/orders-manager/internal/grpcapi"
+	"github.com/Sugar-pack/orders-manager/internal/health"
+	applog "github.com/Sugar-pack/orders-manager/internal/logger"
 	"github.com/Sugar-pack/orders-manager/internal/migration"
-	"github.com/Sugar-pack/orders-manager/internal/repository"
+	"github.com/Sugar-pack/orders-manager/internal/partition"
+	"github.com/Sugar-pack/orders-manager/internal/ratelimit"
+	"github.com/Sugar-pack/orders-manager/internal/tlsconfig"
+	"github.com/Sugar-pack/orders-manager/internal/tracing"
+	"github.com/Sugar-pack/orders-manager/internal/webhook"
+	"github.com/Sugar-pack/orders-manager/pkg/pb"
 )
 
+const usage = `Usage: %s [--config path] <command> [arguments]
+
+Commands:
+  serve [--skip-migrations]  apply pending migrations and serve api (default)
+  migrate up [N]             apply N or all pending migrations
+  migrate down [N]           roll back N (default 1) last applied migrations
+  migrate status             list migrations and when they were applied
+  migrate redo               roll back and apply again the last applied migration
+  migrate new <name>         create empty migration file in db.migration_dir_path or sql-migrations
+  export [flags]             write orders to stdout or a file, see export -h
+  import [flags] <file>      load orders from NDJSON or CSV file with COPY, see import -h
+
+Flags:
+`
+
+// errUsage is returned for malformed command line, usage is printed instead of the error.
+var errUsage = errors.New("invalid usage")
+
 func main() {
-	ctx := context.Background()
-	appConfig, err := config.GetAppConfig()
+	configPath := flag.String("config", "", "path to config file, overrides "+config.PathEnv+" (default "+config.DefaultPath+")")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), usage, os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	command, args := "serve", flag.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	path := config.ResolvePath(*configPath)
+
+	var err error
+	switch command {
+	case "serve":
+		err = serve(path, args)
+	case "migrate":
+		err = runMigrate(path, args)
+	case "export":
+		err = runExport(path, args)
+	case "import":
+		err = runImport(path, args)
+	default:
+		err = errUsage
+	}
+	if errors.Is(err, errUsage) {
+		flag.Usage()
+		os.Exit(2)
+	}
 	if err != nil {
 		log.Fatal(err)
+	}
+}
 
-		return
+func serve(configPath string, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	skipMigrations := flags.Bool("skip-migrations", false, "do not apply pending migrations at startup")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		return errUsage
 	}
 
-	logger := logging.GetLogger()
-	ctx = logging.WithContext(ctx, logger)
-	err = migration.Apply(ctx, appConfig.Db)
+	configWatcher, err := config.NewWatcher(logging.GetLogger(), configPath)
 	if err != nil {
-		log.Fatal(err)
+		return err //nolint:wrapcheck
+	}
+	appConfig := configWatcher.Config()
 
-		return
+	logger, logHandle, err := applog.New(appConfig.Log)
+	if err != nil {
+		return err //nolint:wrapcheck
 	}
+	defer logHandle.Close()
+	ctx, cancel := context.WithCancelCause(logging.WithContext(context.Background(), logger))
+	defer cancel(nil)
 
-	dbConn, err := db.Connect(ctx, appConfig.Db)
+	// instruments of the server and storage are created with the registered provider
+	meterProvider, err := tracing.InitMetrics(ctx, logger, appConfig.Metrics)
 	if err != nil {
-		log.Fatal(err)
+		return err //nolint:wrapcheck
+	}
+	defer func() {
+		if stopErr := meterProvider.Shutdown(context.WithoutCancel(ctx)); stopErr != nil {
+			logger.WithError(stopErr).Error("shutting down meter provider failed")
+		}
+	}()
 
-		return
+	store, err := openStorage(ctx, appConfig.Db)
+	if err != nil {
+		return err //nolint:wrapcheck
 	}
+	if err = store.withCache(appConfig.Cache, meterProvider); err != nil {
+		return err //nolint:wrapcheck
+	}
+	limiter := ratelimit.NewLimiter(appConfig.RateLimit)
+	readiness := health.NewReadiness(
+		pb.OrdersManagerService_ServiceDesc.ServiceName,
+		pb.TnxConfirmingService_ServiceDesc.ServiceName,
+		pb.WebhookAdminService_ServiceDesc.ServiceName,
+	)
 
-	repo := repository.NewPsqlRepository(dbConn)
+	// gRPC and REST listeners share certificates
+	var reloader *tlsconfig.Reloader
+	if appConfig.API.TLS != nil {
+		if reloader, err = tlsconfig.NewReloader(logger, appConfig.API.TLS); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
 
-	server, err := grpcapi.CreateServer(logger, repo)
+	serverOpts := []grpcapi.Option{
+		grpcapi.WithTLS(reloader), grpcapi.WithMeterProvider(meterProvider), grpcapi.WithReadiness(readiness),
+	}
+	if appConfig.Auth != nil && appConfig.Auth.Enabled {
+		authenticator, authErr := auth.NewAuthenticator(appConfig.Auth)
+		if authErr != nil {
+			return authErr //nolint:wrapcheck
+		}
+		serverOpts = append(serverOpts, grpcapi.WithAuth(authenticator, auth.NewPolicy(appConfig.Auth)))
+	}
+	serverOpts = append(serverOpts, grpcapi.WithRateLimiter(limiter))
+	payloads := applog.NewPayloads(logPayloads(appConfig))
+	serverOpts = append(serverOpts, grpcapi.WithPayloadLogger(payloads))
+	if appConfig.Tenancy != nil && appConfig.Tenancy.Enabled {
+		serverOpts = append(serverOpts, grpcapi.WithTenancy())
+	}
+	webhookStore, dispatcher, err := openWebhooks(ctx, appConfig.Db, appConfig.Webhooks, store.repo)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	serverOpts = append(serverOpts, grpcapi.WithWebhooks(webhookStore, dispatcher))
 
-		return
+	server, err := grpcapi.CreateServer(logger, store.repo, serverOpts...)
+	if err != nil {
+		return err //nolint:wrapcheck
 	}
+	// serve health checks while DB is being prepared
+	go func() {
+		cancel(grpcapi.ServeWithTrace(ctx, server, appConfig.API, reloader, appConfig.Tracing))
+	}()
+
+	if err = prepareDB(ctx, appConfig.Db, store.ping, *skipMigrations); err != nil {
+		server.Stop()
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
 
-	err = grpcapi.ServeWithTrace(ctx, server, appConfig.API)
+		return err
+	}
+
+	// partitioned table is created by migrations
+	partitionManagers, err := openPartitionManagers(ctx, appConfig.Db, appConfig.Partitions)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	for _, manager := range partitionManagers {
+		go manager.Run(ctx)
+	}
+	go dispatcher.Run(ctx)
+
+	subscribeRuntimeSettings(configWatcher, logger, logHandle, payloads, store.sqlPools, limiter, partitionManagers,
+		dispatcher)
+	configWatcher.Watch()
+
+	readiness.SetReady()
+	logger.Info("service is ready")
+
+	<-ctx.Done()
+	if cause := context.Cause(ctx); !errors.Is(cause, context.Canceled) {
+		return cause
+	}
 
+	return nil
+}
+
+// prepareDB waits for DB of every shard to accept connections, applies migrations unless skipped and verifies them.
+func prepareDB(ctx context.Context, conf *config.DB, ping func(ctx context.Context) error, skipMigrations bool) error {
+	if err := ping(ctx); err != nil {
+		return err
+	}
+	if skipMigrations {
+		logging.FromContext(ctx).Info("skipping migrations")
+	}
+	for _, shardConf := range conf.Shards() {
+		if !skipMigrations {
+			if err := migration.Apply(ctx, shardConf); err != nil {
+				return err //nolint:wrapcheck
+			}
+		}
+		if err := migration.Verify(ctx, shardConf); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	return nil
+}
+
+// subscribeRuntimeSettings applies settings which are safe to change without restart.
+func subscribeRuntimeSettings(watcher *config.Watcher, logger logging.Logger, logHandle *applog.Handle, payloads *applog.Payloads,
+	pools []*sqlx.DB, limiter *ratelimit.Limiter, partitionManagers []*partition.Manager,
+	dispatcher *webhook.Dispatcher,
+) {
+	config.Subscribe(watcher, "log.level",
+		func(c *config.AppConfig) string {
+			if c.Log == nil || c.Log.Level == "" {
+				return applog.DefaultLevel
+			}
+
+			return c.Log.Level
+		},
+		func(level string) {
+			if err := logHandle.SetLevel(level); err != nil {
+				logger.WithError(err).Error("set log level failed")
+			}
+		})
+	config.Subscribe(watcher, "log.payloads", logPayloads, payloads.Update)
+	config.Subscribe(watcher, "rate_limit",
+		func(c *config.AppConfig) *config.RateLimit { return c.RateLimit },
+		limiter.Update)
+	config.Subscribe(watcher, "webhooks",
+		func(c *config.AppConfig) *config.Webhooks { return c.Webhooks },
+		dispatcher.Update)
+	config.Subscribe(watcher, "partitions",
+		func(c *config.AppConfig) *config.Partitions { return c.Partitions },
+		func(partitions *config.Partitions) {
+			for _, manager := range partitionManagers {
+				manager.Update(partitions)
+			}
+		})
+	if len(pools) == 0 {
+		// pgx backend has no database/sql pools, its pool settings take effect after restart
 		return
 	}
+	config.Subscribe(watcher, "db.max_open_conns",
+		func(c *config.AppConfig) int { return c.Db.MaxOpenConns },
+		func(maxOpenConns int) {
+			for _, pool := range pools {
+				pool.SetMaxOpenConns(maxOpenConns)
+			}
+		})
+	config.Subscribe(watcher, "db.conn_max_lifetime",
+		func(c *config.AppConfig) time.Duration { return c.Db.ConnMaxLifetime },
+		func(lifetime time.Duration) {
+			for _, pool := range pools {
+				pool.SetConnMaxLifetime(lifetime)
+			}
+		})
+}
+
+func logPayloads(c *config.AppConfig) *config.LogPayloads {
+	if c.Log == nil {
+		return nil
+	}
+
+	return c.Log.Payloads
 }
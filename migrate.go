@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Sugar-pack/users-manager/pkg/logging"
+
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	applog "github.com/Sugar-pack/orders-manager/internal/logger"
+	"github.com/Sugar-pack/orders-manager/internal/migration"
+)
+
+// runMigrate runs "migrate" subcommands.
+func runMigrate(configPath string, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	appConfig, err := config.GetAppConfig(configPath)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	logger, logHandle, err := applog.New(appConfig.Log)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	defer logHandle.Close()
+	ctx := logging.WithContext(context.Background(), logger)
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "up":
+		limit, limitErr := countArg(args, 0)
+		if limitErr != nil {
+			return limitErr
+		}
+
+		return eachShard(appConfig.Db, func(conf *config.DB, prefix string) error {
+			count, upErr := migration.Up(ctx, conf, limit)
+			fmt.Printf("%sapplied %d migrations\n", prefix, count)
+
+			return upErr //nolint:wrapcheck
+		})
+	case "down":
+		limit, limitErr := countArg(args, 1)
+		if limitErr != nil {
+			return limitErr
+		}
+
+		return eachShard(appConfig.Db, func(conf *config.DB, prefix string) error {
+			count, downErr := migration.Down(ctx, conf, limit)
+			fmt.Printf("%srolled back %d migrations\n", prefix, count)
+
+			return downErr //nolint:wrapcheck
+		})
+	case "redo":
+		if len(args) > 0 {
+			return errUsage
+		}
+
+		return eachShard(appConfig.Db, func(conf *config.DB, prefix string) error {
+			migrationID, redoErr := migration.Redo(ctx, conf)
+			if redoErr != nil {
+				return redoErr //nolint:wrapcheck
+			}
+			if migrationID == "" {
+				fmt.Printf("%snothing to redo\n", prefix)
+			} else {
+				fmt.Printf("%sredone %s\n", prefix, migrationID)
+			}
+
+			return nil
+		})
+	case "status":
+		if len(args) > 0 {
+			return errUsage
+		}
+
+		return eachShard(appConfig.Db, func(conf *config.DB, prefix string) error {
+			statuses, statusErr := migration.GetStatus(ctx, conf)
+			if statusErr != nil {
+				return statusErr //nolint:wrapcheck
+			}
+			if prefix != "" {
+				fmt.Println(strings.TrimSpace(prefix))
+			}
+
+			return printStatus(statuses)
+		})
+	case "new":
+		if len(args) != 1 {
+			return errUsage
+		}
+		dir := appConfig.Db.MigrationDirPath
+		if dir == "" {
+			dir = migration.SourceDir
+		}
+		path, newErr := migration.New(dir, args[0], time.Now())
+		if newErr != nil {
+			return newErr //nolint:wrapcheck
+		}
+		fmt.Printf("created %s\n", path)
+
+		return nil
+	default:
+		return errUsage
+	}
+}
+
+// eachShard runs fn for every shard in turn and stops at the first error.
+// With several shards prefix names the shard fn works on, otherwise it is empty.
+func eachShard(conf *config.DB, fn func(conf *config.DB, prefix string) error) error {
+	shards := conf.Shards()
+	for i, shardConf := range shards {
+		prefix := ""
+		if len(shards) > 1 {
+			prefix = fmt.Sprintf("shard %d: ", i)
+		}
+		if err := fn(shardConf, prefix); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// countArg parses optional positive number of migrations.
+func countArg(args []string, defaultCount int) (int, error) {
+	switch len(args) {
+	case 0:
+		return defaultCount, nil
+	case 1:
+		count, err := strconv.Atoi(args[0])
+		if err != nil || count <= 0 {
+			return 0, errUsage
+		}
+
+		return count, nil
+	default:
+		return 0, errUsage
+	}
+}
+
+func printStatus(statuses []migration.Status) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "MIGRATION\tAPPLIED")
+	for _, status := range statuses {
+		applied := "pending"
+		if status.AppliedAt != nil {
+			applied = status.AppliedAt.Format(time.RFC3339)
+		}
+		if status.Missing {
+			applied += " (missing in migration dir)"
+		}
+		fmt.Fprintf(writer, "%s\t%s\n", status.ID, applied)
+	}
+
+	return writer.Flush() //nolint:wrapcheck
+}
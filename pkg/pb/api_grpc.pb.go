@@ -24,6 +24,10 @@ const _ = grpc.SupportPackageIsVersion7
 type OrdersManagerServiceClient interface {
 	InsertOrder(ctx context.Context, in *Order, opts ...grpc.CallOption) (*OrderTnxResponse, error)
 	GetOrder(ctx context.Context, in *GetOrderRequest, opts ...grpc.CallOption) (*OrderResponse, error)
+	// BatchGetOrders returns orders with the given ids in the order of the request, ids without an order are reported as missing.
+	BatchGetOrders(ctx context.Context, in *BatchGetOrdersRequest, opts ...grpc.CallOption) (*BatchGetOrdersResponse, error)
+	// ListOrders returns orders of a user sorted by creation time, page by page.
+	ListOrders(ctx context.Context, in *ListOrdersRequest, opts ...grpc.CallOption) (*ListOrdersResponse, error)
 }
 
 type ordersManagerServiceClient struct {
@@ -52,12 +56,34 @@ func (c *ordersManagerServiceClient) GetOrder(ctx context.Context, in *GetOrderR
 	return out, nil
 }
 
+func (c *ordersManagerServiceClient) BatchGetOrders(ctx context.Context, in *BatchGetOrdersRequest, opts ...grpc.CallOption) (*BatchGetOrdersResponse, error) {
+	out := new(BatchGetOrdersResponse)
+	err := c.cc.Invoke(ctx, "/pb.OrdersManagerService/BatchGetOrders", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ordersManagerServiceClient) ListOrders(ctx context.Context, in *ListOrdersRequest, opts ...grpc.CallOption) (*ListOrdersResponse, error) {
+	out := new(ListOrdersResponse)
+	err := c.cc.Invoke(ctx, "/pb.OrdersManagerService/ListOrders", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // OrdersManagerServiceServer is the server API for OrdersManagerService service.
 // All implementations must embed UnimplementedOrdersManagerServiceServer
 // for forward compatibility
 type OrdersManagerServiceServer interface {
 	InsertOrder(context.Context, *Order) (*OrderTnxResponse, error)
 	GetOrder(context.Context, *GetOrderRequest) (*OrderResponse, error)
+	// BatchGetOrders returns orders with the given ids in the order of the request, ids without an order are reported as missing.
+	BatchGetOrders(context.Context, *BatchGetOrdersRequest) (*BatchGetOrdersResponse, error)
+	// ListOrders returns orders of a user sorted by creation time, page by page.
+	ListOrders(context.Context, *ListOrdersRequest) (*ListOrdersResponse, error)
 	mustEmbedUnimplementedOrdersManagerServiceServer()
 }
 
@@ -71,6 +97,12 @@ func (UnimplementedOrdersManagerServiceServer) InsertOrder(context.Context, *Ord
 func (UnimplementedOrdersManagerServiceServer) GetOrder(context.Context, *GetOrderRequest) (*OrderResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetOrder not implemented")
 }
+func (UnimplementedOrdersManagerServiceServer) BatchGetOrders(context.Context, *BatchGetOrdersRequest) (*BatchGetOrdersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetOrders not implemented")
+}
+func (UnimplementedOrdersManagerServiceServer) ListOrders(context.Context, *ListOrdersRequest) (*ListOrdersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOrders not implemented")
+}
 func (UnimplementedOrdersManagerServiceServer) mustEmbedUnimplementedOrdersManagerServiceServer() {}
 
 // UnsafeOrdersManagerServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -120,6 +152,42 @@ func _OrdersManagerService_GetOrder_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _OrdersManagerService_BatchGetOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrdersManagerServiceServer).BatchGetOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.OrdersManagerService/BatchGetOrders",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrdersManagerServiceServer).BatchGetOrders(ctx, req.(*BatchGetOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrdersManagerService_ListOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrdersManagerServiceServer).ListOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.OrdersManagerService/ListOrders",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrdersManagerServiceServer).ListOrders(ctx, req.(*ListOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // OrdersManagerService_ServiceDesc is the grpc.ServiceDesc for OrdersManagerService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -135,6 +203,14 @@ var OrdersManagerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetOrder",
 			Handler:    _OrdersManagerService_GetOrder_Handler,
 		},
+		{
+			MethodName: "BatchGetOrders",
+			Handler:    _OrdersManagerService_BatchGetOrders_Handler,
+		},
+		{
+			MethodName: "ListOrders",
+			Handler:    _OrdersManagerService_ListOrders_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/api.proto",
@@ -145,6 +221,8 @@ var OrdersManagerService_ServiceDesc = grpc.ServiceDesc{
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type TnxConfirmingServiceClient interface {
 	SendConfirmation(ctx context.Context, in *Confirmation, opts ...grpc.CallOption) (*ConfirmationResponse, error)
+	// ListPreparedTransactions returns transactions of the caller's tenant waiting for confirmation, the oldest first.
+	ListPreparedTransactions(ctx context.Context, in *ListPreparedTransactionsRequest, opts ...grpc.CallOption) (*ListPreparedTransactionsResponse, error)
 }
 
 type tnxConfirmingServiceClient struct {
@@ -164,11 +242,22 @@ func (c *tnxConfirmingServiceClient) SendConfirmation(ctx context.Context, in *C
 	return out, nil
 }
 
+func (c *tnxConfirmingServiceClient) ListPreparedTransactions(ctx context.Context, in *ListPreparedTransactionsRequest, opts ...grpc.CallOption) (*ListPreparedTransactionsResponse, error) {
+	out := new(ListPreparedTransactionsResponse)
+	err := c.cc.Invoke(ctx, "/pb.TnxConfirmingService/ListPreparedTransactions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TnxConfirmingServiceServer is the server API for TnxConfirmingService service.
 // All implementations must embed UnimplementedTnxConfirmingServiceServer
 // for forward compatibility
 type TnxConfirmingServiceServer interface {
 	SendConfirmation(context.Context, *Confirmation) (*ConfirmationResponse, error)
+	// ListPreparedTransactions returns transactions of the caller's tenant waiting for confirmation, the oldest first.
+	ListPreparedTransactions(context.Context, *ListPreparedTransactionsRequest) (*ListPreparedTransactionsResponse, error)
 	mustEmbedUnimplementedTnxConfirmingServiceServer()
 }
 
@@ -179,6 +268,9 @@ type UnimplementedTnxConfirmingServiceServer struct {
 func (UnimplementedTnxConfirmingServiceServer) SendConfirmation(context.Context, *Confirmation) (*ConfirmationResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SendConfirmation not implemented")
 }
+func (UnimplementedTnxConfirmingServiceServer) ListPreparedTransactions(context.Context, *ListPreparedTransactionsRequest) (*ListPreparedTransactionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPreparedTransactions not implemented")
+}
 func (UnimplementedTnxConfirmingServiceServer) mustEmbedUnimplementedTnxConfirmingServiceServer() {}
 
 // UnsafeTnxConfirmingServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -210,6 +302,24 @@ func _TnxConfirmingService_SendConfirmation_Handler(srv interface{}, ctx context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TnxConfirmingService_ListPreparedTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPreparedTransactionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TnxConfirmingServiceServer).ListPreparedTransactions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.TnxConfirmingService/ListPreparedTransactions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TnxConfirmingServiceServer).ListPreparedTransactions(ctx, req.(*ListPreparedTransactionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // TnxConfirmingService_ServiceDesc is the grpc.ServiceDesc for TnxConfirmingService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -221,6 +331,210 @@ var TnxConfirmingService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SendConfirmation",
 			Handler:    _TnxConfirmingService_SendConfirmation_Handler,
 		},
+		{
+			MethodName: "ListPreparedTransactions",
+			Handler:    _TnxConfirmingService_ListPreparedTransactions_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/api.proto",
+}
+
+// WebhookAdminServiceClient is the client API for WebhookAdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WebhookAdminServiceClient interface {
+	// CreateWebhook subscribes url to events, a secret signing deliveries is generated when not given.
+	CreateWebhook(ctx context.Context, in *CreateWebhookRequest, opts ...grpc.CallOption) (*Webhook, error)
+	ListWebhooks(ctx context.Context, in *ListWebhooksRequest, opts ...grpc.CallOption) (*ListWebhooksResponse, error)
+	// DeleteWebhook deletes the subscription together with its pending deliveries.
+	DeleteWebhook(ctx context.Context, in *DeleteWebhookRequest, opts ...grpc.CallOption) (*DeleteWebhookResponse, error)
+	// ListDeadLetters returns deliveries given up after too many failed attempts, the latest first.
+	ListDeadLetters(ctx context.Context, in *ListDeadLettersRequest, opts ...grpc.CallOption) (*ListDeadLettersResponse, error)
+}
+
+type webhookAdminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWebhookAdminServiceClient(cc grpc.ClientConnInterface) WebhookAdminServiceClient {
+	return &webhookAdminServiceClient{cc}
+}
+
+func (c *webhookAdminServiceClient) CreateWebhook(ctx context.Context, in *CreateWebhookRequest, opts ...grpc.CallOption) (*Webhook, error) {
+	out := new(Webhook)
+	err := c.cc.Invoke(ctx, "/pb.WebhookAdminService/CreateWebhook", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *webhookAdminServiceClient) ListWebhooks(ctx context.Context, in *ListWebhooksRequest, opts ...grpc.CallOption) (*ListWebhooksResponse, error) {
+	out := new(ListWebhooksResponse)
+	err := c.cc.Invoke(ctx, "/pb.WebhookAdminService/ListWebhooks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *webhookAdminServiceClient) DeleteWebhook(ctx context.Context, in *DeleteWebhookRequest, opts ...grpc.CallOption) (*DeleteWebhookResponse, error) {
+	out := new(DeleteWebhookResponse)
+	err := c.cc.Invoke(ctx, "/pb.WebhookAdminService/DeleteWebhook", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *webhookAdminServiceClient) ListDeadLetters(ctx context.Context, in *ListDeadLettersRequest, opts ...grpc.CallOption) (*ListDeadLettersResponse, error) {
+	out := new(ListDeadLettersResponse)
+	err := c.cc.Invoke(ctx, "/pb.WebhookAdminService/ListDeadLetters", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WebhookAdminServiceServer is the server API for WebhookAdminService service.
+// All implementations must embed UnimplementedWebhookAdminServiceServer
+// for forward compatibility
+type WebhookAdminServiceServer interface {
+	// CreateWebhook subscribes url to events, a secret signing deliveries is generated when not given.
+	CreateWebhook(context.Context, *CreateWebhookRequest) (*Webhook, error)
+	ListWebhooks(context.Context, *ListWebhooksRequest) (*ListWebhooksResponse, error)
+	// DeleteWebhook deletes the subscription together with its pending deliveries.
+	DeleteWebhook(context.Context, *DeleteWebhookRequest) (*DeleteWebhookResponse, error)
+	// ListDeadLetters returns deliveries given up after too many failed attempts, the latest first.
+	ListDeadLetters(context.Context, *ListDeadLettersRequest) (*ListDeadLettersResponse, error)
+	mustEmbedUnimplementedWebhookAdminServiceServer()
+}
+
+// UnimplementedWebhookAdminServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedWebhookAdminServiceServer struct {
+}
+
+func (UnimplementedWebhookAdminServiceServer) CreateWebhook(context.Context, *CreateWebhookRequest) (*Webhook, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateWebhook not implemented")
+}
+func (UnimplementedWebhookAdminServiceServer) ListWebhooks(context.Context, *ListWebhooksRequest) (*ListWebhooksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWebhooks not implemented")
+}
+func (UnimplementedWebhookAdminServiceServer) DeleteWebhook(context.Context, *DeleteWebhookRequest) (*DeleteWebhookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWebhook not implemented")
+}
+func (UnimplementedWebhookAdminServiceServer) ListDeadLetters(context.Context, *ListDeadLettersRequest) (*ListDeadLettersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDeadLetters not implemented")
+}
+func (UnimplementedWebhookAdminServiceServer) mustEmbedUnimplementedWebhookAdminServiceServer() {}
+
+// UnsafeWebhookAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WebhookAdminServiceServer will
+// result in compilation errors.
+type UnsafeWebhookAdminServiceServer interface {
+	mustEmbedUnimplementedWebhookAdminServiceServer()
+}
+
+func RegisterWebhookAdminServiceServer(s grpc.ServiceRegistrar, srv WebhookAdminServiceServer) {
+	s.RegisterService(&WebhookAdminService_ServiceDesc, srv)
+}
+
+func _WebhookAdminService_CreateWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateWebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WebhookAdminServiceServer).CreateWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.WebhookAdminService/CreateWebhook",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WebhookAdminServiceServer).CreateWebhook(ctx, req.(*CreateWebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WebhookAdminService_ListWebhooks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWebhooksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WebhookAdminServiceServer).ListWebhooks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.WebhookAdminService/ListWebhooks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WebhookAdminServiceServer).ListWebhooks(ctx, req.(*ListWebhooksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WebhookAdminService_DeleteWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteWebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WebhookAdminServiceServer).DeleteWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.WebhookAdminService/DeleteWebhook",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WebhookAdminServiceServer).DeleteWebhook(ctx, req.(*DeleteWebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WebhookAdminService_ListDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDeadLettersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WebhookAdminServiceServer).ListDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.WebhookAdminService/ListDeadLetters",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WebhookAdminServiceServer).ListDeadLetters(ctx, req.(*ListDeadLettersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// WebhookAdminService_ServiceDesc is the grpc.ServiceDesc for WebhookAdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WebhookAdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pb.WebhookAdminService",
+	HandlerType: (*WebhookAdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateWebhook",
+			Handler:    _WebhookAdminService_CreateWebhook_Handler,
+		},
+		{
+			MethodName: "ListWebhooks",
+			Handler:    _WebhookAdminService_ListWebhooks_Handler,
+		},
+		{
+			MethodName: "DeleteWebhook",
+			Handler:    _WebhookAdminService_DeleteWebhook_Handler,
+		},
+		{
+			MethodName: "ListDeadLetters",
+			Handler:    _WebhookAdminService_ListDeadLetters_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/api.proto",
@@ -0,0 +1,10 @@
+package pb
+
+import (
+	_ "embed"
+)
+
+// OpenAPISpec is the OpenAPI v2 description of the REST/JSON gateway generated from api/api.proto.
+//
+//go:embed api.swagger.json
+var OpenAPISpec []byte
@@ -0,0 +1,9 @@
+// Package sqlmigrations embeds database migrations into the binary.
+package sqlmigrations
+
+import "embed"
+
+// FS contains migration files.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,24 @@
+-- +migrate Up
+-- +migrate StatementBegin
+-- orders made before tenancy belong to the empty tenant
+ALTER TABLE orders ADD COLUMN tenant_id varchar NOT NULL DEFAULT '';
+-- new orders get the tenant of the transaction, policy below rejects them when it is not set
+ALTER TABLE orders ALTER COLUMN tenant_id SET DEFAULT coalesce(current_setting('orders_manager.tenant_id', true), '');
+CREATE INDEX orders_tenant_id_idx ON orders (tenant_id);
+
+ALTER TABLE orders ENABLE ROW LEVEL SECURITY;
+-- the service usually owns the table, owners bypass policies unless forced
+ALTER TABLE orders FORCE ROW LEVEL SECURITY;
+CREATE POLICY orders_tenant_isolation ON orders
+    USING (tenant_id = current_setting('orders_manager.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('orders_manager.tenant_id', true));
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DROP POLICY IF EXISTS orders_tenant_isolation ON orders;
+ALTER TABLE orders NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE orders DISABLE ROW LEVEL SECURITY;
+DROP INDEX IF EXISTS orders_tenant_id_idx;
+ALTER TABLE orders DROP COLUMN IF EXISTS tenant_id;
+-- +migrate StatementEnd
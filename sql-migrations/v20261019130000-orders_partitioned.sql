@@ -0,0 +1,83 @@
+-- +migrate Up
+-- +migrate StatementBegin
+-- prepared transactions keep locks on orders, fail instead of blocking every call until they are confirmed
+SET LOCAL lock_timeout = '10s';
+
+ALTER TABLE orders RENAME TO orders_unpartitioned;
+ALTER INDEX orders_pkey RENAME TO orders_unpartitioned_pkey;
+ALTER INDEX orders_tenant_id_idx RENAME TO orders_unpartitioned_tenant_id_idx;
+
+-- primary key of a partitioned table must include the partition key
+CREATE TABLE orders (
+    id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    label varchar NOT NULL,
+    created_at timestamp NOT NULL,
+    tenant_id varchar NOT NULL DEFAULT coalesce(current_setting('orders_manager.tenant_id', true), ''),
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+CREATE INDEX orders_user_id_idx ON orders (user_id);
+CREATE INDEX orders_tenant_id_idx ON orders (tenant_id);
+
+-- rows of months without partition, e.g. created_at far in the past or future, go here
+CREATE TABLE orders_default PARTITION OF orders DEFAULT;
+
+-- monthly partitions orders_pYYYYMM for the last year and the current month,
+-- the partition manager creates the next ones ahead of time
+DO $$
+DECLARE
+    month date;
+    last_month date := date_trunc('month', now() AT TIME ZONE 'UTC')::date;
+BEGIN
+    SELECT greatest(date_trunc('month', min(created_at))::date, (last_month - interval '1 year')::date)
+    INTO month FROM orders_unpartitioned;
+    month := coalesce(month, last_month);
+    WHILE month <= last_month LOOP
+        EXECUTE format('CREATE TABLE %I PARTITION OF orders FOR VALUES FROM (%L) TO (%L)',
+            'orders_p' || to_char(month, 'YYYYMM'), month, (month + interval '1 month')::date);
+        month := (month + interval '1 month')::date;
+    END LOOP;
+END $$;
+
+-- the forced policy would hide orders of every tenant from the copy, the owner bypasses it otherwise
+ALTER TABLE orders_unpartitioned NO FORCE ROW LEVEL SECURITY;
+INSERT INTO orders (id, user_id, label, created_at, tenant_id)
+SELECT id, user_id, label, created_at, tenant_id FROM orders_unpartitioned;
+DROP TABLE orders_unpartitioned;
+
+ALTER TABLE orders ENABLE ROW LEVEL SECURITY;
+ALTER TABLE orders FORCE ROW LEVEL SECURITY;
+CREATE POLICY orders_tenant_isolation ON orders
+    USING (tenant_id = current_setting('orders_manager.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('orders_manager.tenant_id', true));
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+SET LOCAL lock_timeout = '10s';
+
+ALTER TABLE orders RENAME TO orders_partitioned;
+ALTER INDEX orders_pkey RENAME TO orders_partitioned_pkey;
+ALTER INDEX orders_tenant_id_idx RENAME TO orders_partitioned_tenant_id_idx;
+
+CREATE TABLE orders (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL,
+    label varchar NOT NULL,
+    created_at timestamp NOT NULL,
+    tenant_id varchar NOT NULL DEFAULT coalesce(current_setting('orders_manager.tenant_id', true), '')
+);
+CREATE INDEX orders_tenant_id_idx ON orders (tenant_id);
+
+-- detached and archived partitions are not copied back
+ALTER TABLE orders_partitioned NO FORCE ROW LEVEL SECURITY;
+INSERT INTO orders (id, user_id, label, created_at, tenant_id)
+SELECT id, user_id, label, created_at, tenant_id FROM orders_partitioned;
+DROP TABLE orders_partitioned;
+
+ALTER TABLE orders ENABLE ROW LEVEL SECURITY;
+ALTER TABLE orders FORCE ROW LEVEL SECURITY;
+CREATE POLICY orders_tenant_isolation ON orders
+    USING (tenant_id = current_setting('orders_manager.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('orders_manager.tenant_id', true));
+-- +migrate StatementEnd
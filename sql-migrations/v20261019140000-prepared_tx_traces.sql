@@ -0,0 +1,16 @@
+-- +migrate Up
+-- +migrate StatementBegin
+-- W3C trace context of calls preparing transactions, kept until the transaction is committed or rolled back
+CREATE TABLE prepared_tx_traces
+(
+    gid         varchar PRIMARY KEY,
+    traceparent varchar     NOT NULL,
+    tracestate  varchar     NOT NULL DEFAULT '',
+    created_at  timestamptz NOT NULL DEFAULT now()
+);
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DROP TABLE IF EXISTS prepared_tx_traces;
+-- +migrate StatementEnd
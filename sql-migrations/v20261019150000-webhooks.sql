@@ -0,0 +1,50 @@
+-- +migrate Up
+-- +migrate StatementBegin
+-- subscriptions of partners to transaction outcomes, only shard 0 keeps webhook tables filled
+CREATE TABLE webhook_subscriptions
+(
+    id         uuid PRIMARY KEY,
+    tenant_id  varchar     NOT NULL DEFAULT '',
+    url        varchar     NOT NULL,
+    secret     varchar     NOT NULL,
+    events     text[]      NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX webhook_subscriptions_tenant_id_idx ON webhook_subscriptions (tenant_id);
+
+-- deliveries waiting for the next attempt, a delivery is removed once the receiver accepts it
+CREATE TABLE webhook_deliveries
+(
+    id              uuid PRIMARY KEY,
+    subscription_id uuid        NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event           varchar     NOT NULL,
+    payload         jsonb       NOT NULL,
+    attempts        int         NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    last_error      varchar     NOT NULL DEFAULT '',
+    created_at      timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX webhook_deliveries_next_attempt_at_idx ON webhook_deliveries (next_attempt_at);
+
+-- deliveries which failed webhooks.max_attempts times, they outlive their subscriptions
+CREATE TABLE webhook_dead_letters
+(
+    id              uuid PRIMARY KEY,
+    subscription_id uuid        NOT NULL,
+    tenant_id       varchar     NOT NULL DEFAULT '',
+    url             varchar     NOT NULL,
+    event           varchar     NOT NULL,
+    payload         jsonb       NOT NULL,
+    attempts        int         NOT NULL,
+    last_error      varchar     NOT NULL,
+    failed_at       timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX webhook_dead_letters_tenant_id_idx ON webhook_dead_letters (tenant_id, failed_at);
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DROP TABLE IF EXISTS webhook_dead_letters;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+-- +migrate StatementEnd
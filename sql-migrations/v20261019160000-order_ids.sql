@@ -0,0 +1,60 @@
+-- +migrate Up
+-- +migrate StatementBegin
+SET LOCAL lock_timeout = '10s';
+
+-- primary key of partitioned orders is (id, created_at), ids are kept unique across partitions here.
+-- Ids stay registered when their orders are detached, archived or deleted, so they are never reused.
+CREATE TABLE order_ids
+(
+    id         uuid PRIMARY KEY,
+    created_at timestamp NOT NULL,
+    tenant_id  varchar   NOT NULL DEFAULT coalesce(current_setting('orders_manager.tenant_id', true), '')
+);
+
+-- an order with the id and created_at of a stored one is left to the primary key of orders,
+-- so inserts with ON CONFLICT DO NOTHING still skip orders imported before.
+-- Ids of other tenants are hidden by the policy of order_ids, reusing one fails with no_data_found,
+-- the repository reports it as a missing order, so tenants do not learn ids of each other.
+CREATE FUNCTION orders_register_id() RETURNS trigger LANGUAGE plpgsql AS $$
+DECLARE
+    registered timestamp;
+BEGIN
+    INSERT INTO order_ids (id, created_at, tenant_id) VALUES (NEW.id, NEW.created_at, NEW.tenant_id)
+    ON CONFLICT (id) DO NOTHING;
+    IF NOT FOUND THEN
+        SELECT created_at INTO registered FROM order_ids WHERE id = NEW.id;
+        IF NOT FOUND THEN
+            RAISE EXCEPTION 'order % not found', NEW.id USING ERRCODE = 'no_data_found';
+        END IF;
+        IF registered IS DISTINCT FROM NEW.created_at THEN
+            RAISE EXCEPTION 'order id % is already used by another order', NEW.id
+                USING ERRCODE = 'unique_violation', CONSTRAINT = 'order_ids_pkey';
+        END IF;
+    END IF;
+    RETURN NEW;
+END $$;
+
+-- the trigger is cloned to partitions, including ones the partition manager attaches later
+CREATE TRIGGER orders_register_id BEFORE INSERT ON orders
+    FOR EACH ROW EXECUTE FUNCTION orders_register_id();
+
+-- the forced policy would hide orders of every tenant, the owner bypasses it otherwise
+ALTER TABLE orders NO FORCE ROW LEVEL SECURITY;
+INSERT INTO order_ids (id, created_at, tenant_id)
+SELECT DISTINCT ON (id) id, created_at, tenant_id FROM orders ORDER BY id, created_at;
+ALTER TABLE orders FORCE ROW LEVEL SECURITY;
+
+CREATE INDEX order_ids_tenant_id_idx ON order_ids (tenant_id);
+ALTER TABLE order_ids ENABLE ROW LEVEL SECURITY;
+ALTER TABLE order_ids FORCE ROW LEVEL SECURITY;
+CREATE POLICY order_ids_tenant_isolation ON order_ids
+    USING (tenant_id = current_setting('orders_manager.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('orders_manager.tenant_id', true));
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DROP TRIGGER IF EXISTS orders_register_id ON orders;
+DROP FUNCTION IF EXISTS orders_register_id();
+DROP TABLE IF EXISTS order_ids;
+-- +migrate StatementEnd
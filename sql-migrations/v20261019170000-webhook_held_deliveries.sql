@@ -0,0 +1,16 @@
+-- +migrate Up
+-- +migrate StatementBegin
+-- deliveries are queued held before their transaction is confirmed and released once it is,
+-- held ones are not sent; tx_id is the confirmed transaction, empty for deliveries queued before
+ALTER TABLE webhook_deliveries
+    ADD COLUMN tx_id varchar NOT NULL DEFAULT '',
+    ADD COLUMN held  boolean NOT NULL DEFAULT false;
+CREATE INDEX webhook_deliveries_held_idx ON webhook_deliveries (tx_id) WHERE held;
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DELETE FROM webhook_deliveries WHERE held;
+DROP INDEX IF EXISTS webhook_deliveries_held_idx;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS held, DROP COLUMN IF EXISTS tx_id;
+-- +migrate StatementEnd
@@ -0,0 +1,22 @@
+-- +migrate Up
+-- +migrate StatementBegin
+-- PrepareInsertOrder records its transaction here together with the order, so a row exists only once
+-- the transaction is committed: webhooks of transactions which are not prepared anymore are settled by it.
+CREATE TABLE order_transactions
+(
+    tx_id     uuid PRIMARY KEY,
+    order_id  uuid    NOT NULL,
+    tenant_id varchar NOT NULL DEFAULT coalesce(current_setting('orders_manager.tenant_id', true), '')
+);
+
+ALTER TABLE order_transactions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE order_transactions FORCE ROW LEVEL SECURITY;
+CREATE POLICY order_transactions_tenant_isolation ON order_transactions
+    USING (tenant_id = current_setting('orders_manager.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('orders_manager.tenant_id', true));
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DROP TABLE IF EXISTS order_transactions;
+-- +migrate StatementEnd
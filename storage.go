@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/Sugar-pack/orders-manager/internal/cache"
+	"github.com/Sugar-pack/orders-manager/internal/config"
+	"github.com/Sugar-pack/orders-manager/internal/db"
+	"github.com/Sugar-pack/orders-manager/internal/partition"
+	"github.com/Sugar-pack/orders-manager/internal/repository"
+	"github.com/Sugar-pack/orders-manager/internal/webhook"
+)
+
+// storage is a repository of the configured backend together with its pools.
+type storage struct {
+	repo interface {
+		repository.OrderRepoWith2PC
+		repository.PreparedTxLister
+		repository.CommittedOrderReader
+	}
+	// pings wait until primary DB of every shard accepts connections
+	pings []func(ctx context.Context) error
+	// sqlPools are resized on config reload, empty for pgx backend
+	sqlPools []*sqlx.DB
+}
+
+// ping waits until every shard accepts connections.
+func (s *storage) ping(ctx context.Context) error {
+	for _, ping := range s.pings {
+		if err := ping(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// openStorage creates pools of conf.Backend for every shard without waiting for DB.
+func openStorage(ctx context.Context, conf *config.DB) (*storage, error) {
+	store := new(storage)
+	shards := make([]repository.Shard, 0, len(conf.ShardConnStrings)+1)
+	for _, shardConf := range conf.Shards() {
+		shard, err := store.openShard(ctx, shardConf)
+		if err != nil {
+			return nil, err
+		}
+		shards = append(shards, shard)
+	}
+	if len(shards) == 1 {
+		store.repo = shards[0]
+	} else {
+		store.repo = repository.NewShardedRepository(shards...)
+	}
+
+	return store, nil
+}
+
+// withCache puts the GetOrder cache in front of the repository when conf enables it.
+func (s *storage) withCache(conf *config.Cache, provider metric.MeterProvider) error {
+	if conf == nil || !conf.Enabled {
+		return nil
+	}
+	backend, err := cache.New(conf)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	cached, err := cache.NewRepository(s.repo, backend, conf.MaxEntries, provider)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	s.repo = cached
+
+	return nil
+}
+
+func (s *storage) openShard(ctx context.Context, conf *config.DB) (repository.Shard, error) {
+	if conf.Backend == config.BackendPgx {
+		pool, err := db.OpenPool(ctx, conf)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		s.pings = append(s.pings, func(ctx context.Context) error { return db.PingPool(ctx, pool, conf) })
+
+		return repository.NewPgxRepository(pool), nil
+	}
+
+	dbConn, err := db.Open(ctx, conf)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	// replicas are not waited for, reads fall back to primary while they are unreachable
+	replicas := make([]*sqlx.DB, 0, len(conf.ReplicaConnStrings))
+	for _, connString := range conf.ReplicaConnStrings {
+		replicaConf := *conf
+		replicaConf.ConnString = connString
+		replica, openErr := db.Open(ctx, &replicaConf)
+		if openErr != nil {
+			return nil, openErr //nolint:wrapcheck
+		}
+		replicas = append(replicas, replica)
+	}
+	s.pings = append(s.pings, func(ctx context.Context) error { return db.Ping(ctx, dbConn, conf) })
+	s.sqlPools = append(append(s.sqlPools, dbConn), replicas...)
+
+	return repository.NewPsqlRepository(dbConn, replicas...), nil
+}
+
+// openPartitionManagers creates partition manager of every shard. A manager needs a single
+// connection holding its lock, so it gets a pool of its own.
+func openPartitionManagers(ctx context.Context, conf *config.DB, partitions *config.Partitions,
+) ([]*partition.Manager, error) {
+	shards := conf.Shards()
+	managers := make([]*partition.Manager, 0, len(shards))
+	for _, shardConf := range shards {
+		managerConf := *shardConf
+		managerConf.MaxOpenConns = 1
+		dbConn, err := db.Open(ctx, &managerConf)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		managers = append(managers, partition.NewManager(dbConn, partitions))
+	}
+
+	return managers, nil
+}
+
+// openWebhooks creates webhook store on the primary DB of shard 0 and dispatcher of its deliveries.
+// They share a pool of their own, so deliveries are kept whatever db.backend is. transactions tell
+// the dispatcher outcomes of transactions with held deliveries.
+func openWebhooks(ctx context.Context, conf *config.DB, webhooks *config.Webhooks, transactions webhook.Transactions,
+) (*webhook.Store, *webhook.Dispatcher, error) {
+	dbConn, err := db.Open(ctx, conf)
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck
+	}
+	store := webhook.NewStore(dbConn)
+
+	return store, webhook.NewDispatcher(store, transactions, webhooks), nil
+}